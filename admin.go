@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"weather-aggregator/aggregator"
+	"weather-aggregator/models"
+	"weather-aggregator/providers/chaos"
+)
+
+// adminOnly проверяет токен администратора (Authorization: Bearer <ADMIN_TOKEN>).
+// Без настроенного токена админские эндпоинты недоступны.
+func adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.AdminToken == "" {
+			writeJSON(w, http.StatusForbidden, models.ErrorResponse{
+				Error: "Админские эндпоинты отключены: не задан ADMIN_TOKEN",
+			})
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, models.ErrorResponse{
+				Error: "Требуется токен администратора",
+			})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// chaosHandler показывает (GET) или заменяет (PUT) конфигурацию сбоев
+func chaosHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, chaosCtl.Snapshot())
+
+	case http.MethodPut:
+		var configs map[string]chaos.Config
+		if err := json.NewDecoder(r.Body).Decode(&configs); err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Некорректный JSON",
+				Details: err.Error(),
+			})
+			return
+		}
+		if err := chaosCtl.Replace(configs); err != nil {
+			writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+				Error:   "Некорректная конфигурация",
+				Details: err.Error(),
+			})
+			return
+		}
+		// Закешированные результаты получены до изменения сбоев
+		agg.ClearCache()
+		writeJSON(w, http.StatusOK, chaosCtl.Snapshot())
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeJSON(w, http.StatusMethodNotAllowed, models.ErrorResponse{
+			Error: "Метод не поддерживается",
+		})
+	}
+}
+
+// schedulerHandler показывает состояние фонового обновления городов
+func schedulerHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, watcher.Status())
+}
+
+// providersAdminHandler показывает состояние провайдеров и журнал изменений
+func providersAdminHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"providers": agg.ProviderStatuses(),
+		"events":    agg.ProviderEvents(),
+	})
+}
+
+// providerActionHandler включает, отключает (POST .../{action}) или удаляет (DELETE) провайдер
+func providerActionHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	actor := adminActor(r)
+	reason, err := adminReason(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Некорректный JSON",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodDelete:
+		err = agg.RemoveProvider(name, actor, reason)
+	case r.PathValue("action") == aggregator.ActionEnable:
+		err = agg.EnableProvider(name, actor, reason)
+	case r.PathValue("action") == aggregator.ActionDisable:
+		err = agg.DisableProvider(name, actor, reason)
+	default:
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{
+			Error: "Неизвестное действие",
+		})
+		return
+	}
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, aggregator.ErrProviderNotFound) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, models.ErrorResponse{
+			Error:   "Не удалось изменить провайдер",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	providersAdminHandler(w, r)
+}
+
+// adminActor определяет инициатора изменения: заголовок X-Admin-User или адрес клиента
+func adminActor(r *http.Request) string {
+	if user := r.Header.Get("X-Admin-User"); user != "" {
+		return user
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// adminReason читает причину изменения из ?reason= или JSON тела {"reason": "..."}.
+// Пустое тело допустимо, некорректный JSON - ошибка.
+func adminReason(r *http.Request) (string, error) {
+	if reason := r.URL.Query().Get("reason"); reason != "" {
+		return reason, nil
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return body.Reason, nil
+}
+
+// writeJSON отправляет JSON ответ с указанным статусом
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"weather-aggregator/aggregator"
+	"weather-aggregator/config"
+	"weather-aggregator/models"
+	"weather-aggregator/scheduler"
+)
+
+// adminClient обращается к админским эндпоинтам запущенного сервера
+type adminClient struct {
+	serverURL string
+	token     string
+	user      string
+	client    *http.Client
+}
+
+// newAdminCmd создает команду "admin" для управления запущенным сервером
+func newAdminCmd() *cobra.Command {
+	clientCfg := config.LoadClient()
+	admin := &adminClient{client: &http.Client{Timeout: 10 * time.Second}}
+
+	var adminCmd = &cobra.Command{
+		Use:   "admin",
+		Short: "Управление запущенным сервером",
+		// Клиенту не нужны ключи API и собственный агрегатор
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	}
+
+	adminCmd.PersistentFlags().StringVar(&admin.serverURL, "server", clientCfg.ServerURL, "Адрес сервера")
+	adminCmd.PersistentFlags().StringVar(&admin.token, "token", clientCfg.AdminToken, "Токен администратора (ADMIN_TOKEN)")
+	adminCmd.PersistentFlags().StringVar(&admin.user, "user", os.Getenv("USER"), "Имя инициатора для журнала изменений")
+
+	var providersCmd = &cobra.Command{
+		Use:   "providers",
+		Short: "Состояние провайдеров и журнал изменений",
+		Run: func(cmd *cobra.Command, args []string) {
+			admin.run(http.MethodGet, "/admin/providers", "")
+		},
+	}
+
+	action := func(use, short, method, suffix string) *cobra.Command {
+		cmd := &cobra.Command{
+			Use:   use + " [провайдер]",
+			Short: short,
+			Args:  cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				reason, _ := cmd.Flags().GetString("reason")
+				admin.run(method, "/admin/providers/"+url.PathEscape(args[0])+suffix, reason)
+			},
+		}
+		cmd.Flags().StringP("reason", "r", "", "Причина изменения")
+		return cmd
+	}
+
+	providersCmd.AddCommand(
+		action("enable", "Включить провайдер", http.MethodPost, "/"+aggregator.ActionEnable),
+		action("disable", "Отключить провайдер", http.MethodPost, "/"+aggregator.ActionDisable),
+		action("remove", "Удалить провайдер до перезапуска", http.MethodDelete, ""),
+	)
+
+	var schedulerCmd = &cobra.Command{
+		Use:   "scheduler",
+		Short: "Состояние фонового обновления городов",
+		Run: func(cmd *cobra.Command, args []string) {
+			admin.scheduler()
+		},
+	}
+
+	adminCmd.AddCommand(providersCmd, schedulerCmd)
+	return adminCmd
+}
+
+// request выполняет запрос к серверу и разбирает ответ в out
+func (c *adminClient) request(method, path, reason string, out interface{}) {
+	var body bytes.Buffer
+	if reason != "" {
+		json.NewEncoder(&body).Encode(map[string]string{"reason": reason})
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(c.serverURL, "/")+path, &body)
+	if err != nil {
+		log.Fatalf("Ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.user != "" {
+		req.Header.Set("X-Admin-User", c.user)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Fatalf("Сервер недоступен: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr models.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		log.Fatalf("Ошибка %d: %s %s", resp.StatusCode, apiErr.Error, apiErr.Details)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		log.Fatalf("Ошибка разбора ответа: %v", err)
+	}
+}
+
+// run выполняет действие с провайдером и печатает состояние провайдеров
+func (c *adminClient) run(method, path, reason string) {
+	var state struct {
+		Providers []aggregator.ProviderStatus `json:"providers"`
+		Events    []aggregator.ProviderEvent  `json:"events"`
+	}
+	c.request(method, path, reason, &state)
+
+	fmt.Println("📡 Провайдеры сервера:")
+	fmt.Println(strings.Repeat("-", 30))
+	for _, p := range state.Providers {
+		mark := "✓"
+		if !p.Enabled {
+			mark = "✗"
+		}
+		fmt.Printf("%s %s\n", mark, p.Name)
+	}
+
+	if len(state.Events) > 0 {
+		fmt.Println()
+		fmt.Println("Журнал изменений:")
+		for _, e := range state.Events {
+			fmt.Printf("%s  %-8s %-16s %s", e.Time.Format("2006-01-02 15:04:05"), e.Action, e.Provider, e.Actor)
+			if e.Reason != "" {
+				fmt.Printf(" (%s)", e.Reason)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// scheduler печатает состояние фонового обновления городов
+func (c *adminClient) scheduler() {
+	var status scheduler.Status
+	c.request(http.MethodGet, "/admin/scheduler", "", &status)
+
+	clock := func(t *time.Time) string {
+		if t == nil {
+			return "—"
+		}
+		return t.Local().Format("15:04:05")
+	}
+
+	fmt.Printf("⏱ Планировщик: обновление раз в %s, запущен %s\n", status.Interval, clock(status.StartedAt))
+	fmt.Println(strings.Repeat("-", 30))
+	for _, loc := range status.Locations {
+		mark := "✓"
+		if loc.Failures > 0 {
+			mark = "✗"
+		} else if loc.LastRun == nil {
+			mark = "·"
+		}
+		fmt.Printf("%s %s, %s: последнее %s, следующее %s, ошибок %d/%d\n",
+			mark, loc.City, loc.Country, clock(loc.LastRun), clock(&loc.NextRun), loc.TotalFails, loc.TotalRuns)
+		if loc.LastError != "" {
+			fmt.Printf("    %s\n", loc.LastError)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{"Query", "/?reason=плановые+работы", `{"reason": "тело"}`, "плановые работы", false},
+		{"Body", "/", `{"reason": "квота исчерпана"}`, "квота исчерпана", false},
+		{"Empty", "/", "", "", false},
+		{"Malformed", "/", `{"reason": `, "", true},
+		{"NotObject", "/", `"причина"`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			reason, err := adminReason(r)
+			if (err != nil) != tt.wantErr || reason != tt.want {
+				t.Errorf("adminReason = %q, %v; ожидалось %q, ошибка %v", reason, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProviderActionMalformedJSON(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/providers/A/disable", strings.NewReader("{"))
+	r.SetPathValue("name", "A")
+	r.SetPathValue("action", "disable")
+	w := httptest.NewRecorder()
+
+	// До обращения к агрегатору: agg в тесте не создан
+	providerActionHandler(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("статус %d, ожидался 400", w.Code)
+	}
+}
@@ -3,19 +3,44 @@ package aggregator
 import (
 	"context"
 	"fmt"
+	"log"
 	"math"
 	"sync"
 	"time"
 
+	"weather-aggregator/airquality"
+	"weather-aggregator/conditions"
+	"weather-aggregator/derivations"
+	"weather-aggregator/geo"
 	"weather-aggregator/models"
 	"weather-aggregator/providers"
 )
 
 type Aggregator struct {
-	providers []providers.Provider
-	cache     map[string]cacheEntry
-	cacheMu   sync.RWMutex
-	cacheTTL  time.Duration
+	providers   []*providerEntry
+	providersMu sync.RWMutex
+	events      []ProviderEvent
+	eventLog    EventLog
+	middlewares []providers.Middleware
+	cache       map[string]cacheEntry
+	cacheMu     sync.RWMutex
+	cacheTTL    time.Duration
+
+	alertSources  []providers.AlertSource
+	alertCache    map[string]alertCacheEntry
+	alertsEnabled bool
+	iconBaseURL   string
+	geocoder      geo.Geocoder
+	recorder      Recorder
+	weigher       Weigher
+	corrector     Corrector
+	climatology   Climatology
+
+	forecastRecorder ForecastRecorder
+	forecastInterval time.Duration
+	forecastMaxLead  time.Duration
+	forecastAt       map[string]time.Time
+	forecastMu       sync.Mutex
 }
 
 type cacheEntry struct {
@@ -25,16 +50,40 @@ type cacheEntry struct {
 
 func NewAggregator(cacheDurationMinutes int) *Aggregator {
 	return &Aggregator{
-		providers: make([]providers.Provider, 0),
+		providers: make([]*providerEntry, 0),
 		cache:     make(map[string]cacheEntry),
 		cacheTTL:  time.Duration(cacheDurationMinutes) * time.Minute,
+
+		alertCache:    make(map[string]alertCacheEntry),
+		alertsEnabled: true,
+		forecastAt:    make(map[string]time.Time),
 	}
 }
 
-// AddProvider добавляет провайдера
-func (a *Aggregator) AddProvider(provider providers.Provider) {
+// Use добавляет middleware, которыми оборачиваются все провайдеры,
+// добавленные после вызова. Общие middleware оказываются снаружи
+// middleware конкретного провайдера.
+func (a *Aggregator) Use(mws ...providers.Middleware) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.middlewares = append(a.middlewares, mws...)
+}
+
+// AddProvider добавляет провайдера, обернутого общими middleware и mws
+func (a *Aggregator) AddProvider(provider providers.Provider, mws ...providers.Middleware) {
 	if provider.IsAvailable() {
-		a.providers = append(a.providers, provider)
+		a.providersMu.Lock()
+		defer a.providersMu.Unlock()
+
+		chain := make([]providers.Middleware, 0, len(a.middlewares)+len(mws))
+		chain = append(chain, a.middlewares...)
+		chain = append(chain, mws...)
+		a.providers = append(a.providers, &providerEntry{
+			provider:  providers.Chain(provider, chain...),
+			enabled:   true,
+			changedAt: time.Now(),
+		})
 	}
 }
 
@@ -47,16 +96,36 @@ func (a *Aggregator) GetWeather(ctx context.Context, city, country string) (*mod
 		return cached, nil
 	}
 
-	if len(a.providers) == 0 {
+	return a.Refresh(ctx, city, country)
+}
+
+// Refresh запрашивает погоду у провайдеров без учета кеша и обновляет кеш
+// (например, для фонового обновления отслеживаемых городов)
+func (a *Aggregator) Refresh(ctx context.Context, city, country string) (*models.AggregatedWeather, error) {
+	cacheKey := fmt.Sprintf("%s,%s", city, country)
+
+	active := a.activeProviders()
+	if len(active) == 0 {
 		return nil, fmt.Errorf("нет доступных провайдеров")
 	}
 
+	// Предупреждения запрашиваются параллельно с погодой; их ошибки не
+	// мешают ответу
+	alertsDone := make(chan []models.Alert, 1)
+	go func() {
+		alerts, err := a.GetAlerts(ctx, city, country)
+		if err != nil {
+			log.Printf("предупреждения для %s недоступны: %v", city, err)
+		}
+		alertsDone <- alerts
+	}()
+
 	var wg sync.WaitGroup
-	results := make(chan *models.WeatherData, len(a.providers))
-	errors := make(chan error, len(a.providers))
+	results := make(chan *models.WeatherData, len(active))
+	errors := make(chan error, len(active))
 
 	// Запускаем запросы ко всем провайдерам параллельно
-	for _, provider := range a.providers {
+	for _, provider := range active {
 		wg.Add(1)
 		go func(p providers.Provider) {
 			defer wg.Done()
@@ -71,6 +140,12 @@ func (a *Aggregator) GetWeather(ctx context.Context, city, country string) (*mod
 					errors <- fmt.Errorf("%s: %w", p.Name(), err)
 					return
 				}
+				// Некорректный ответ не должен испортить среднее значение
+				if err := validateWeather(weather, time.Now()); err != nil {
+					errors <- fmt.Errorf("%s: %w", p.Name(), err)
+					return
+				}
+				dropInvalidExtended(weather)
 				results <- weather
 			}
 		}(provider)
@@ -100,50 +175,258 @@ func (a *Aggregator) GetWeather(ctx context.Context, city, country string) (*mod
 		return nil, fmt.Errorf("не удалось получить данные от провайдеров")
 	}
 
-	// Агрегируем данные
-	aggregated := a.aggregateWeather(weatherData, city, country)
+	// Агрегируем данные с поправками систематических ошибок провайдеров
+	corrected, corrections := a.correct(city, country, weatherData)
+	astro := a.astronomy(ctx, city, country, corrected, time.Now())
+	aggregated := a.aggregateWeather(corrected, city, country, astro)
+	aggregated.Corrections = corrections
+	aggregated.Anomalies = a.anomalies(city, country, aggregated)
+	aggregated.Alerts = <-alertsDone
 
-	// Сохраняем в кеш
+	// Сохраняем в кеш; в историю попадают исходные ответы, чтобы поправки
+	// рассчитывались по ним, а не по уже исправленным значениям
 	a.saveToCache(cacheKey, aggregated)
+	a.record(city, country, aggregated, weatherData)
+	a.recordForecasts(ctx, city, country)
 
 	return aggregated, nil
 }
 
+// Допустимое расхождение времени наблюдения с текущим временем
+const (
+	maxFutureSkew     = 10 * time.Minute
+	maxObservationAge = 6 * time.Hour
+)
+
+// validateWeather отбрасывает физически невозможные значения и устаревшие
+// или пришедшие "из будущего" наблюдения
+func validateWeather(d *models.WeatherData, now time.Time) error {
+	if d == nil {
+		return fmt.Errorf("пустой ответ провайдера")
+	}
+
+	checks := []struct {
+		name     string
+		value    *float64
+		min, max float64
+	}{
+		{"температура", d.Temperature, -90, 60},
+		{"ощущаемая температура", d.FeelsLike, -110, 80},
+		{"влажность", d.Humidity, 0, 100},
+		{"давление", d.Pressure, 850, 1090},
+		{"скорость ветра", d.WindSpeed, 0, 120},
+		{"направление ветра", d.WindDirection, 0, 360},
+	}
+
+	// Отсутствующие измерения допустимы, но хотя бы одно должно быть
+	measured := false
+	for _, c := range checks {
+		if c.value == nil {
+			continue
+		}
+		measured = true
+		if v := *c.value; math.IsNaN(v) || math.IsInf(v, 0) || v < c.min || v > c.max {
+			return fmt.Errorf("некорректные данные: %s = %v", c.name, v)
+		}
+	}
+	if !measured {
+		return fmt.Errorf("нет данных измерений")
+	}
+
+	if d.Timestamp.After(now.Add(maxFutureSkew)) {
+		return fmt.Errorf("некорректные данные: время наблюдения %s в будущем", d.Timestamp.Format(time.RFC3339))
+	}
+	if !d.Timestamp.IsZero() && d.Timestamp.Before(now.Add(-maxObservationAge)) {
+		return fmt.Errorf("устаревшие данные: время наблюдения %s", d.Timestamp.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
 // aggregateWeather агрегирует данные от разных провайдеров
-func (a *Aggregator) aggregateWeather(data []*models.WeatherData, city, country string) *models.AggregatedWeather {
+func (a *Aggregator) aggregateWeather(data []*models.WeatherData, city, country string, astro *models.Astronomy) *models.AggregatedWeather {
 	aggregated := &models.AggregatedWeather{
 		Location:    fmt.Sprintf("%s, %s", city, country),
+		Astronomy:   astro,
 		LastUpdated: time.Now(),
 		Providers:   make([]string, 0, len(data)),
 	}
 
-	// Собираем значения для агрегации
-	var temps, feelsLike, humidity, pressure, windSpeed []float64
 	var descriptions []string
+	var codes []string
 
 	for _, d := range data {
 		aggregated.Providers = append(aggregated.Providers, d.Provider)
-		temps = append(temps, d.Temperature)
-		feelsLike = append(feelsLike, d.FeelsLike)
-		humidity = append(humidity, float64(d.Humidity))
-		pressure = append(pressure, float64(d.Pressure))
-		windSpeed = append(windSpeed, d.WindSpeed)
-		descriptions = append(descriptions, d.Description)
+		aggregated.Sources = append(aggregated.Sources, models.SourceInfo{
+			Provider:   d.Provider,
+			Station:    d.Station,
+			ObservedAt: d.Timestamp,
+		})
+		if d.Description != "" {
+			descriptions = append(descriptions, d.Description)
+		}
+		codes = append(codes, d.Condition)
 	}
 
-	// Агрегируем температуру
-	aggregated.Temperature = aggregateValues(temps)
-	aggregated.FeelsLike = aggregateValues(feelsLike)
-	aggregated.Humidity = aggregateValues(humidity)
-	aggregated.Pressure = aggregateValues(pressure)
-	aggregated.WindSpeed = aggregateValues(windSpeed)
+	// Каждое измерение агрегируется только по источникам, которые его сообщили
+	aggregated.Temperature = aggregateField(data, func(d *models.WeatherData) *float64 { return d.Temperature })
+	aggregated.FeelsLike = aggregateField(data, func(d *models.WeatherData) *float64 { return d.FeelsLike })
+	aggregated.Humidity = aggregateField(data, func(d *models.WeatherData) *float64 { return d.Humidity })
+	aggregated.Pressure = aggregateField(data, func(d *models.WeatherData) *float64 { return d.Pressure })
+	aggregated.WindSpeed = aggregateField(data, func(d *models.WeatherData) *float64 { return d.WindSpeed })
+	a.applyWeights(aggregated, data, city, country)
+
+	// Провайдеры считают "ощущается как" по разным формулам, поэтому
+	// производные величины рассчитываются заново по средним значениям
+	aggregated.Derived = derivations.Compute(average(aggregated.Temperature),
+		average(aggregated.Humidity), average(aggregated.WindSpeed))
+
+	// Явление выбирается по общим кодам: тексты провайдеров на разных языках
+	// и с разной детализацией не совпадают. Тексты остаются запасным вариантом
+	// для источников без кода.
+	aggregated.Condition = conditions.Consensus(codes)
+	aggregated.Description = conditions.Describe(aggregated.Condition, "ru")
+	if aggregated.Description == "" {
+		aggregated.Description = mostFrequent(descriptions)
+	}
+	aggregated.IconURL = a.iconURL(aggregated.Condition, data, astro, aggregated.LastUpdated)
 
-	// Выбираем наиболее частую погоду
-	aggregated.Description = mostFrequent(descriptions)
+	aggregated.AirQuality = aggregateAirQuality(data)
+	aggregateExtended(aggregated, data)
 
 	return aggregated
 }
 
+// aggregateField агрегирует измерение по источникам, которые его сообщили;
+// nil, если не сообщил никто
+func aggregateField(data []*models.WeatherData, get func(d *models.WeatherData) *float64) *models.AggregatedValue {
+	var values []float64
+	for _, d := range data {
+		if v := get(d); v != nil {
+			values = append(values, *v)
+		}
+	}
+	return aggregateOptional(values)
+}
+
+// extendedRanges допустимые значения дополнительных параметров
+var extendedRanges = []struct {
+	name     string
+	field    func(d *models.WeatherData) **float64
+	min, max float64
+}{
+	{"порывы ветра", func(d *models.WeatherData) **float64 { return &d.WindGust }, 0, 150},
+	{"точка росы", func(d *models.WeatherData) **float64 { return &d.DewPoint }, -90, 40},
+	{"облачность", func(d *models.WeatherData) **float64 { return &d.CloudCover }, 0, 100},
+	{"видимость", func(d *models.WeatherData) **float64 { return &d.Visibility }, 0, 500},
+	{"УФ индекс", func(d *models.WeatherData) **float64 { return &d.UVIndex }, 0, 20},
+	{"осадки", func(d *models.WeatherData) **float64 { return &d.Precipitation }, 0, 500},
+	{"дождь", func(d *models.WeatherData) **float64 { return &d.Rain }, 0, 500},
+	{"снег", func(d *models.WeatherData) **float64 { return &d.Snow }, 0, 500},
+}
+
+// dropInvalidExtended убирает некорректные дополнительные параметры: в отличие
+// от основных, из-за них ответ провайдера целиком не отбрасывается
+func dropInvalidExtended(d *models.WeatherData) {
+	for _, r := range extendedRanges {
+		field := r.field(d)
+		if v := *field; v != nil && (math.IsNaN(*v) || math.IsInf(*v, 0) || *v < r.min || *v > r.max) {
+			log.Printf("%s: некорректные данные: %s = %v", d.Provider, r.name, *v)
+			*field = nil
+		}
+	}
+}
+
+// aggregateExtended агрегирует дополнительные параметры по источникам,
+// которые их сообщили
+func aggregateExtended(aggregated *models.AggregatedWeather, data []*models.WeatherData) {
+	aggregated.WindGust = aggregateField(data, func(d *models.WeatherData) *float64 { return d.WindGust })
+	aggregated.DewPoint = aggregateField(data, func(d *models.WeatherData) *float64 { return d.DewPoint })
+	aggregated.CloudCover = aggregateField(data, func(d *models.WeatherData) *float64 { return d.CloudCover })
+	aggregated.Visibility = aggregateField(data, func(d *models.WeatherData) *float64 { return d.Visibility })
+	aggregated.UVIndex = aggregateField(data, func(d *models.WeatherData) *float64 { return d.UVIndex })
+	aggregated.Precipitation = aggregateField(data, func(d *models.WeatherData) *float64 { return d.Precipitation })
+	aggregated.Rain = aggregateField(data, func(d *models.WeatherData) *float64 { return d.Rain })
+	aggregated.Snow = aggregateField(data, func(d *models.WeatherData) *float64 { return d.Snow })
+}
+
+// aggregateAirQuality агрегирует концентрации загрязнителей по источникам,
+// которые их сообщили, и рассчитывает индексы по средним значениям; nil,
+// если качество воздуха не сообщил никто
+func aggregateAirQuality(data []*models.WeatherData) *models.AggregatedAirQuality {
+	var aqi, pm25, pm10, o3, no2, so2, co []float64
+	collect := func(values []float64, v *float64) []float64 {
+		if v == nil {
+			return values
+		}
+		return append(values, *v)
+	}
+
+	for _, d := range data {
+		aq := d.AirQuality
+		if aq == nil || !validAirQuality(aq) {
+			continue
+		}
+		if aq.AQI != nil {
+			aqi = append(aqi, float64(*aq.AQI))
+		}
+		pm25 = collect(pm25, aq.PM25)
+		pm10 = collect(pm10, aq.PM10)
+		o3 = collect(o3, aq.O3)
+		no2 = collect(no2, aq.NO2)
+		so2 = collect(so2, aq.SO2)
+		co = collect(co, aq.CO)
+	}
+
+	result := &models.AggregatedAirQuality{
+		AQI:  aggregateOptional(aqi),
+		PM25: aggregateOptional(pm25),
+		PM10: aggregateOptional(pm10),
+		O3:   aggregateOptional(o3),
+		NO2:  aggregateOptional(no2),
+		SO2:  aggregateOptional(so2),
+		CO:   aggregateOptional(co),
+	}
+
+	averaged := &models.AirQuality{
+		PM25: average(result.PM25),
+		PM10: average(result.PM10),
+		O3:   average(result.O3),
+		NO2:  average(result.NO2),
+		SO2:  average(result.SO2),
+		CO:   average(result.CO),
+	}
+	result.USEPA = airquality.USEPA(averaged)
+	result.CAQI = airquality.CAQI(averaged)
+
+	// Источник может сообщить только готовый индекс без концентраций
+	if result.AQI == nil && result.USEPA == nil && result.CAQI == nil &&
+		result.PM25 == nil && result.PM10 == nil && result.O3 == nil &&
+		result.NO2 == nil && result.SO2 == nil && result.CO == nil {
+		return nil
+	}
+	return result
+}
+
+// validAirQuality отбрасывает отрицательные и нечисловые концентрации
+func validAirQuality(aq *models.AirQuality) bool {
+	for _, v := range []*float64{aq.PM25, aq.PM10, aq.O3, aq.NO2, aq.SO2, aq.CO} {
+		if v != nil && (math.IsNaN(*v) || math.IsInf(*v, 0) || *v < 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// aggregateOptional как aggregateValues, но nil для пустого набора
+func aggregateOptional(values []float64) *models.AggregatedValue {
+	if len(values) == 0 {
+		return nil
+	}
+	v := aggregateValues(values)
+	return &v
+}
+
 // aggregateValues вычисляет среднее, мин и макс
 func aggregateValues(values []float64) models.AggregatedValue {
 	if len(values) == 0 {
@@ -168,11 +451,21 @@ func aggregateValues(values []float64) models.AggregatedValue {
 		Average: sum / float64(len(values)),
 		Min:     min,
 		Max:     max,
+		Count:   len(values),
 		Values:  values,
 	}
 }
 
-// mostFrequent находит наиболее частое значение
+// average среднее агрегированного значения или nil, если его нет
+func average(v *models.AggregatedValue) *float64 {
+	if v == nil {
+		return nil
+	}
+	return &v.Average
+}
+
+// mostFrequent находит наиболее частое значение; при равенстве побеждает
+// встретившееся первым, чтобы результат не зависел от порядка обхода map
 func mostFrequent(values []string) string {
 	freq := make(map[string]int)
 	for _, v := range values {
@@ -181,9 +474,9 @@ func mostFrequent(values []string) string {
 
 	maxFreq := 0
 	var result string
-	for v, f := range freq {
-		if f > maxFreq {
-			maxFreq = f
+	for _, v := range values {
+		if freq[v] > maxFreq {
+			maxFreq = freq[v]
 			result = v
 		}
 	}
@@ -226,16 +519,19 @@ func (a *Aggregator) ClearCache() {
 	defer a.cacheMu.Unlock()
 
 	a.cache = make(map[string]cacheEntry)
+	a.alertCache = make(map[string]alertCacheEntry)
 }
 
+// GetProviderCount возвращает количество включенных провайдеров
 func (a *Aggregator) GetProviderCount() int {
-	return len(a.providers)
+	return len(a.activeProviders())
 }
 
-// GetProvidersInfo возвращает информацию о провайдерах
+// GetProvidersInfo возвращает имена включенных провайдеров
 func (a *Aggregator) GetProvidersInfo() []string {
-	info := make([]string, len(a.providers))
-	for i, provider := range a.providers {
+	active := a.activeProviders()
+	info := make([]string, len(active))
+	for i, provider := range active {
 		info[i] = provider.Name()
 	}
 	return info
@@ -0,0 +1,57 @@
+package aggregator
+
+import (
+	"testing"
+
+	"weather-aggregator/models"
+)
+
+func TestAggregateAirQuality(t *testing.T) {
+	aqi := func(v int) *int { return &v }
+
+	t.Run("None", func(t *testing.T) {
+		data := []*models.WeatherData{{Provider: "A"}, {Provider: "B", AirQuality: &models.AirQuality{}}}
+		if aq := aggregateAirQuality(data); aq != nil {
+			t.Errorf("ожидается nil без данных о качестве воздуха, получено %+v", aq)
+		}
+	})
+
+	t.Run("IndexOnly", func(t *testing.T) {
+		data := []*models.WeatherData{
+			{Provider: "A", AirQuality: &models.AirQuality{AQI: aqi(40)}},
+			{Provider: "B", AirQuality: &models.AirQuality{AQI: aqi(60)}},
+		}
+		aq := aggregateAirQuality(data)
+		if aq == nil || aq.AQI == nil {
+			t.Fatalf("индекс провайдеров потерян: %+v", aq)
+		}
+		if aq.AQI.Average != 50 || aq.AQI.Count != 2 {
+			t.Errorf("AQI = %+v, ожидается среднее 50 по двум источникам", aq.AQI)
+		}
+		if aq.USEPA != nil || aq.CAQI != nil {
+			t.Errorf("индексы без концентраций: USEPA %+v, CAQI %+v", aq.USEPA, aq.CAQI)
+		}
+	})
+
+	t.Run("Concentrations", func(t *testing.T) {
+		data := []*models.WeatherData{
+			{Provider: "A", AirQuality: &models.AirQuality{PM25: models.Float(10), PM10: models.Float(20)}},
+			{Provider: "B", AirQuality: &models.AirQuality{PM25: models.Float(20)}},
+			// Отрицательная концентрация отбрасывает ответ источника целиком
+			{Provider: "C", AirQuality: &models.AirQuality{AQI: aqi(300), PM25: models.Float(-5)}},
+		}
+		aq := aggregateAirQuality(data)
+		if aq == nil || aq.PM25 == nil || aq.PM10 == nil {
+			t.Fatalf("концентрации потеряны: %+v", aq)
+		}
+		if aq.PM25.Average != 15 || aq.PM25.Count != 2 || aq.PM10.Count != 1 {
+			t.Errorf("PM2.5 %+v, PM10 %+v", aq.PM25, aq.PM10)
+		}
+		if aq.AQI != nil {
+			t.Errorf("AQI = %+v из некорректного ответа", aq.AQI)
+		}
+		if aq.USEPA == nil {
+			t.Error("USEPA не рассчитан по средним концентрациям")
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"weather-aggregator/models"
+	"weather-aggregator/providers"
+)
+
+// alertCacheEntry закешированные предупреждения для города
+type alertCacheEntry struct {
+	alerts    []models.Alert
+	timestamp time.Time
+}
+
+// AddAlertSource добавляет источник предупреждений, не являющийся
+// провайдером погоды (например, ленту CAP)
+func (a *Aggregator) AddAlertSource(source providers.AlertSource) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.alertSources = append(a.alertSources, source)
+}
+
+// SetAlertsEnabled включает или отключает сбор предупреждений
+func (a *Aggregator) SetAlertsEnabled(enabled bool) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.alertsEnabled = enabled
+}
+
+// activeAlertSources возвращает источники предупреждений: включенные провайдеры,
+// которые их поддерживают, и отдельные источники
+func (a *Aggregator) activeAlertSources() []providers.AlertSource {
+	a.providersMu.RLock()
+	enabled := a.alertsEnabled
+	a.providersMu.RUnlock()
+	if !enabled {
+		return nil
+	}
+
+	var sources []providers.AlertSource
+	for _, p := range a.activeProviders() {
+		if source, ok := providers.AsAlertSource(p); ok {
+			sources = append(sources, source)
+		}
+	}
+
+	a.providersMu.RLock()
+	sources = append(sources, a.alertSources...)
+	a.providersMu.RUnlock()
+
+	return sources
+}
+
+// GetAlerts собирает действующие предупреждения из всех источников,
+// объединяет дубликаты и сортирует по серьезности
+func (a *Aggregator) GetAlerts(ctx context.Context, city, country string) ([]models.Alert, error) {
+	cacheKey := fmt.Sprintf("%s,%s", city, country)
+
+	a.cacheMu.RLock()
+	cached, found := a.alertCache[cacheKey]
+	a.cacheMu.RUnlock()
+	if found && time.Since(cached.timestamp) < a.cacheTTL {
+		return cached.alerts, nil
+	}
+
+	sources := a.activeAlertSources()
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		alerts []models.Alert
+		errs   []string
+	)
+	for _, source := range sources {
+		wg.Add(1)
+		go func(s providers.AlertSource) {
+			defer wg.Done()
+
+			result, err := s.GetAlerts(ctx, city, country)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", s.Name(), err))
+				return
+			}
+			alerts = append(alerts, result...)
+		}(source)
+	}
+	wg.Wait()
+
+	if len(errs) == len(sources) {
+		return nil, fmt.Errorf("все источники предупреждений вернули ошибки: %v", errs)
+	}
+
+	alerts = mergeAlerts(alerts, time.Now())
+
+	a.cacheMu.Lock()
+	a.alertCache[cacheKey] = alertCacheEntry{alerts: alerts, timestamp: time.Now()}
+	a.cacheMu.Unlock()
+
+	return alerts, nil
+}
+
+// severityRank порядок серьезности для сортировки и выбора версии дубликата
+var severityRank = map[string]int{
+	models.SeverityExtreme:  4,
+	models.SeveritySevere:   3,
+	models.SeverityModerate: 2,
+	models.SeverityMinor:    1,
+}
+
+// hazardKeywords ключевые слова типов явлений на английском и русском.
+// Разные источники называют одно явление по-разному ("Wind Advisory",
+// "Сильный ветер"), поэтому дубликаты ищутся по типу явления.
+var hazardKeywords = []struct {
+	hazard   string
+	keywords []string
+}{
+	{"thunderstorm", []string{"thunder", "гроз"}},
+	{"tornado", []string{"tornado", "смерч", "торнадо"}},
+	{"hurricane", []string{"hurricane", "typhoon", "tropical", "ураган", "тайфун"}},
+	{"flood", []string{"flood", "паводок", "наводнен", "подтоплен"}},
+	{"fire", []string{"fire", "пожар", "пожаро"}},
+	{"fog", []string{"fog", "туман"}},
+	{"heat", []string{"heat", "high temperature", "жара", "зной", "высокая температура"}},
+	{"frost", []string{"frost", "freeze", "cold", "chill", "low temperature", "заморозк", "мороз", "низкая температура"}},
+	{"snow", []string{"snow", "blizzard", "winter", "снег", "метель", "вьюг"}},
+	{"ice", []string{"ice storm", "black ice", "icing", "icy", "freezing rain", "гололед", "гололёд", "налипани"}},
+	{"wind", []string{"wind", "gale", "storm", "ветер", "шторм", "буря"}},
+	{"rain", []string{"rain", "ливень", "ливн", "дожд", "осадк"}},
+	{"coastal", []string{"coastal", "surf", "tide", "wave", "прибреж", "волн"}},
+	{"air", []string{"air quality", "smoke", "dust", "качество воздуха", "дым", "пыль"}},
+}
+
+// alertHazard определяет тип явления по названию предупреждения
+func alertHazard(alert models.Alert) string {
+	text := strings.ToLower(alert.Event + " " + alert.Headline)
+	for _, h := range hazardKeywords {
+		for _, keyword := range h.keywords {
+			if strings.Contains(text, keyword) {
+				return h.hazard
+			}
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(alert.Event))
+}
+
+// mergeAlerts убирает истекшие предупреждения и объединяет дубликаты: одно
+// явление с пересекающимися интервалами действия. Остается версия с большей
+// серьезностью (при равенстве - с более подробным описанием), области и
+// источники объединяются.
+func mergeAlerts(alerts []models.Alert, now time.Time) []models.Alert {
+	var merged []models.Alert
+	var hazards []string
+
+	for _, alert := range alerts {
+		if !alert.Expires.IsZero() && alert.Expires.Before(now) {
+			continue
+		}
+		if alert.Severity == "" {
+			alert.Severity = models.SeverityUnknown
+		}
+		if alert.Urgency == "" {
+			alert.Urgency = models.UrgencyUnknown
+		}
+		hazard := alertHazard(alert)
+
+		duplicate := -1
+		for i, existing := range merged {
+			if hazards[i] == hazard && (sameID(existing, alert) || overlaps(existing, alert)) {
+				duplicate = i
+				break
+			}
+		}
+
+		if duplicate < 0 {
+			alert.Sources = []string{alert.Source}
+			merged = append(merged, alert)
+			hazards = append(hazards, hazard)
+			continue
+		}
+
+		existing := merged[duplicate]
+		winner, other := existing, alert
+		if preferAlert(alert, existing) {
+			winner, other = alert, existing
+		}
+		winner.Sources = appendUnique(existing.Sources, alert.Source)
+		winner.Areas = appendUnique(winner.Areas, other.Areas...)
+		if winner.Onset.IsZero() || (!other.Onset.IsZero() && other.Onset.Before(winner.Onset)) {
+			winner.Onset = other.Onset
+		}
+		if other.Expires.After(winner.Expires) {
+			winner.Expires = other.Expires
+		}
+		merged[duplicate] = winner
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		ri, rj := severityRank[merged[i].Severity], severityRank[merged[j].Severity]
+		if ri != rj {
+			return ri > rj
+		}
+		return merged[i].Onset.Before(merged[j].Onset)
+	})
+
+	return merged
+}
+
+func sameID(a, b models.Alert) bool {
+	return a.ID != "" && a.ID == b.ID
+}
+
+// overlaps проверяет пересечение интервалов действия; открытый конец
+// интервала считается бесконечным
+func overlaps(a, b models.Alert) bool {
+	aEnd, bEnd := a.Expires, b.Expires
+	if (aEnd.IsZero() || !b.Onset.After(aEnd)) && (bEnd.IsZero() || !a.Onset.After(bEnd)) {
+		return true
+	}
+	return false
+}
+
+// preferAlert выбирает версию дубликата для показа
+func preferAlert(candidate, current models.Alert) bool {
+	rc, rk := severityRank[candidate.Severity], severityRank[current.Severity]
+	if rc != rk {
+		return rc > rk
+	}
+	return len(candidate.Description) > len(current.Description)
+}
+
+func appendUnique(list []string, values ...string) []string {
+	result := append([]string(nil), list...)
+	for _, v := range values {
+		found := false
+		for _, existing := range result {
+			if strings.EqualFold(existing, v) {
+				found = true
+				break
+			}
+		}
+		if !found && v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
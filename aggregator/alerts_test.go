@@ -0,0 +1,132 @@
+package aggregator_test
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-aggregator/aggregator"
+	"weather-aggregator/models"
+	"weather-aggregator/providers"
+	"weather-aggregator/providers/chaos"
+)
+
+// alertProvider дополнительно отдает предупреждения и считает их запросы
+type alertProvider struct {
+	fakeProvider
+	event    string
+	requests atomic.Int32
+}
+
+func (p *alertProvider) GetAlerts(ctx context.Context, city, country string) ([]models.Alert, error) {
+	p.requests.Add(1)
+	return []models.Alert{{
+		Event:    p.event,
+		Severity: models.SeverityModerate,
+		Expires:  time.Now().Add(time.Hour),
+		Source:   p.name,
+	}}, nil
+}
+
+// TestAlertsThroughMiddleware проверяет, что запросы предупреждений проходят
+// через middleware провайдера, включая внедрение сбоев
+func TestAlertsThroughMiddleware(t *testing.T) {
+	ctl := chaos.NewController()
+	if err := ctl.Set("Faulty", chaos.Config{Enabled: true, ErrorRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var logged strings.Builder
+	good := &alertProvider{fakeProvider: fakeProvider{name: "Good"}, event: "Гололед"}
+	faulty := &alertProvider{fakeProvider: fakeProvider{name: "Faulty"}, event: "Ураган"}
+
+	agg := aggregator.NewAggregator(10)
+	agg.Use(providers.Logging(log.New(&logged, "", 0)), providers.Timing(providers.NewTimingStats()), ctl.Middleware())
+	agg.AddProvider(good)
+	agg.AddProvider(faulty)
+	// Провайдер без предупреждений не становится их источником из-за оберток
+	agg.AddProvider(&fakeProvider{name: "NoAlerts"})
+
+	alerts, err := agg.GetAlerts(context.Background(), "Москва", "RU")
+	if err != nil {
+		t.Fatalf("GetAlerts: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Event != "Гололед" {
+		t.Fatalf("предупреждения %+v, ожидалось одно от Good", alerts)
+	}
+	if n := faulty.requests.Load(); n != 0 {
+		t.Errorf("внедренная ошибка не остановила запрос предупреждений Faulty: %d запросов", n)
+	}
+	if !strings.Contains(logged.String(), "Good: предупреждения Москва,RU - 1") {
+		t.Errorf("запрос предупреждений не прошел через logging:\n%s", logged.String())
+	}
+	if strings.Contains(logged.String(), "NoAlerts") {
+		t.Errorf("у провайдера без предупреждений запрошены предупреждения:\n%s", logged.String())
+	}
+}
+
+// TestCAPAlertsMerged проверяет, что одно предупреждение CAP из нескольких
+// лент показывается один раз, а истекшие отбрасываются
+func TestCAPAlertsMerged(t *testing.T) {
+	alert, err := os.ReadFile("../capfeed/testdata/alert.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded := strings.TrimPrefix(string(alert), `<?xml version="1.0" encoding="UTF-8"?>`)
+	feed := `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:cap="urn:oasis:names:tc:emergency:cap:1.2">
+  <entry><id>storm</id><content type="application/cap+xml">` + embedded + `</content></entry>
+  <entry>
+    <id>expired-fog</id>
+    <title>Туман</title>
+    <cap:event>Туман</cap:event>
+    <cap:severity>Moderate</cap:severity>
+    <cap:expires>2020-01-01T00:00:00Z</cap:expires>
+    <cap:areaDesc>Москва</cap:areaDesc>
+  </entry>
+</feed>`
+
+	serve := func(body string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	document := providers.NewCAPSource(serve(string(alert)).URL, "ru", nil)
+	atom := providers.NewCAPSource(serve(feed).URL, "ru", nil)
+
+	agg := aggregator.NewAggregator(10)
+	agg.AddAlertSource(document)
+	agg.AddAlertSource(atom)
+
+	alerts, err := agg.GetAlerts(context.Background(), "Москва", "RU")
+	if err != nil {
+		t.Fatalf("GetAlerts: %v", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("предупреждения %+v, ожидалось одно без истекшего и дубликата", alerts)
+	}
+	got := alerts[0]
+	if got.ID != "urn:oid:2.49.0.1.643.0.2099.1" || got.Event != "Сильный ветер" || got.Severity != models.SeveritySevere {
+		t.Errorf("предупреждение %+v", got)
+	}
+	sources := append([]string(nil), got.Sources...)
+	sort.Strings(sources)
+	want := []string{document.Name(), atom.Name()}
+	sort.Strings(want)
+	if strings.Join(sources, ",") != strings.Join(want, ",") {
+		t.Errorf("источники %v, ожидалось %v", got.Sources, want)
+	}
+	if !reflect.DeepEqual(got.Areas, []string{"Москва", "Московская область"}) {
+		t.Errorf("области %v", got.Areas)
+	}
+}
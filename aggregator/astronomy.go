@@ -0,0 +1,75 @@
+package aggregator
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"weather-aggregator/astronomy"
+	"weather-aggregator/geo"
+	"weather-aggregator/models"
+)
+
+// maxAstronomyDeviation расхождение восхода или заката провайдера с расчетом,
+// после которого оно записывается в лог: обычно это ошибка координат или
+// часового пояса у провайдера
+const maxAstronomyDeviation = 10 * time.Minute
+
+// SetGeocoder задает геокодер для расчета астрономических данных. Без него
+// ответ не содержит поле astronomy.
+func (a *Aggregator) SetGeocoder(geocoder geo.Geocoder) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.geocoder = geocoder
+}
+
+// astronomy рассчитывает положение Солнца и Луны для города на текущие сутки
+// и сверяет с ним восход и закат, сообщенные провайдерами
+func (a *Aggregator) astronomy(ctx context.Context, city, country string, data []*models.WeatherData, now time.Time) *models.Astronomy {
+	a.providersMu.RLock()
+	geocoder := a.geocoder
+	a.providersMu.RUnlock()
+	if geocoder == nil {
+		return nil
+	}
+
+	loc, err := geocoder.Resolve(ctx, city, country)
+	if err != nil {
+		log.Printf("астрономические данные для %s недоступны: %v", city, err)
+		return nil
+	}
+
+	zone := astronomy.SolarZone(loc.Lon)
+	astro := astronomy.Compute(now.In(zone), loc.Lat, loc.Lon)
+
+	for _, d := range data {
+		check := func(event string, reported *time.Time, computed func(astronomy.SunTimes) *time.Time) {
+			if reported == nil {
+				return
+			}
+			// Провайдер может сообщить восход на другие сутки, поэтому
+			// расчет ведется на дату сообщенного события
+			expected := computed(astronomy.Sun(reported.In(zone), loc.Lat, loc.Lon))
+			if expected == nil {
+				return
+			}
+			diff := reported.Sub(*expected)
+			astro.Deviations = append(astro.Deviations, models.AstronomyDeviation{
+				Provider: d.Provider,
+				Event:    event,
+				Reported: *reported,
+				Minutes:  math.Round(diff.Minutes()*10) / 10,
+			})
+			if diff > maxAstronomyDeviation || diff < -maxAstronomyDeviation {
+				log.Printf("%s: %s %s отличается от расчета на %.0f мин",
+					d.Provider, event, reported.In(zone).Format("15:04"), diff.Minutes())
+			}
+		}
+		check("sunrise", d.Sunrise, func(s astronomy.SunTimes) *time.Time { return s.Sunrise })
+		check("sunset", d.Sunset, func(s astronomy.SunTimes) *time.Time { return s.Sunset })
+	}
+
+	return astro
+}
@@ -0,0 +1,133 @@
+package aggregator_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"weather-aggregator/aggregator"
+	"weather-aggregator/models"
+	"weather-aggregator/providers/chaos"
+)
+
+// fakeProvider отдает фиксированную погоду со временем наблюдения "сейчас"
+type fakeProvider struct {
+	name        string
+	temperature float64
+}
+
+func (p *fakeProvider) Name() string      { return p.name }
+func (p *fakeProvider) IsAvailable() bool { return true }
+
+func (p *fakeProvider) GetWeather(ctx context.Context, city, country string) (*models.WeatherData, error) {
+	return &models.WeatherData{
+		Provider:      p.name,
+		Location:      city + ", " + country,
+		Temperature:   models.Float(p.temperature),
+		FeelsLike:     models.Float(p.temperature - 3),
+		Humidity:      models.Float(80),
+		Pressure:      models.Float(1015),
+		WindSpeed:     models.Float(3),
+		WindDirection: models.Float(180),
+		Timestamp:     time.Now(),
+		Units:         "metric",
+	}, nil
+}
+
+// TestChaosFaultyProviderDropped проверяет, что ответ провайдера со
+// внедренным сбоем не попадает в агрегат, а остальные агрегируются
+func TestChaosFaultyProviderDropped(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   chaos.Config
+		attempts int
+	}{
+		{"Error", chaos.Config{Enabled: true, ErrorRate: 1}, 1},
+		{"Timeout", chaos.Config{Enabled: true, TimeoutRate: 1}, 1},
+		// Вид мусора выбирается случайно, поэтому запрос повторяется
+		{"Garbage", chaos.Config{Enabled: true, GarbageRate: 1}, 20},
+		{"FutureTimestamp", chaos.Config{Enabled: true, SkewRate: 1, TimestampSkewSec: 3600}, 1},
+		{"StaleTimestamp", chaos.Config{Enabled: true, SkewRate: 1, TimestampSkewSec: -8 * 3600}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctl := chaos.NewController()
+			if err := ctl.Set("Faulty", tt.config); err != nil {
+				t.Fatal(err)
+			}
+
+			agg := aggregator.NewAggregator(10)
+			agg.Use(ctl.Middleware())
+			agg.AddProvider(&fakeProvider{name: "Good1", temperature: 10})
+			agg.AddProvider(&fakeProvider{name: "Good2", temperature: 12})
+			agg.AddProvider(&fakeProvider{name: "Faulty", temperature: 30})
+
+			for i := 0; i < tt.attempts; i++ {
+				// Зависший провайдер отпускается по истечении контекста
+				ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+				result, err := agg.Refresh(ctx, "Москва", "RU")
+				cancel()
+				if err != nil {
+					t.Fatalf("Refresh: %v", err)
+				}
+				if slices.Contains(result.Providers, "Faulty") {
+					t.Fatalf("ответ со сбоем попал в агрегат: провайдеры %v", result.Providers)
+				}
+				if result.Temperature == nil || result.Temperature.Count != 2 || result.Temperature.Average != 11 {
+					t.Fatalf("температура %+v, ожидается среднее 11 по двум источникам", result.Temperature)
+				}
+			}
+		})
+	}
+}
+
+// TestChaosSmallSkewAccepted проверяет, что небольшой сдвиг времени
+// наблюдения допустим и ответ провайдера агрегируется
+func TestChaosSmallSkewAccepted(t *testing.T) {
+	ctl := chaos.NewController()
+	if err := ctl.Set(chaos.AllProviders, chaos.Config{Enabled: true, SkewRate: 1, TimestampSkewSec: -600}); err != nil {
+		t.Fatal(err)
+	}
+
+	agg := aggregator.NewAggregator(10)
+	agg.AddProvider(&fakeProvider{name: "Skewed", temperature: 10}, ctl.Middleware())
+
+	result, err := agg.Refresh(context.Background(), "Москва", "RU")
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if len(result.Sources) != 1 {
+		t.Fatalf("источники %+v, ожидается один", result.Sources)
+	}
+	if age := time.Since(result.Sources[0].ObservedAt); age < 9*time.Minute || age > 11*time.Minute {
+		t.Errorf("время наблюдения сдвинуто на %s, ожидается 10 минут", age)
+	}
+}
+
+// TestChaosAllProvidersFail проверяет, что при сбое всех провайдеров
+// возвращается ошибка со всеми причинами, а в кеш ничего не попадает
+func TestChaosAllProvidersFail(t *testing.T) {
+	ctl := chaos.NewController()
+	if err := ctl.Set(chaos.AllProviders, chaos.Config{Enabled: true, ErrorRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	agg := aggregator.NewAggregator(10)
+	agg.Use(ctl.Middleware())
+	agg.AddProvider(&fakeProvider{name: "A", temperature: 10})
+	agg.AddProvider(&fakeProvider{name: "B", temperature: 12})
+
+	for i := 0; i < 2; i++ {
+		result, err := agg.GetWeather(context.Background(), "Москва", "RU")
+		if err == nil {
+			t.Fatalf("ожидается ошибка, получено %+v", result)
+		}
+		if !strings.Contains(err.Error(), "A: "+chaos.ErrInjected.Error()) ||
+			!strings.Contains(err.Error(), "B: "+chaos.ErrInjected.Error()) {
+			t.Errorf("ошибка %q должна перечислять сбои обоих провайдеров", err)
+		}
+	}
+}
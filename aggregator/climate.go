@@ -0,0 +1,33 @@
+package aggregator
+
+import (
+	"time"
+
+	"weather-aggregator/models"
+)
+
+// Climatology сравнивает агрегированную погоду с климатической нормой
+// (например, climate.Climatology)
+type Climatology interface {
+	Anomalies(city, country string, weather *models.AggregatedWeather, now time.Time) []models.Anomaly
+}
+
+// SetClimatology включает расчет отклонений от климатической нормы; nil отключает
+func (a *Aggregator) SetClimatology(climatology Climatology) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.climatology = climatology
+}
+
+// anomalies отклонения от нормы, если их расчет включен
+func (a *Aggregator) anomalies(city, country string, weather *models.AggregatedWeather) []models.Anomaly {
+	a.providersMu.RLock()
+	climatology := a.climatology
+	a.providersMu.RUnlock()
+	if climatology == nil {
+		return nil
+	}
+
+	return climatology.Anomalies(city, country, weather, time.Now())
+}
@@ -0,0 +1,32 @@
+package aggregator
+
+import (
+	"weather-aggregator/models"
+)
+
+// Corrector поправляет систематические ошибки провайдеров перед агрегацией
+// (например, correction.Corrector). Возвращает исправленные копии ответов,
+// исходные не изменяются, и список примененных поправок.
+type Corrector interface {
+	Correct(city, country string, data []*models.WeatherData) ([]*models.WeatherData, []models.BiasCorrection)
+}
+
+// SetCorrector включает поправку систематических ошибок провайдеров; nil отключает
+func (a *Aggregator) SetCorrector(corrector Corrector) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.corrector = corrector
+}
+
+// correct применяет поправки, если они включены
+func (a *Aggregator) correct(city, country string, data []*models.WeatherData) ([]*models.WeatherData, []models.BiasCorrection) {
+	a.providersMu.RLock()
+	corrector := a.corrector
+	a.providersMu.RUnlock()
+	if corrector == nil {
+		return data, nil
+	}
+
+	return corrector.Correct(city, country, data)
+}
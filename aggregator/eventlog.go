@@ -0,0 +1,122 @@
+package aggregator
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// EventLog сохраняет журнал изменений провайдеров, чтобы он переживал
+// перезапуск сервера (например, FileEventLog в каталоге истории)
+type EventLog interface {
+	Load() ([]ProviderEvent, error)
+	Append(event ProviderEvent) error
+}
+
+// SetEventLog задает хранилище журнала и загружает из него последние записи;
+// nil оставляет журнал только в памяти
+func (a *Aggregator) SetEventLog(eventLog EventLog) error {
+	var events []ProviderEvent
+	if eventLog != nil {
+		loaded, err := eventLog.Load()
+		if err != nil {
+			return err
+		}
+		events = loaded
+	}
+
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.eventLog = eventLog
+	// Записи, сделанные до подключения хранилища, идут после сохраненных
+	a.events = append(events, a.events...)
+	if len(a.events) > maxProviderEvents {
+		a.events = a.events[len(a.events)-maxProviderEvents:]
+	}
+	return nil
+}
+
+// persistEvent сохраняет запись; ошибка не отменяет изменение провайдера.
+// Вызывается под providersMu.
+func (a *Aggregator) persistEvent(event ProviderEvent) {
+	if a.eventLog == nil {
+		return
+	}
+	if err := a.eventLog.Append(event); err != nil {
+		log.Printf("ошибка записи журнала провайдеров: %v", err)
+	}
+}
+
+// FileEventLog журнал изменений провайдеров в файле JSON Lines. Файл только
+// дописывается; при загрузке остаются последние maxProviderEvents записей.
+type FileEventLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileEventLog создает журнал в файле path
+func NewFileEventLog(path string) *FileEventLog {
+	return &FileEventLog{path: path}
+}
+
+// Load читает записи журнала; поврежденные строки пропускаются
+func (l *FileEventLog) Load() ([]ProviderEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала провайдеров: %w", err)
+	}
+	defer f.Close()
+
+	var events []ProviderEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event ProviderEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+		if len(events) > 2*maxProviderEvents {
+			events = append(events[:0], events[len(events)-maxProviderEvents:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала провайдеров: %w", err)
+	}
+
+	if len(events) > maxProviderEvents {
+		events = events[len(events)-maxProviderEvents:]
+	}
+	return events, nil
+}
+
+// Append дописывает запись в конец файла
+func (l *FileEventLog) Append(event ProviderEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,85 @@
+package aggregator_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"weather-aggregator/aggregator"
+)
+
+func TestProviderEventsPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider_events.jsonl")
+
+	agg := aggregator.NewAggregator(10)
+	agg.AddProvider(&fakeProvider{name: "A"})
+	// Запись до подключения журнала тоже сохраняется в памяти
+	if err := agg.DisableProvider("A", "alice", "до журнала"); err != nil {
+		t.Fatal(err)
+	}
+	if err := agg.SetEventLog(aggregator.NewFileEventLog(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := agg.EnableProvider("A", "bob", "квота восстановлена"); err != nil {
+		t.Fatal(err)
+	}
+	if err := agg.RemoveProvider("A", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(agg.ProviderEvents()); n != 3 {
+		t.Errorf("записей в памяти %d, ожидалось 3", n)
+	}
+
+	// Перезапуск: журнал читается из файла, поврежденная строка пропускается
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"provider\":\"A\",\"act\n")
+	f.Close()
+
+	restarted := aggregator.NewAggregator(10)
+	if err := restarted.SetEventLog(aggregator.NewFileEventLog(path)); err != nil {
+		t.Fatal(err)
+	}
+	events := restarted.ProviderEvents()
+	if len(events) != 2 {
+		t.Fatalf("после перезапуска записи %+v, ожидалось две сохраненные", events)
+	}
+	if e := events[0]; e.Provider != "A" || e.Action != aggregator.ActionEnable || e.Actor != "bob" || e.Reason != "квота восстановлена" {
+		t.Errorf("первая запись %+v", e)
+	}
+	if e := events[1]; e.Action != aggregator.ActionRemove || e.Actor != "unknown" {
+		t.Errorf("вторая запись %+v", e)
+	}
+}
+
+func TestProviderEventsLoadKeepsLatest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider_events.jsonl")
+	eventLog := aggregator.NewFileEventLog(path)
+	for i := 0; i < 450; i++ {
+		err := eventLog.Append(aggregator.ProviderEvent{Provider: fmt.Sprint(i), Action: aggregator.ActionDisable, Actor: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	agg := aggregator.NewAggregator(10)
+	if err := agg.SetEventLog(eventLog); err != nil {
+		t.Fatal(err)
+	}
+	events := agg.ProviderEvents()
+	if len(events) != 200 || events[0].Provider != "250" || events[199].Provider != "449" {
+		t.Errorf("загружено %d записей с %s по %s, ожидалось 200 последних", len(events), events[0].Provider, events[len(events)-1].Provider)
+	}
+
+	// Файла еще нет - журнал пуст
+	empty := aggregator.NewAggregator(10)
+	if err := empty.SetEventLog(aggregator.NewFileEventLog(filepath.Join(t.TempDir(), "none.jsonl"))); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(empty.ProviderEvents()); n != 0 {
+		t.Errorf("записей %d без файла", n)
+	}
+}
@@ -0,0 +1,102 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"weather-aggregator/models"
+	"weather-aggregator/providers"
+)
+
+// forecastTimeout ограничение сбора прогнозов для города
+const forecastTimeout = 30 * time.Second
+
+// ForecastRecorder сохраняет прогнозы провайдеров, чтобы позже сравнить их с
+// наблюдениями (например, history.Store)
+type ForecastRecorder interface {
+	RecordForecast(city, country, provider string, issued time.Time, forecast []models.ForecastPoint) error
+}
+
+// SetForecastRecording включает сохранение прогнозов: при обновлении погоды
+// для города, но не чаще interval, у провайдеров запрашивается прогноз на
+// maxLead вперед. nil отключает сохранение.
+func (a *Aggregator) SetForecastRecording(recorder ForecastRecorder, interval, maxLead time.Duration) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.forecastRecorder = recorder
+	a.forecastInterval = interval
+	a.forecastMaxLead = maxLead
+}
+
+// activeForecastSources возвращает включенные провайдеры, которые отдают
+// прогноз; запросы прогноза идут через их middleware
+func (a *Aggregator) activeForecastSources() []providers.ForecastSource {
+	var sources []providers.ForecastSource
+	for _, p := range a.activeProviders() {
+		if source, ok := providers.AsForecastSource(p); ok {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// recordForecasts в фоне сохраняет прогнозы для города, если с прошлого
+// сохранения прошло не меньше интервала. Ошибки не мешают ответу.
+func (a *Aggregator) recordForecasts(ctx context.Context, city, country string) {
+	a.providersMu.RLock()
+	recorder, interval, maxLead := a.forecastRecorder, a.forecastInterval, a.forecastMaxLead
+	a.providersMu.RUnlock()
+	if recorder == nil {
+		return
+	}
+
+	key := fmt.Sprintf("%s,%s", city, country)
+	now := time.Now()
+	a.forecastMu.Lock()
+	if last, ok := a.forecastAt[key]; ok && now.Sub(last) < interval {
+		a.forecastMu.Unlock()
+		return
+	}
+	a.forecastAt[key] = now
+	a.forecastMu.Unlock()
+
+	sources := a.activeForecastSources()
+	if len(sources) == 0 {
+		return
+	}
+
+	// Запрос погоды может завершиться раньше, чем придут прогнозы
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), forecastTimeout)
+	go func() {
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for _, source := range sources {
+			wg.Add(1)
+			go func(s providers.ForecastSource) {
+				defer wg.Done()
+
+				forecast, err := s.GetForecast(ctx, city, country)
+				if err != nil {
+					log.Printf("прогноз %s для %s недоступен: %v", s.Name(), city, err)
+					return
+				}
+				// Прошедшие часы не прогноз, дальние - вне проверяемых заблаговременностей
+				var upcoming []models.ForecastPoint
+				for _, point := range forecast {
+					if point.Time.After(now) && !point.Time.After(now.Add(maxLead)) {
+						upcoming = append(upcoming, point)
+					}
+				}
+				if err := recorder.RecordForecast(city, country, s.Name(), now, upcoming); err != nil {
+					log.Printf("ошибка записи прогноза %s для %s: %v", s.Name(), city, err)
+				}
+			}(source)
+		}
+		wg.Wait()
+	}()
+}
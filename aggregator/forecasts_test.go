@@ -0,0 +1,82 @@
+package aggregator_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-aggregator/aggregator"
+	"weather-aggregator/models"
+	"weather-aggregator/providers"
+	"weather-aggregator/providers/chaos"
+)
+
+// forecastProvider дополнительно отдает прогноз и считает запросы прогноза
+type forecastProvider struct {
+	fakeProvider
+	requests atomic.Int32
+}
+
+func (p *forecastProvider) GetForecast(ctx context.Context, city, country string) ([]models.ForecastPoint, error) {
+	p.requests.Add(1)
+	return []models.ForecastPoint{
+		{Time: time.Now().Add(time.Hour), Temperature: models.Float(p.temperature)},
+	}, nil
+}
+
+// forecastRecorder передает в канал имена провайдеров, чьи прогнозы сохранены
+type forecastRecorder chan string
+
+func (r forecastRecorder) RecordForecast(city, country, provider string, issued time.Time, forecast []models.ForecastPoint) error {
+	r <- provider
+	return nil
+}
+
+// TestForecastThroughMiddleware проверяет, что запросы прогноза проходят
+// через middleware провайдера, включая внедрение сбоев
+func TestForecastThroughMiddleware(t *testing.T) {
+	ctl := chaos.NewController()
+	if err := ctl.Set("Faulty", chaos.Config{Enabled: true, ErrorRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	good := &forecastProvider{fakeProvider: fakeProvider{name: "Good", temperature: 10}}
+	faulty := &forecastProvider{fakeProvider: fakeProvider{name: "Faulty", temperature: 30}}
+	recorder := make(forecastRecorder, 4)
+
+	agg := aggregator.NewAggregator(10)
+	agg.Use(providers.Logging(log.New(io.Discard, "", 0)), providers.Timing(providers.NewTimingStats()), ctl.Middleware())
+	agg.AddProvider(good)
+	agg.AddProvider(faulty)
+	// Провайдер без прогноза не становится его источником из-за оберток
+	agg.AddProvider(&fakeProvider{name: "NoForecast", temperature: 12})
+	agg.SetForecastRecording(recorder, time.Hour, 24*time.Hour)
+
+	if _, err := agg.Refresh(context.Background(), "Москва", "RU"); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	select {
+	case provider := <-recorder:
+		if provider != "Good" {
+			t.Fatalf("сохранен прогноз %s, ожидался Good", provider)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("прогноз не сохранен")
+	}
+	select {
+	case provider := <-recorder:
+		t.Fatalf("сохранен лишний прогноз %s", provider)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if n := good.requests.Load(); n != 1 {
+		t.Errorf("запросов прогноза Good: %d, ожидался 1", n)
+	}
+	if n := faulty.requests.Load(); n != 0 {
+		t.Errorf("внедренная ошибка не остановила запрос прогноза Faulty: %d запросов", n)
+	}
+}
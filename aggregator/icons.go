@@ -0,0 +1,71 @@
+package aggregator
+
+import (
+	"strings"
+	"time"
+
+	"weather-aggregator/astronomy"
+	"weather-aggregator/icons"
+	"weather-aggregator/models"
+)
+
+// SetIconBaseURL задает адрес, относительно которого строится icon_url
+// (например, публичный адрес сервера). По умолчанию адрес относительный: /icons/...
+func (a *Aggregator) SetIconBaseURL(baseURL string) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.iconBaseURL = baseURL
+}
+
+// iconURL адрес иконки согласованного явления
+func (a *Aggregator) iconURL(condition string, data []*models.WeatherData, astro *models.Astronomy, now time.Time) string {
+	a.providersMu.RLock()
+	baseURL := a.iconBaseURL
+	a.providersMu.RUnlock()
+
+	return icons.URL(baseURL, condition, isNight(data, astro, now))
+}
+
+// isNight определяет время суток: по расчету положения Солнца, затем по
+// восходу и закату провайдера, затем по признаку дня и ночи в иконках
+// провайдеров. Без данных считается, что сейчас день.
+func isNight(data []*models.WeatherData, astro *models.Astronomy, now time.Time) bool {
+	if astro != nil {
+		return astronomy.IsNight(astro, now)
+	}
+	for _, d := range data {
+		if d.Sunrise != nil && d.Sunset != nil {
+			return now.Before(*d.Sunrise) || now.After(*d.Sunset)
+		}
+	}
+
+	votes := 0
+	for _, d := range data {
+		if night, ok := iconNight(d.Icon); ok {
+			if night {
+				votes++
+			} else {
+				votes--
+			}
+		}
+	}
+	return votes > 0
+}
+
+// iconNight извлекает признак ночи из иконки провайдера: код OpenWeatherMap
+// ("04n"), адрес WeatherAPI или NWS (".../night/...") и symbol_code met.no
+// ("clearsky_night")
+func iconNight(icon string) (night, ok bool) {
+	switch {
+	case icon == "":
+		return false, false
+	case strings.Contains(icon, "/night/"), strings.HasSuffix(icon, "_night"):
+		return true, true
+	case strings.Contains(icon, "/day/"), strings.HasSuffix(icon, "_day"):
+		return false, true
+	case len(icon) == 3 && (icon[2] == 'n' || icon[2] == 'd'):
+		return icon[2] == 'n', true
+	}
+	return false, false
+}
@@ -0,0 +1,175 @@
+package aggregator
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"weather-aggregator/providers"
+)
+
+// ErrProviderNotFound возвращается, если провайдер с таким именем не добавлен
+var ErrProviderNotFound = errors.New("провайдер не найден")
+
+// maxProviderEvents сколько последних изменений провайдеров хранится в журнале
+const maxProviderEvents = 200
+
+// Действия над провайдерами в журнале
+const (
+	ActionEnable  = "enable"
+	ActionDisable = "disable"
+	ActionRemove  = "remove"
+)
+
+type providerEntry struct {
+	provider  providers.Provider
+	enabled   bool
+	changedAt time.Time
+	changedBy string
+}
+
+// ProviderStatus состояние провайдера в агрегаторе
+type ProviderStatus struct {
+	Name      string    `json:"name"`
+	Enabled   bool      `json:"enabled"`
+	ChangedAt time.Time `json:"changed_at"`
+	ChangedBy string    `json:"changed_by,omitempty"`
+}
+
+// ProviderEvent запись журнала: кто, когда и что сделал с провайдером
+type ProviderEvent struct {
+	Provider string    `json:"provider"`
+	Action   string    `json:"action"`
+	Actor    string    `json:"actor"`
+	Reason   string    `json:"reason,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// EnableProvider включает ранее отключенный провайдер
+func (a *Aggregator) EnableProvider(name, actor, reason string) error {
+	return a.setEnabled(name, true, actor, reason)
+}
+
+// DisableProvider исключает провайдер из опроса без удаления
+func (a *Aggregator) DisableProvider(name, actor, reason string) error {
+	return a.setEnabled(name, false, actor, reason)
+}
+
+func (a *Aggregator) setEnabled(name string, enabled bool, actor, reason string) error {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	i := a.findProvider(name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+
+	entry := a.providers[i]
+	action := ActionDisable
+	if enabled {
+		action = ActionEnable
+	}
+
+	entry.enabled = enabled
+	entry.changedAt = time.Now()
+	entry.changedBy = actor
+	a.recordEvent(entry.provider.Name(), action, actor, reason, entry.changedAt)
+
+	// В кеше могут быть данные с отключенным провайдером или без включенного
+	a.ClearCache()
+	return nil
+}
+
+// RemoveProvider удаляет провайдер из агрегатора
+func (a *Aggregator) RemoveProvider(name, actor, reason string) error {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	i := a.findProvider(name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+
+	removed := a.providers[i].provider.Name()
+	a.providers = append(a.providers[:i:i], a.providers[i+1:]...)
+	a.recordEvent(removed, ActionRemove, actor, reason, time.Now())
+
+	a.ClearCache()
+	return nil
+}
+
+// ProviderStatuses возвращает состояние всех добавленных провайдеров
+func (a *Aggregator) ProviderStatuses() []ProviderStatus {
+	a.providersMu.RLock()
+	defer a.providersMu.RUnlock()
+
+	statuses := make([]ProviderStatus, len(a.providers))
+	for i, entry := range a.providers {
+		statuses[i] = ProviderStatus{
+			Name:      entry.provider.Name(),
+			Enabled:   entry.enabled,
+			ChangedAt: entry.changedAt,
+			ChangedBy: entry.changedBy,
+		}
+	}
+	return statuses
+}
+
+// ProviderEvents возвращает журнал изменений провайдеров, от старых к новым
+func (a *Aggregator) ProviderEvents() []ProviderEvent {
+	a.providersMu.RLock()
+	defer a.providersMu.RUnlock()
+
+	events := make([]ProviderEvent, len(a.events))
+	copy(events, a.events)
+	return events
+}
+
+// activeProviders возвращает снимок включенных провайдеров
+func (a *Aggregator) activeProviders() []providers.Provider {
+	a.providersMu.RLock()
+	defer a.providersMu.RUnlock()
+
+	active := make([]providers.Provider, 0, len(a.providers))
+	for _, entry := range a.providers {
+		if entry.enabled {
+			active = append(active, entry.provider)
+		}
+	}
+	return active
+}
+
+// findProvider ищет провайдер по имени без учета регистра; вызывается под providersMu
+func (a *Aggregator) findProvider(name string) int {
+	for i, entry := range a.providers {
+		if strings.EqualFold(entry.provider.Name(), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// recordEvent добавляет запись в журнал, пишет ее в лог и сохраняет в
+// EventLog, если он задан; вызывается под providersMu
+func (a *Aggregator) recordEvent(provider, action, actor, reason string, at time.Time) {
+	if actor == "" {
+		actor = "unknown"
+	}
+
+	event := ProviderEvent{
+		Provider: provider,
+		Action:   action,
+		Actor:    actor,
+		Reason:   reason,
+		Time:     at,
+	}
+	a.events = append(a.events, event)
+	log.Printf("Провайдер %s: %s, инициатор %s, причина %q", provider, action, actor, reason)
+	a.persistEvent(event)
+
+	if len(a.events) > maxProviderEvents {
+		a.events = a.events[len(a.events)-maxProviderEvents:]
+	}
+}
@@ -0,0 +1,35 @@
+package aggregator
+
+import (
+	"log"
+
+	"weather-aggregator/models"
+)
+
+// Recorder сохраняет свежие результаты агрегации вместе с исходными ответами
+// провайдеров (например, history.Store). Результаты из кеша не записываются.
+type Recorder interface {
+	Record(city, country string, aggregated *models.AggregatedWeather, raw []*models.WeatherData) error
+}
+
+// SetRecorder задает получателя свежих результатов; nil отключает запись
+func (a *Aggregator) SetRecorder(recorder Recorder) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.recorder = recorder
+}
+
+// record передает результат получателю; ошибка записи не мешает ответу
+func (a *Aggregator) record(city, country string, aggregated *models.AggregatedWeather, raw []*models.WeatherData) {
+	a.providersMu.RLock()
+	recorder := a.recorder
+	a.providersMu.RUnlock()
+	if recorder == nil {
+		return
+	}
+
+	if err := recorder.Record(city, country, aggregated, raw); err != nil {
+		log.Printf("ошибка записи истории для %s: %v", city, err)
+	}
+}
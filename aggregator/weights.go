@@ -0,0 +1,99 @@
+package aggregator
+
+import (
+	"weather-aggregator/models"
+)
+
+// Стратегии агрегации измерений (AGGREGATION_STRATEGY)
+const (
+	StrategyMean     = "mean"     // простое среднее по источникам
+	StrategyWeighted = "weighted" // среднее с весами по точности прогнозов
+)
+
+// Weigher веса провайдеров в городе: провайдер -> величина -> вес. Провайдеров
+// и величин, для оценки которых данных недостаточно, нет (например,
+// verification.Scorer). Вызывается один раз на агрегацию.
+type Weigher interface {
+	Weights(city, country string) map[string]map[string]float64
+}
+
+// SetWeigher включает взвешенную стратегию: средние температуры, влажности,
+// давления и скорости ветра считаются с весами провайдеров. nil - простое среднее.
+func (a *Aggregator) SetWeigher(weigher Weigher) {
+	a.providersMu.Lock()
+	defer a.providersMu.Unlock()
+
+	a.weigher = weigher
+}
+
+// weightedFields величины, для которых есть оценки точности прогнозов
+var weightedFields = []struct {
+	name  string
+	get   func(d *models.WeatherData) *float64
+	value func(w *models.AggregatedWeather) *models.AggregatedValue
+}{
+	{"temperature", func(d *models.WeatherData) *float64 { return d.Temperature }, func(w *models.AggregatedWeather) *models.AggregatedValue { return w.Temperature }},
+	{"humidity", func(d *models.WeatherData) *float64 { return d.Humidity }, func(w *models.AggregatedWeather) *models.AggregatedValue { return w.Humidity }},
+	{"pressure", func(d *models.WeatherData) *float64 { return d.Pressure }, func(w *models.AggregatedWeather) *models.AggregatedValue { return w.Pressure }},
+	{"wind_speed", func(d *models.WeatherData) *float64 { return d.WindSpeed }, func(w *models.AggregatedWeather) *models.AggregatedValue { return w.WindSpeed }},
+}
+
+// applyWeights пересчитывает средние с весами провайдеров. Провайдер без
+// оценки получает средний вес оцененных; если не оценен никто, остается
+// простое среднее.
+func (a *Aggregator) applyWeights(aggregated *models.AggregatedWeather, data []*models.WeatherData, city, country string) {
+	a.providersMu.RLock()
+	weigher := a.weigher
+	a.providersMu.RUnlock()
+	if weigher == nil {
+		return
+	}
+	byProvider := weigher.Weights(city, country)
+	if len(byProvider) == 0 {
+		return
+	}
+
+	for _, field := range weightedFields {
+		value := field.value(aggregated)
+		if value == nil {
+			continue
+		}
+
+		// Порядок совпадает с value.Values: aggregateField обходит data так же
+		var weights []float64
+		var known []bool
+		sum, n := 0.0, 0
+		for _, d := range data {
+			if field.get(d) == nil {
+				continue
+			}
+			w, ok := byProvider[d.Provider][field.name]
+			ok = ok && w > 0
+			weights = append(weights, w)
+			known = append(known, ok)
+			if ok {
+				sum += w
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+
+		fallback := sum / float64(n)
+		total, weighted := 0.0, 0.0
+		for i := range weights {
+			if !known[i] {
+				weights[i] = fallback
+			}
+			total += weights[i]
+			weighted += weights[i] * value.Values[i]
+		}
+		// Веса нормируются, чтобы их можно было сравнивать между собой
+		for i := range weights {
+			weights[i] /= total
+		}
+		value.Average = weighted / total
+		value.Weights = weights
+	}
+}
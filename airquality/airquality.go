@@ -0,0 +1,229 @@
+// Package airquality рассчитывает индексы качества воздуха по концентрациям
+// загрязнителей: US EPA AQI (с изменениями 2024 года для PM2.5) и
+// европейский CAQI (часовая шкала для фонового мониторинга).
+package airquality
+
+import (
+	"math"
+
+	"weather-aggregator/models"
+)
+
+// Пересчет мкг/м³ в объемные доли при 25 °C и 1 атм (коэффициенты EPA)
+const (
+	o3PerPPB  = 1.96   // мкг/м³ на ppb
+	no2PerPPB = 1.88   // мкг/м³ на ppb
+	so2PerPPB = 2.62   // мкг/м³ на ppb
+	coPerPPM  = 1145.0 // мкг/м³ на ppm
+)
+
+// breakpoint диапазон концентраций, соответствующий диапазону индекса
+type breakpoint struct {
+	cLow, cHigh float64
+	iLow, iHigh int
+}
+
+// Таблицы US EPA. Единицы: PM - мкг/м³, O3/NO2/SO2 - ppb, CO - ppm.
+var (
+	epaPM25 = []breakpoint{
+		{0, 9.0, 0, 50}, {9.1, 35.4, 51, 100}, {35.5, 55.4, 101, 150},
+		{55.5, 125.4, 151, 200}, {125.5, 225.4, 201, 300}, {225.5, 325.4, 301, 500},
+	}
+	epaPM10 = []breakpoint{
+		{0, 54, 0, 50}, {55, 154, 51, 100}, {155, 254, 101, 150},
+		{255, 354, 151, 200}, {355, 424, 201, 300}, {425, 604, 301, 500},
+	}
+	// O3 8-часовой; выше 200 ppb 8-часовая шкала не определена
+	epaO3 = []breakpoint{
+		{0, 54, 0, 50}, {55, 70, 51, 100}, {71, 85, 101, 150},
+		{86, 105, 151, 200}, {106, 200, 201, 300},
+	}
+	// O3 часовой, применяется начиная со 125 ppb
+	epaO3Hourly = []breakpoint{
+		{125, 164, 101, 150}, {165, 204, 151, 200}, {205, 404, 201, 300}, {405, 604, 301, 500},
+	}
+	epaNO2 = []breakpoint{
+		{0, 53, 0, 50}, {54, 100, 51, 100}, {101, 360, 101, 150},
+		{361, 649, 151, 200}, {650, 1249, 201, 300}, {1250, 2049, 301, 500},
+	}
+	epaSO2 = []breakpoint{
+		{0, 35, 0, 50}, {36, 75, 51, 100}, {76, 185, 101, 150},
+		{186, 304, 151, 200}, {305, 604, 201, 300}, {605, 1004, 301, 500},
+	}
+	epaCO = []breakpoint{
+		{0, 4.4, 0, 50}, {4.5, 9.4, 51, 100}, {9.5, 12.4, 101, 150},
+		{12.5, 15.4, 151, 200}, {15.5, 30.4, 201, 300}, {30.5, 50.4, 301, 500},
+	}
+)
+
+var epaCategories = []struct {
+	max  int
+	name string
+}{
+	{50, "хорошо"},
+	{100, "удовлетворительно"},
+	{150, "вредно для чувствительных групп"},
+	{200, "вредно"},
+	{300, "очень вредно"},
+	{500, "опасно"},
+}
+
+// USEPA рассчитывает индекс US EPA. Возвращает nil, если нет ни одной
+// концентрации. Текущие значения подставляются вместо средних за 8 и 24 часа.
+func USEPA(aq *models.AirQuality) *models.AirQualityIndex {
+	if aq == nil {
+		return nil
+	}
+
+	var best *models.AirQualityIndex
+	consider := func(name string, value int) {
+		if best == nil || value > best.Value {
+			best = &models.AirQualityIndex{Value: value, Dominant: name}
+		}
+	}
+
+	if aq.PM25 != nil {
+		consider("pm2_5", epaIndex(epaPM25, truncate(*aq.PM25, 1)))
+	}
+	if aq.PM10 != nil {
+		consider("pm10", epaIndex(epaPM10, truncate(*aq.PM10, 0)))
+	}
+	if aq.O3 != nil {
+		ppb := truncate(*aq.O3/o3PerPPB, 0)
+		index := 0
+		if ppb <= 200 {
+			index = epaIndex(epaO3, ppb)
+		}
+		if ppb >= 125 {
+			index = max(index, epaIndex(epaO3Hourly, ppb))
+		}
+		consider("o3", index)
+	}
+	if aq.NO2 != nil {
+		consider("no2", epaIndex(epaNO2, truncate(*aq.NO2/no2PerPPB, 0)))
+	}
+	if aq.SO2 != nil {
+		consider("so2", epaIndex(epaSO2, truncate(*aq.SO2/so2PerPPB, 0)))
+	}
+	if aq.CO != nil {
+		consider("co", epaIndex(epaCO, truncate(*aq.CO/coPerPPM, 1)))
+	}
+
+	if best == nil {
+		return nil
+	}
+	for _, c := range epaCategories {
+		if best.Value <= c.max {
+			best.Category = c.name
+			break
+		}
+	}
+	return best
+}
+
+// epaIndex линейная интерполяция по таблице; выше таблицы - 500
+func epaIndex(table []breakpoint, c float64) int {
+	if c < 0 {
+		c = 0
+	}
+	for _, bp := range table {
+		if c <= bp.cHigh {
+			if c < bp.cLow {
+				// Значение в промежутке между диапазонами после округления
+				c = bp.cLow
+			}
+			index := float64(bp.iHigh-bp.iLow)/(bp.cHigh-bp.cLow)*(c-bp.cLow) + float64(bp.iLow)
+			return int(math.Round(index))
+		}
+	}
+	return 500
+}
+
+// truncate отбрасывает знаки после digits, как предписывает методика EPA
+func truncate(v float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Floor(v*scale) / scale
+}
+
+// Часовая шкала CAQI для фоновых станций, мкг/м³. Границы соответствуют
+// индексу 0, 25, 50, 75 и 100.
+var caqiGrid = []struct {
+	name   string
+	limits [5]float64
+	value  func(aq *models.AirQuality) *float64
+}{
+	{"no2", [5]float64{0, 50, 100, 200, 400}, func(aq *models.AirQuality) *float64 { return aq.NO2 }},
+	{"pm10", [5]float64{0, 25, 50, 90, 180}, func(aq *models.AirQuality) *float64 { return aq.PM10 }},
+	{"o3", [5]float64{0, 60, 120, 180, 240}, func(aq *models.AirQuality) *float64 { return aq.O3 }},
+	{"pm2_5", [5]float64{0, 15, 30, 55, 110}, func(aq *models.AirQuality) *float64 { return aq.PM25 }},
+	{"co", [5]float64{0, 5000, 7500, 10000, 20000}, func(aq *models.AirQuality) *float64 { return aq.CO }},
+	{"so2", [5]float64{0, 50, 100, 350, 500}, func(aq *models.AirQuality) *float64 { return aq.SO2 }},
+}
+
+var caqiCategories = []string{
+	"очень низкое",
+	"низкое",
+	"среднее",
+	"высокое",
+	"очень высокое",
+}
+
+// CAQI рассчитывает европейский индекс CAQI (часовой, фоновый). Индекс не
+// ограничен сверху: выше 100 шкала продолжается линейно. Возвращает nil,
+// если нет ни одной концентрации.
+func CAQI(aq *models.AirQuality) *models.AirQualityIndex {
+	if aq == nil {
+		return nil
+	}
+
+	var best *models.AirQualityIndex
+	for _, pollutant := range caqiGrid {
+		v := pollutant.value(aq)
+		if v == nil {
+			continue
+		}
+
+		index := caqiIndex(pollutant.limits, *v)
+		if best == nil || index > best.Value {
+			best = &models.AirQualityIndex{Value: index, Dominant: pollutant.name}
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	// Границы категорий: 25, 50, 75, 100 включительно относятся к нижней
+	level := 0
+	if best.Value > 0 {
+		level = min((best.Value-1)/25, len(caqiCategories)-1)
+	}
+	best.Category = caqiCategories[level]
+	return best
+}
+
+func caqiIndex(limits [5]float64, c float64) int {
+	if c <= 0 {
+		return 0
+	}
+	for i := 1; i < len(limits); i++ {
+		if c <= limits[i] {
+			index := 25*float64(i-1) + 25*(c-limits[i-1])/(limits[i]-limits[i-1])
+			return int(math.Round(index))
+		}
+	}
+	// Выше 100 продолжаем последний отрезок шкалы
+	last := len(limits) - 1
+	index := 100 + 25*(c-limits[last])/(limits[last]-limits[last-1])
+	return int(math.Round(index))
+}
+
+// Fill заполняет AQI по концентрациям и возвращает aq; nil, если
+// концентраций нет
+func Fill(aq *models.AirQuality) *models.AirQuality {
+	index := USEPA(aq)
+	if index == nil {
+		return nil
+	}
+	aq.AQI = &index.Value
+	return aq
+}
@@ -0,0 +1,105 @@
+package airquality
+
+import (
+	"testing"
+
+	"weather-aggregator/models"
+)
+
+func TestUSEPA(t *testing.T) {
+	tests := []struct {
+		name     string
+		aq       models.AirQuality
+		value    int
+		category string
+		dominant string
+	}{
+		{"PM25Zero", models.AirQuality{PM25: models.Float(0)}, 0, "хорошо", "pm2_5"},
+		{"PM25Negative", models.AirQuality{PM25: models.Float(-1)}, 0, "хорошо", "pm2_5"},
+		{"PM25GoodEdge", models.AirQuality{PM25: models.Float(9.0)}, 50, "хорошо", "pm2_5"},
+		// 9.05 усекается до 9.0, а не округляется до 9.1
+		{"PM25TruncatedToGood", models.AirQuality{PM25: models.Float(9.05)}, 50, "хорошо", "pm2_5"},
+		{"PM25Moderate", models.AirQuality{PM25: models.Float(9.1)}, 51, "удовлетворительно", "pm2_5"},
+		{"PM25Interpolated", models.AirQuality{PM25: models.Float(12)}, 56, "удовлетворительно", "pm2_5"},
+		{"PM25ModerateEdge", models.AirQuality{PM25: models.Float(35.4)}, 100, "удовлетворительно", "pm2_5"},
+		{"PM25TruncatedToModerate", models.AirQuality{PM25: models.Float(35.45)}, 100, "удовлетворительно", "pm2_5"},
+		{"PM25Sensitive", models.AirQuality{PM25: models.Float(35.5)}, 101, "вредно для чувствительных групп", "pm2_5"},
+		{"PM25AboveTable", models.AirQuality{PM25: models.Float(400)}, 500, "опасно", "pm2_5"},
+		{"PM10TruncatedToGood", models.AirQuality{PM10: models.Float(54.9)}, 50, "хорошо", "pm10"},
+		{"PM10Moderate", models.AirQuality{PM10: models.Float(55)}, 51, "удовлетворительно", "pm10"},
+		// 139.2 мкг/м³ = 71 ppb
+		{"O3", models.AirQuality{O3: models.Float(139.2)}, 101, "вредно для чувствительных групп", "o3"},
+		// 150 ppb: 8-часовая шкала строже часовой
+		{"O3EightHourWins", models.AirQuality{O3: models.Float(294)}, 247, "очень вредно", "o3"},
+		// 250 ppb: 8-часовая шкала не определена, действует часовая
+		{"O3HourlyOnly", models.AirQuality{O3: models.Float(490)}, 223, "очень вредно", "o3"},
+		// 189 мкг/м³ = 100.5 ppb, усекается до 100
+		{"NO2", models.AirQuality{NO2: models.Float(189)}, 100, "удовлетворительно", "no2"},
+		// 5725 мкг/м³ = 5 ppm
+		{"CO", models.AirQuality{CO: models.Float(5725)}, 56, "удовлетворительно", "co"},
+		{"Dominant", models.AirQuality{PM25: models.Float(12), PM10: models.Float(160), SO2: models.Float(10)}, 103, "вредно для чувствительных групп", "pm10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := USEPA(&tt.aq)
+			if index == nil {
+				t.Fatal("индекс не рассчитан")
+			}
+			if index.Value != tt.value || index.Category != tt.category || index.Dominant != tt.dominant {
+				t.Errorf("индекс %+v, ожидалось %d (%s, %s)", *index, tt.value, tt.category, tt.dominant)
+			}
+		})
+	}
+
+	if index := USEPA(nil); index != nil {
+		t.Errorf("индекс без данных: %+v", index)
+	}
+	if index := USEPA(&models.AirQuality{}); index != nil {
+		t.Errorf("индекс без концентраций: %+v", index)
+	}
+}
+
+func TestCAQI(t *testing.T) {
+	tests := []struct {
+		name     string
+		aq       models.AirQuality
+		value    int
+		category string
+		dominant string
+	}{
+		{"Zero", models.AirQuality{PM25: models.Float(0)}, 0, "очень низкое", "pm2_5"},
+		{"VeryLowEdge", models.AirQuality{PM25: models.Float(15)}, 25, "очень низкое", "pm2_5"},
+		{"Low", models.AirQuality{PM25: models.Float(15.6)}, 26, "низкое", "pm2_5"},
+		{"MediumEdge", models.AirQuality{NO2: models.Float(200)}, 75, "среднее", "no2"},
+		{"HighEdge", models.AirQuality{PM25: models.Float(110)}, 100, "высокое", "pm2_5"},
+		// Выше 100 шкала продолжается последним отрезком
+		{"AboveScale", models.AirQuality{PM25: models.Float(165)}, 125, "очень высокое", "pm2_5"},
+		{"CO", models.AirQuality{CO: models.Float(6250)}, 38, "низкое", "co"},
+		{"Dominant", models.AirQuality{PM10: models.Float(50), NO2: models.Float(60), O3: models.Float(90)}, 50, "низкое", "pm10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := CAQI(&tt.aq)
+			if index == nil {
+				t.Fatal("индекс не рассчитан")
+			}
+			if index.Value != tt.value || index.Category != tt.category || index.Dominant != tt.dominant {
+				t.Errorf("индекс %+v, ожидалось %d (%s, %s)", *index, tt.value, tt.category, tt.dominant)
+			}
+		})
+	}
+
+	if index := CAQI(&models.AirQuality{}); index != nil {
+		t.Errorf("индекс без концентраций: %+v", index)
+	}
+}
+
+func TestFill(t *testing.T) {
+	aq := Fill(&models.AirQuality{PM25: models.Float(35.45)})
+	if aq == nil || aq.AQI == nil || *aq.AQI != 100 {
+		t.Errorf("AQI %+v, ожидалось 100", aq)
+	}
+	if aq := Fill(&models.AirQuality{}); aq != nil {
+		t.Errorf("без концентраций ожидался nil, получено %+v", aq)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"weather-aggregator/astronomy"
+	"weather-aggregator/config"
+	"weather-aggregator/geo"
+	"weather-aggregator/models"
+)
+
+// newAstroCmd создает команду астрономического расчета для города
+func newAstroCmd() *cobra.Command {
+	var astroCmd = &cobra.Command{
+		Use:   "astro [город]",
+		Short: "Восход и заход Солнца и Луны, сумерки и фаза Луны",
+		Long: "Рассчитывает астрономические данные по координатам города без обращения\n" +
+			"к провайдерам погоды. Время выводится в часовом поясе --tz, по умолчанию -\n" +
+			"в поясе среднего солнечного времени долготы города.",
+		Args: cobra.ExactArgs(1),
+		// Расчету не нужны ключи API и агрегатор
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			country, _ := cmd.Flags().GetString("country")
+			date, _ := cmd.Flags().GetString("date")
+			tz, _ := cmd.Flags().GetString("tz")
+			output, _ := cmd.Flags().GetString("output")
+
+			astroCLI(args[0], country, date, tz, output)
+		},
+	}
+
+	astroCmd.Flags().StringP("country", "c", "RU", "Код страны (например, RU, US)")
+	astroCmd.Flags().StringP("date", "d", "", "Дата в формате ГГГГ-ММ-ДД (по умолчанию сегодня)")
+	astroCmd.Flags().String("tz", "", "Часовой пояс IANA, например Europe/Moscow")
+	astroCmd.Flags().StringP("output", "o", "text", "Формат вывода (text, json)")
+
+	return astroCmd
+}
+
+// astroCLI рассчитывает и выводит астрономические данные
+func astroCLI(city, country, date, tz, output string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	loc, err := geo.NewDefault(config.LoadClient().GeocoderURL).Resolve(ctx, city, country)
+	if err != nil {
+		log.Fatalf("Ошибка: %v", err)
+	}
+
+	zone := astronomy.SolarZone(loc.Lon)
+	if tz != "" {
+		if zone, err = time.LoadLocation(tz); err != nil {
+			log.Fatalf("Неизвестный часовой пояс %q: %v", tz, err)
+		}
+	}
+
+	at := time.Now().In(zone)
+	if date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, zone)
+		if err != nil {
+			log.Fatalf("Некорректная дата %q: ожидается ГГГГ-ММ-ДД", date)
+		}
+		// Фаза Луны рассчитывается на полдень выбранных суток
+		at = day.Add(12 * time.Hour)
+	}
+
+	astro := astronomy.Compute(at, loc.Lat, loc.Lon)
+
+	if output == "json" {
+		data, _ := json.MarshalIndent(astro, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	clock := func(t *time.Time) string {
+		if t == nil {
+			return "—"
+		}
+		return t.Format("15:04")
+	}
+
+	fmt.Printf("🌅 %s, %s (%.4f, %.4f), %s %s\n", city, country, loc.Lat, loc.Lon, astro.Date, at.Format("MST"))
+	fmt.Println(strings.Repeat("=", 40))
+	fmt.Printf("Солнце: %s\n", sunSummary(astro))
+	fmt.Printf("Солнечный полдень: %s\n", astro.SolarNoon.Format("15:04"))
+	fmt.Printf("Гражданские сумерки: %s - %s\n", clock(astro.CivilDawn), clock(astro.CivilDusk))
+	fmt.Printf("Навигационные сумерки: %s - %s\n", clock(astro.NauticalDawn), clock(astro.NauticalDusk))
+	fmt.Printf("Астрономические сумерки: %s - %s\n", clock(astro.AstronomicalDawn), clock(astro.AstronomicalDusk))
+	fmt.Printf("Луна: восход %s, заход %s\n", clock(astro.Moonrise), clock(astro.Moonset))
+	fmt.Printf("Фаза: %s, освещено %.0f%%, возраст %.1f сут\n", astro.MoonPhaseName, astro.MoonIllumination, astro.MoonAge)
+}
+
+// sunSummary восход, заход и долгота дня одной строкой
+func sunSummary(astro *models.Astronomy) string {
+	switch {
+	case astro.PolarDay:
+		return "полярный день"
+	case astro.PolarNight:
+		return "полярная ночь"
+	}
+	minutes := int(astro.DayLength)
+	return fmt.Sprintf("восход %s, заход %s, день %d ч %02d мин",
+		astro.Sunrise.Format("15:04"), astro.Sunset.Format("15:04"), minutes/60, minutes%60)
+}
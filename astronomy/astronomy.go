@@ -0,0 +1,77 @@
+// Package astronomy рассчитывает восход и заход Солнца, сумерки, солнечный
+// полдень, долготу дня, фазу Луны, ее восход и заход по координатам и дате.
+// Сеть не нужна: используются упрощенные формулы NOAA и теории движения Луны.
+package astronomy
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"weather-aggregator/models"
+)
+
+// Compute рассчитывает положение Солнца и Луны для календарной даты t в ее
+// часовом поясе; фаза Луны - на момент t. Время событий возвращается в поясе t.
+func Compute(t time.Time, lat, lon float64) *models.Astronomy {
+	sun := Sun(t, lat, lon)
+	moon := Moon(t, lat, lon)
+	loc := t.Location()
+
+	return &models.Astronomy{
+		Date:             t.Format("2006-01-02"),
+		SolarNoon:        sun.SolarNoon.In(loc),
+		Sunrise:          in(sun.Sunrise, loc),
+		Sunset:           in(sun.Sunset, loc),
+		CivilDawn:        in(sun.CivilDawn, loc),
+		CivilDusk:        in(sun.CivilDusk, loc),
+		NauticalDawn:     in(sun.NauticalDawn, loc),
+		NauticalDusk:     in(sun.NauticalDusk, loc),
+		AstronomicalDawn: in(sun.AstronomicalDawn, loc),
+		AstronomicalDusk: in(sun.AstronomicalDusk, loc),
+		DayLength:        math.Round(sun.DayLength.Minutes()),
+		PolarDay:         sun.PolarDay,
+		PolarNight:       sun.PolarNight,
+
+		Moonrise:         in(moon.Moonrise, loc),
+		Moonset:          in(moon.Moonset, loc),
+		MoonPhase:        math.Round(moon.Phase*1000) / 1000,
+		MoonPhaseName:    PhaseName(moon.Phase),
+		MoonIllumination: math.Round(moon.Illumination * 100),
+		MoonAge:          math.Round(moon.Age*10) / 10,
+	}
+}
+
+// SolarZone часовой пояс среднего солнечного времени долготы lon с шагом в
+// час. Используется, когда настоящий часовой пояс города неизвестен: дата в
+// нем совпадает с местной почти везде.
+func SolarZone(lon float64) *time.Location {
+	offset := int(math.Round(lon / 15))
+	if offset == 0 {
+		return time.UTC
+	}
+	return time.FixedZone(fmt.Sprintf("UTC%+d", offset), offset*3600)
+}
+
+// IsNight проверяет, что в момент t Солнце ниже горизонта
+func IsNight(a *models.Astronomy, t time.Time) bool {
+	switch {
+	case a.PolarNight:
+		return true
+	case a.PolarDay:
+		return false
+	case a.Sunrise != nil && t.Before(*a.Sunrise):
+		return true
+	case a.Sunset != nil && t.After(*a.Sunset):
+		return true
+	}
+	return false
+}
+
+func in(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := t.In(loc)
+	return &v
+}
@@ -0,0 +1,121 @@
+package astronomy
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var (
+	msk = time.FixedZone("MSK", 3*3600)
+	edt = time.FixedZone("EDT", -4*3600)
+	est = time.FixedZone("EST", -5*3600)
+)
+
+// Эталонные восход и заход - NOAA Solar Calculator
+func TestSunriseSunset(t *testing.T) {
+	tests := []struct {
+		name            string
+		lat, lon        float64
+		sunrise, sunset time.Time
+	}{
+		{"MoscowSummer", 55.7558, 37.6173,
+			time.Date(2024, 6, 21, 3, 44, 36, 0, msk), time.Date(2024, 6, 21, 21, 18, 13, 0, msk)},
+		{"MoscowWinter", 55.7558, 37.6173,
+			time.Date(2024, 12, 21, 8, 57, 42, 0, msk), time.Date(2024, 12, 21, 15, 57, 53, 0, msk)},
+		{"MoscowEquinox", 55.7558, 37.6173,
+			time.Date(2024, 3, 20, 6, 30, 57, 0, msk), time.Date(2024, 3, 20, 18, 43, 55, 0, msk)},
+		{"NewYorkSummer", 40.7128, -74.0060,
+			time.Date(2024, 6, 20, 5, 24, 54, 0, edt), time.Date(2024, 6, 20, 20, 30, 39, 0, edt)},
+		{"NewYorkWinter", 40.7128, -74.0060,
+			time.Date(2024, 12, 21, 7, 16, 49, 0, est), time.Date(2024, 12, 21, 16, 32, 4, 0, est)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date := time.Date(tt.sunrise.Year(), tt.sunrise.Month(), tt.sunrise.Day(), 12, 0, 0, 0, tt.sunrise.Location())
+			a := Compute(date, tt.lat, tt.lon)
+			if a.Sunrise == nil || a.Sunset == nil {
+				t.Fatalf("нет восхода или заката: %+v", a)
+			}
+			if d := a.Sunrise.Sub(tt.sunrise).Abs(); d > time.Minute {
+				t.Errorf("восход %s, ожидалось %s (±1 мин)", a.Sunrise.Format(time.TimeOnly), tt.sunrise.Format(time.TimeOnly))
+			}
+			if d := a.Sunset.Sub(tt.sunset).Abs(); d > time.Minute {
+				t.Errorf("закат %s, ожидалось %s (±1 мин)", a.Sunset.Format(time.TimeOnly), tt.sunset.Format(time.TimeOnly))
+			}
+			if a.Sunrise.Location() != tt.sunrise.Location() {
+				t.Errorf("время восхода в поясе %s, ожидался пояс даты", a.Sunrise.Location())
+			}
+			if want := tt.sunset.Sub(tt.sunrise).Minutes(); math.Abs(a.DayLength-want) > 2 {
+				t.Errorf("долгота дня %v мин, ожидалось %.0f", a.DayLength, want)
+			}
+			if a.PolarDay || a.PolarNight {
+				t.Error("полярный день или ночь вне полярных широт")
+			}
+		})
+	}
+}
+
+func TestPolarDayAndNight(t *testing.T) {
+	const lat, lon = 68.97, 33.07 // Мурманск
+
+	day := Compute(time.Date(2024, 6, 21, 12, 0, 0, 0, msk), lat, lon)
+	if !day.PolarDay || day.PolarNight || day.Sunrise != nil || day.Sunset != nil || day.DayLength != 24*60 {
+		t.Errorf("полярный день: %+v", day)
+	}
+	if IsNight(day, time.Date(2024, 6, 21, 0, 30, 0, 0, msk)) {
+		t.Error("ночь в полярный день")
+	}
+
+	night := Compute(time.Date(2024, 12, 21, 12, 0, 0, 0, msk), lat, lon)
+	if !night.PolarNight || night.PolarDay || night.Sunrise != nil || night.Sunset != nil || night.DayLength != 0 {
+		t.Errorf("полярная ночь: %+v", night)
+	}
+	// В полдень Солнце лишь на пару градусов ниже горизонта: сумерки есть
+	if night.CivilDawn == nil || night.CivilDusk == nil || night.AstronomicalDawn == nil ||
+		!night.CivilDawn.Before(night.SolarNoon) || !night.AstronomicalDawn.Before(*night.CivilDawn) {
+		t.Errorf("сумерки в полярную ночь: гражданские %v - %v, астрономические с %v",
+			night.CivilDawn, night.CivilDusk, night.AstronomicalDawn)
+	}
+	if !IsNight(night, time.Date(2024, 12, 21, 12, 0, 0, 0, msk)) {
+		t.Error("день в полярную ночь")
+	}
+}
+
+func TestMoonPhase(t *testing.T) {
+	tests := []struct {
+		name         string
+		at           time.Time
+		phase        float64
+		illumination float64
+		phaseName    string
+	}{
+		// Новолуние 11 января 2024 в 11:57 UTC
+		{"NewMoon", time.Date(2024, 1, 11, 11, 57, 0, 0, time.UTC), 0, 0, "новолуние"},
+		// Первая четверть 18 января 2024 в 03:53 UTC
+		{"FirstQuarter", time.Date(2024, 1, 18, 3, 53, 0, 0, time.UTC), 0.25, 50, "первая четверть"},
+		// Полнолуние 25 января 2024 в 17:54 UTC
+		{"FullMoon", time.Date(2024, 1, 25, 17, 54, 0, 0, time.UTC), 0.5, 100, "полнолуние"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Compute(tt.at, 55.7558, 37.6173)
+			// Фаза около нуля может оказаться и чуть меньше единицы
+			d := math.Abs(a.MoonPhase - tt.phase)
+			if math.Min(d, 1-d) > 0.005 {
+				t.Errorf("фаза %v, ожидалось %v", a.MoonPhase, tt.phase)
+			}
+			if math.Abs(a.MoonIllumination-tt.illumination) > 2 {
+				t.Errorf("освещенность %v%%, ожидалось %v%%", a.MoonIllumination, tt.illumination)
+			}
+			if a.MoonPhaseName != tt.phaseName {
+				t.Errorf("фаза %q, ожидалось %q", a.MoonPhaseName, tt.phaseName)
+			}
+		})
+	}
+
+	newMoon := Compute(time.Date(2024, 1, 11, 11, 57, 0, 0, time.UTC), 55.7558, 37.6173)
+	if newMoon.MoonAge > 0.5 && newMoon.MoonAge < 29 {
+		t.Errorf("возраст Луны в новолуние %v суток", newMoon.MoonAge)
+	}
+}
@@ -0,0 +1,156 @@
+package astronomy
+
+import (
+	"math"
+	"time"
+)
+
+// synodicMonth средняя продолжительность лунного месяца, сутки
+const synodicMonth = 29.530588853
+
+// sunDistance среднее расстояние до Солнца, км
+const sunDistance = 149598000
+
+// moonHorizon высота центра Луны при восходе и заходе: параллакс (~0.95°)
+// поднимает видимый диск, рефракция и радиус опускают горизонт
+const moonHorizon = 0.133
+
+// MoonTimes положение Луны на сутки. Moonrise или Moonset равны nil, если
+// в эти сутки Луна не восходит или не заходит.
+type MoonTimes struct {
+	Moonrise *time.Time
+	Moonset  *time.Time
+	// Phase доля лунного месяца: 0 - новолуние, 0.25 - первая четверть,
+	// 0.5 - полнолуние, 0.75 - последняя четверть
+	Phase float64
+	// Illumination освещенная доля диска, 0..1
+	Illumination float64
+	// Age возраст Луны в сутках от новолуния
+	Age float64
+}
+
+// Moon рассчитывает восход и заход Луны для календарной даты date (в ее
+// часовом поясе) и фазу на момент date. Положение Луны вычисляется по
+// главным членам теории движения; точность восхода и захода - несколько минут.
+func Moon(date time.Time, lat, lon float64) MoonTimes {
+	phase, illumination := moonIllumination(julianDay(date) - j2000)
+	times := MoonTimes{
+		Phase:        phase,
+		Illumination: illumination,
+		Age:          phase * synodicMonth,
+	}
+
+	y, m, d := date.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, date.Location())
+	end := start.AddDate(0, 0, 1)
+
+	altitude := func(t time.Time) float64 {
+		return moonAltitude(julianDay(t)-j2000, lat, lon) - moonHorizon*rad
+	}
+
+	// Ищем пересечения горизонта с шагом в час и уточняем их делением отрезка
+	prev := start
+	prevAlt := altitude(prev)
+	for t := start.Add(time.Hour); !t.After(end); t = t.Add(time.Hour) {
+		alt := altitude(t)
+		if (prevAlt < 0) != (alt < 0) {
+			crossing := bisect(prev, t, prevAlt < 0, altitude)
+			if prevAlt < 0 && times.Moonrise == nil {
+				times.Moonrise = &crossing
+			} else if prevAlt >= 0 && times.Moonset == nil {
+				times.Moonset = &crossing
+			}
+		}
+		prev, prevAlt = t, alt
+	}
+
+	return times
+}
+
+// bisect уточняет момент смены знака высоты на отрезке [a, b] до секунды
+func bisect(a, b time.Time, rising bool, altitude func(time.Time) float64) time.Time {
+	for b.Sub(a) > time.Second {
+		mid := a.Add(b.Sub(a) / 2)
+		if (altitude(mid) < 0) == rising {
+			a = mid
+		} else {
+			b = mid
+		}
+	}
+	return a.Add(b.Sub(a) / 2).Round(time.Second).UTC()
+}
+
+// moonCoords геоцентрические координаты Луны (радианы) и расстояние (км)
+// для d суток от J2000
+func moonCoords(d float64) (ra, dec, dist float64) {
+	l, b, dist := moonEcliptic(d)
+	ra, dec = eclipticToEquatorial(l, b)
+	return ra, dec, dist
+}
+
+// moonEcliptic эклиптические долгота и широта Луны (радианы) и расстояние
+// (км) для d суток от J2000. Кроме уравнения центра учтены эвекция,
+// вариация и годичное неравенство: без них долгота ошибается до 2°, то есть
+// на несколько часов в моменте фазы.
+func moonEcliptic(d float64) (l, b, dist float64) {
+	L := (218.316 + 13.176396*d) * rad   // средняя долгота
+	M := (134.963 + 13.064993*d) * rad   // средняя аномалия
+	F := (93.272 + 13.229350*d) * rad    // аргумент широты
+	D := (297.850 + 12.190749*d) * rad   // средняя элонгация
+	Ms := (357.529 + 0.98560028*d) * rad // средняя аномалия Солнца
+
+	l = L + 6.289*rad*math.Sin(M) +
+		1.274*rad*math.Sin(2*D-M) + // эвекция
+		0.658*rad*math.Sin(2*D) + // вариация
+		0.214*rad*math.Sin(2*M) -
+		0.186*rad*math.Sin(Ms) // годичное неравенство
+	b = 5.128 * rad * math.Sin(F)
+	dist = 385001 - 20905*math.Cos(M)
+	return l, b, dist
+}
+
+// moonAltitude высота Луны над горизонтом, радианы
+func moonAltitude(d, lat, lon float64) float64 {
+	ra, dec, _ := moonCoords(d)
+	sidereal := (280.16+360.9856235*d)*rad + lon*rad
+	hourAngle := sidereal - ra
+	return math.Asin(math.Sin(lat*rad)*math.Sin(dec) + math.Cos(lat*rad)*math.Cos(dec)*math.Cos(hourAngle))
+}
+
+// moonIllumination фаза (доля лунного месяца) и освещенная доля диска.
+// Фаза считается по разности эклиптических долгот, как и моменты фаз в
+// календарях: по угловому расстоянию до Солнца она не доходит до нуля, когда
+// Луна в новолуние на 5° выше или ниже эклиптики.
+func moonIllumination(d float64) (phase, fraction float64) {
+	sunRA, sunDec := sunCoords(d)
+	moonRA, moonDec, moonDist := moonCoords(d)
+
+	elongation := math.Acos(math.Sin(sunDec)*math.Sin(moonDec) +
+		math.Cos(sunDec)*math.Cos(moonDec)*math.Cos(sunRA-moonRA))
+	inc := math.Atan2(sunDistance*math.Sin(elongation), moonDist-sunDistance*math.Cos(elongation))
+	fraction = (1 + math.Cos(inc)) / 2
+
+	moonLon, _, _ := moonEcliptic(d)
+	phase = math.Mod(moonLon-sunLongitude(d), 2*math.Pi) / (2 * math.Pi)
+	if phase < 0 {
+		phase++
+	}
+	return phase, fraction
+}
+
+// phaseNames названия фаз Луны по восьмым долям месяца
+var phaseNames = []string{
+	"новолуние",
+	"растущий серп",
+	"первая четверть",
+	"растущая луна",
+	"полнолуние",
+	"убывающая луна",
+	"последняя четверть",
+	"убывающий серп",
+}
+
+// PhaseName название фазы Луны
+func PhaseName(phase float64) string {
+	return phaseNames[int(math.Floor(phase*8+0.5))%len(phaseNames)]
+}
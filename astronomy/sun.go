@@ -0,0 +1,150 @@
+package astronomy
+
+import (
+	"math"
+	"time"
+)
+
+// Высота центра Солнца для событий: восход и закат учитывают рефракцию и
+// радиус диска, сумерки отсчитываются по углу погружения под горизонт
+const (
+	horizonSunrise      = -0.833
+	horizonCivil        = -6.0
+	horizonNautical     = -12.0
+	horizonAstronomical = -18.0
+)
+
+// obliquity наклон эклиптики к экватору, радианы
+const obliquity = 23.4397 * rad
+
+const rad = math.Pi / 180
+
+// j2000 юлианская дата эпохи J2000.0
+const j2000 = 2451545.0
+
+// SunTimes моменты солнечных событий за сутки. Событие, которого в эти
+// сутки нет (полярный день или ночь), равно nil.
+type SunTimes struct {
+	SolarNoon        time.Time
+	Sunrise          *time.Time
+	Sunset           *time.Time
+	CivilDawn        *time.Time
+	CivilDusk        *time.Time
+	NauticalDawn     *time.Time
+	NauticalDusk     *time.Time
+	AstronomicalDawn *time.Time
+	AstronomicalDusk *time.Time
+	// DayLength от восхода до заката; 24 часа в полярный день, 0 в полярную ночь
+	DayLength  time.Duration
+	PolarDay   bool
+	PolarNight bool
+}
+
+// Sun рассчитывает солнечные события для календарной даты date (в ее часовом
+// поясе) в точке lat, lon (градусы, восточная долгота положительна).
+// Используется уравнение восхода NOAA; точность порядка минуты вне полярных
+// широт.
+func Sun(date time.Time, lat, lon float64) SunTimes {
+	y, m, d := date.Date()
+	// Номер суток от J2000 для полудня по UTC выбранной даты
+	n := julianDay(time.Date(y, m, d, 12, 0, 0, 0, time.UTC)) - j2000
+
+	// Средний солнечный полдень в точке
+	meanNoon := n - lon/360
+	M := math.Mod(357.5291+0.98560028*meanNoon, 360) * rad
+	C := 1.9148*math.Sin(M) + 0.02*math.Sin(2*M) + 0.0003*math.Sin(3*M)
+	lambda := math.Mod(M/rad+C+180+102.9372, 360) * rad
+	transit := j2000 + meanNoon + 0.0053*math.Sin(M) - 0.0069*math.Sin(2*lambda)
+	declination := math.Asin(math.Sin(lambda) * math.Sin(obliquity))
+
+	times := SunTimes{SolarNoon: fromJulian(transit)}
+
+	// hourAngle часовой угол Солнца на высоте horizon при склонении dec, градусы
+	hourAngle := func(horizon, dec float64) (float64, int) {
+		cosOmega := (math.Sin(horizon*rad) - math.Sin(lat*rad)*math.Sin(dec)) /
+			(math.Cos(lat*rad) * math.Cos(dec))
+		switch {
+		case cosOmega > 1:
+			// Солнце весь день ниже заданной высоты
+			return 0, -1
+		case cosOmega < -1:
+			// Солнце весь день выше заданной высоты
+			return 0, 1
+		}
+		return math.Acos(cosOmega) / rad, 0
+	}
+
+	// refine уточняет момент события по склонению Солнца в этот момент: за
+	// полсуток оно меняется до 0.2°, что у равноденствия сдвигает восход и
+	// закат больше чем на минуту
+	refine := func(horizon, omega, sign float64) time.Time {
+		jd := transit + sign*omega/360
+		for i := 0; i < 2; i++ {
+			_, dec := sunCoords(jd - j2000)
+			refined, state := hourAngle(horizon, dec)
+			if state != 0 {
+				break
+			}
+			jd = transit + sign*refined/360
+		}
+		return fromJulian(jd)
+	}
+
+	event := func(horizon float64) (dawn, dusk *time.Time, state int) {
+		omega, state := hourAngle(horizon, declination)
+		if state != 0 {
+			return nil, nil, state
+		}
+		rise := refine(horizon, omega, -1)
+		set := refine(horizon, omega, 1)
+		return &rise, &set, 0
+	}
+
+	var state int
+	times.Sunrise, times.Sunset, state = event(horizonSunrise)
+	times.CivilDawn, times.CivilDusk, _ = event(horizonCivil)
+	times.NauticalDawn, times.NauticalDusk, _ = event(horizonNautical)
+	times.AstronomicalDawn, times.AstronomicalDusk, _ = event(horizonAstronomical)
+
+	switch state {
+	case 1:
+		times.PolarDay = true
+		times.DayLength = 24 * time.Hour
+	case -1:
+		times.PolarNight = true
+	default:
+		times.DayLength = times.Sunset.Sub(*times.Sunrise)
+	}
+
+	return times
+}
+
+// sunCoords экваториальные координаты Солнца (радианы) для d суток от J2000
+func sunCoords(d float64) (ra, dec float64) {
+	return eclipticToEquatorial(sunLongitude(d), 0)
+}
+
+// sunLongitude эклиптическая долгота Солнца (радианы) для d суток от J2000
+func sunLongitude(d float64) float64 {
+	M := (357.5291 + 0.98560028*d) * rad
+	C := (1.9148*math.Sin(M) + 0.02*math.Sin(2*M) + 0.0003*math.Sin(3*M)) * rad
+	return M + C + 102.9372*rad + math.Pi
+}
+
+// eclipticToEquatorial переводит эклиптические долготу и широту в прямое
+// восхождение и склонение
+func eclipticToEquatorial(l, b float64) (ra, dec float64) {
+	ra = math.Atan2(math.Sin(l)*math.Cos(obliquity)-math.Tan(b)*math.Sin(obliquity), math.Cos(l))
+	dec = math.Asin(math.Sin(b)*math.Cos(obliquity) + math.Cos(b)*math.Sin(obliquity)*math.Sin(l))
+	return ra, dec
+}
+
+// julianDay юлианская дата момента t
+func julianDay(t time.Time) float64 {
+	return float64(t.UnixMilli())/86400000 + 2440587.5
+}
+
+// fromJulian момент времени по юлианской дате (UTC) с точностью до секунды
+func fromJulian(jd float64) time.Time {
+	return time.Unix(int64(math.Round((jd-2440587.5)*86400)), 0).UTC()
+}
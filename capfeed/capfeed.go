@@ -0,0 +1,384 @@
+// Package capfeed разбирает предупреждения в формате CAP 1.2 (Common
+// Alerting Protocol): отдельные документы <alert>, ленты Atom со встроенными
+// предупреждениями или полями cap:* (MeteoAlarm) и ленты RSS/Atom со
+// ссылками на CAP документы.
+package capfeed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"weather-aggregator/geo"
+	"weather-aggregator/models"
+)
+
+// ErrUnsupported возвращается для XML, не являющегося CAP, Atom или RSS
+var ErrUnsupported = errors.New("неизвестный формат ленты предупреждений")
+
+// Point точка полигона области
+type Point struct {
+	Lat, Lon float64
+}
+
+// Circle круговая область, радиус в км
+type Circle struct {
+	Center   Point
+	RadiusKm float64
+}
+
+// Alert предупреждение с геометрией областей
+type Alert struct {
+	models.Alert
+	Polygons [][]Point
+	Circles  []Circle
+}
+
+// Document результат разбора: предупреждения и ссылки на CAP документы,
+// которые нужно загрузить отдельно
+type Document struct {
+	Alerts []Alert
+	Links  []string
+}
+
+type capAlert struct {
+	Identifier string    `xml:"identifier"`
+	Status     string    `xml:"status"`
+	MsgType    string    `xml:"msgType"`
+	Infos      []capInfo `xml:"info"`
+}
+
+type capInfo struct {
+	Language    string    `xml:"language"`
+	Event       string    `xml:"event"`
+	Urgency     string    `xml:"urgency"`
+	Severity    string    `xml:"severity"`
+	Effective   string    `xml:"effective"`
+	Onset       string    `xml:"onset"`
+	Expires     string    `xml:"expires"`
+	Headline    string    `xml:"headline"`
+	Description string    `xml:"description"`
+	Instruction string    `xml:"instruction"`
+	Areas       []capArea `xml:"area"`
+}
+
+type capArea struct {
+	AreaDesc string   `xml:"areaDesc"`
+	Polygons []string `xml:"polygon"`
+	Circles  []string `xml:"circle"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomEntry struct {
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Summary string     `xml:"summary"`
+	Links   []atomLink `xml:"link"`
+	Alert   *capAlert  `xml:"alert"`
+	Content struct {
+		Alert *capAlert `xml:"alert"`
+	} `xml:"content"`
+
+	// Поля cap:* прямо в записи ленты (MeteoAlarm)
+	Event       string `xml:"event"`
+	Severity    string `xml:"severity"`
+	Urgency     string `xml:"urgency"`
+	Effective   string `xml:"effective"`
+	Onset       string `xml:"onset"`
+	Expires     string `xml:"expires"`
+	AreaDesc    string `xml:"areaDesc"`
+	Polygon     string `xml:"polygon"`
+	Status      string `xml:"status"`
+	MessageType string `xml:"msgType"`
+}
+
+type rssItem struct {
+	Link string `xml:"link"`
+}
+
+// Parse разбирает CAP документ или ленту. language - предпочтительный язык
+// блоков <info> (например "ru"); если такого нет, берется первый блок.
+func Parse(data []byte, language string) (*Document, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	switch root {
+	case "alert":
+		var a capAlert
+		if err := xml.Unmarshal(data, &a); err != nil {
+			return nil, fmt.Errorf("ошибка разбора CAP: %w", err)
+		}
+		if alert, ok := a.convert(language); ok {
+			doc.Alerts = append(doc.Alerts, alert)
+		}
+
+	case "feed":
+		var feed struct {
+			Entries []atomEntry `xml:"entry"`
+		}
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			return nil, fmt.Errorf("ошибка разбора Atom: %w", err)
+		}
+		for _, e := range feed.Entries {
+			doc.addEntry(e, language)
+		}
+
+	case "rss":
+		var rss struct {
+			Items []rssItem `xml:"channel>item"`
+		}
+		if err := xml.Unmarshal(data, &rss); err != nil {
+			return nil, fmt.Errorf("ошибка разбора RSS: %w", err)
+		}
+		for _, item := range rss.Items {
+			if item.Link != "" {
+				doc.Links = append(doc.Links, strings.TrimSpace(item.Link))
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: <%s>", ErrUnsupported, root)
+	}
+
+	return doc, nil
+}
+
+// rootElement возвращает локальное имя корневого элемента
+func rootElement(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("ошибка разбора XML: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+func (d *Document) addEntry(e atomEntry, language string) {
+	embedded := e.Alert
+	if embedded == nil {
+		embedded = e.Content.Alert
+	}
+	if embedded != nil {
+		if alert, ok := embedded.convert(language); ok {
+			d.Alerts = append(d.Alerts, alert)
+		}
+		return
+	}
+
+	if e.Event != "" {
+		if !isActual(e.Status, e.MessageType) {
+			return
+		}
+		alert := Alert{Alert: models.Alert{
+			ID:          e.ID,
+			Event:       e.Event,
+			Headline:    strings.TrimSpace(e.Title),
+			Description: strings.TrimSpace(e.Summary),
+			Severity:    normalize(e.Severity, models.SeverityUnknown),
+			Urgency:     normalize(e.Urgency, models.UrgencyUnknown),
+			Onset:       firstTime(e.Onset, e.Effective),
+			Expires:     parseTime(e.Expires),
+		}}
+		if e.AreaDesc != "" {
+			alert.Areas = splitAreas(e.AreaDesc)
+		}
+		if polygon, ok := parsePolygon(e.Polygon); ok {
+			alert.Polygons = append(alert.Polygons, polygon)
+		}
+		d.Alerts = append(d.Alerts, alert)
+		return
+	}
+
+	// Запись только ссылается на CAP документ
+	for _, link := range e.Links {
+		if strings.Contains(link.Type, "cap") || strings.HasSuffix(link.Href, ".xml") ||
+			(link.Rel == "alternate" && link.Type == "") {
+			d.Links = append(d.Links, link.Href)
+			return
+		}
+	}
+}
+
+// convert выбирает блок <info> на нужном языке. Отмены, учения и тестовые
+// сообщения пропускаются.
+func (a capAlert) convert(language string) (Alert, bool) {
+	if !isActual(a.Status, a.MsgType) || len(a.Infos) == 0 {
+		return Alert{}, false
+	}
+
+	info := a.Infos[0]
+	for _, candidate := range a.Infos {
+		if language != "" && strings.HasPrefix(strings.ToLower(candidate.Language), strings.ToLower(language)) {
+			info = candidate
+			break
+		}
+	}
+
+	alert := Alert{Alert: models.Alert{
+		ID:          a.Identifier,
+		Event:       strings.TrimSpace(info.Event),
+		Headline:    strings.TrimSpace(info.Headline),
+		Description: strings.TrimSpace(info.Description),
+		Instruction: strings.TrimSpace(info.Instruction),
+		Severity:    normalize(info.Severity, models.SeverityUnknown),
+		Urgency:     normalize(info.Urgency, models.UrgencyUnknown),
+		Onset:       firstTime(info.Onset, info.Effective),
+		Expires:     parseTime(info.Expires),
+	}}
+
+	for _, area := range info.Areas {
+		alert.Areas = append(alert.Areas, splitAreas(area.AreaDesc)...)
+		for _, raw := range area.Polygons {
+			if polygon, ok := parsePolygon(raw); ok {
+				alert.Polygons = append(alert.Polygons, polygon)
+			}
+		}
+		for _, raw := range area.Circles {
+			if circle, ok := parseCircle(raw); ok {
+				alert.Circles = append(alert.Circles, circle)
+			}
+		}
+	}
+
+	return alert, alert.Event != ""
+}
+
+// Covers проверяет, входит ли точка в области предупреждения. Второе
+// значение false, если у предупреждения нет геометрии.
+func (a Alert) Covers(lat, lon float64) (bool, bool) {
+	if len(a.Polygons) == 0 && len(a.Circles) == 0 {
+		return false, false
+	}
+
+	for _, polygon := range a.Polygons {
+		if inPolygon(polygon, lat, lon) {
+			return true, true
+		}
+	}
+	for _, c := range a.Circles {
+		if geo.Distance(lat, lon, c.Center.Lat, c.Center.Lon) <= c.RadiusKm {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// inPolygon проверка принадлежности точки полигону методом лучей
+func inPolygon(polygon []Point, lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		pi, pj := polygon[i], polygon[j]
+		if (pi.Lat > lat) != (pj.Lat > lat) &&
+			lon < (pj.Lon-pi.Lon)*(lat-pi.Lat)/(pj.Lat-pi.Lat)+pi.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// parsePolygon разбирает "lat,lon lat,lon ..." (не менее 4 точек по CAP 1.2)
+func parsePolygon(raw string) ([]Point, bool) {
+	fields := strings.Fields(raw)
+	if len(fields) < 4 {
+		return nil, false
+	}
+
+	polygon := make([]Point, 0, len(fields))
+	for _, f := range fields {
+		p, ok := parsePoint(f)
+		if !ok {
+			return nil, false
+		}
+		polygon = append(polygon, p)
+	}
+	return polygon, true
+}
+
+// parseCircle разбирает "lat,lon radius"
+func parseCircle(raw string) (Circle, bool) {
+	fields := strings.Fields(raw)
+	if len(fields) != 2 {
+		return Circle{}, false
+	}
+	center, ok := parsePoint(fields[0])
+	if !ok {
+		return Circle{}, false
+	}
+	radius, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return Circle{}, false
+	}
+	return Circle{Center: center, RadiusKm: radius}, true
+}
+
+func parsePoint(raw string) (Point, bool) {
+	latRaw, lonRaw, ok := strings.Cut(raw, ",")
+	if !ok {
+		return Point{}, false
+	}
+	lat, errLat := strconv.ParseFloat(latRaw, 64)
+	lon, errLon := strconv.ParseFloat(lonRaw, 64)
+	if errLat != nil || errLon != nil {
+		return Point{}, false
+	}
+	return Point{Lat: lat, Lon: lon}, true
+}
+
+// isActual пропускает учения, тесты и отмены
+func isActual(status, msgType string) bool {
+	if status != "" && !strings.EqualFold(status, "Actual") {
+		return false
+	}
+	return !strings.EqualFold(msgType, "Cancel")
+}
+
+func normalize(value, fallback string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func splitAreas(desc string) []string {
+	var areas []string
+	for _, area := range strings.FieldsFunc(desc, func(r rune) bool { return r == ';' }) {
+		if area = strings.TrimSpace(area); area != "" {
+			areas = append(areas, area)
+		}
+	}
+	return areas
+}
+
+func firstTime(values ...string) time.Time {
+	for _, v := range values {
+		if t := parseTime(v); !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func parseTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,131 @@
+package capfeed
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"weather-aggregator/models"
+)
+
+func parseFile(t *testing.T, name, language string) *Document {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := Parse(data, language)
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", name, err)
+	}
+	return doc
+}
+
+func TestParseAlert(t *testing.T) {
+	doc := parseFile(t, "alert.xml", "ru")
+	if len(doc.Alerts) != 1 || len(doc.Links) != 0 {
+		t.Fatalf("документ %+v, ожидалось одно предупреждение", doc)
+	}
+	a := doc.Alerts[0]
+
+	msk := time.FixedZone("", 3*3600)
+	want := models.Alert{
+		ID:          "urn:oid:2.49.0.1.643.0.2099.1",
+		Event:       "Сильный ветер",
+		Headline:    "Штормовое предупреждение",
+		Description: "Порывы до 25 м/с.",
+		Instruction: "Не оставляйте автомобили под деревьями.",
+		Severity:    models.SeveritySevere,
+		Urgency:     models.UrgencyExpected,
+		Areas:       []string{"Москва", "Московская область"},
+		Onset:       time.Date(2099, 1, 10, 9, 0, 0, 0, msk),
+		Expires:     time.Date(2099, 1, 11, 9, 0, 0, 0, msk),
+	}
+	got := a.Alert
+	if !got.Onset.Equal(want.Onset) || !got.Expires.Equal(want.Expires) {
+		t.Errorf("интервал %s - %s, ожидалось %s - %s", got.Onset, got.Expires, want.Onset, want.Expires)
+	}
+	got.Onset, got.Expires, want.Onset, want.Expires = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("предупреждение\n%+v\nожидалось\n%+v", got, want)
+	}
+	if len(a.Polygons) != 1 || len(a.Polygons[0]) != 5 || len(a.Circles) != 1 || a.Circles[0].RadiusKm != 20 {
+		t.Errorf("геометрия: полигоны %v, круги %v", a.Polygons, a.Circles)
+	}
+
+	// Без блока на нужном языке берется первый
+	if doc := parseFile(t, "alert.xml", "de"); doc.Alerts[0].Event != "Strong wind" {
+		t.Errorf("событие %q, ожидался первый блок <info>", doc.Alerts[0].Event)
+	}
+}
+
+func TestParseAtomFeed(t *testing.T) {
+	doc := parseFile(t, "feed.xml", "ru")
+
+	// Учения и отмены пропускаются
+	if len(doc.Alerts) != 2 {
+		t.Fatalf("предупреждения %+v, ожидалось два", doc.Alerts)
+	}
+	frost, fog := doc.Alerts[0], doc.Alerts[1]
+	if frost.ID != "frost-1" || frost.Event != "Заморозки" || frost.Severity != models.SeverityModerate ||
+		frost.Urgency != models.UrgencyFuture || !reflect.DeepEqual(frost.Areas, []string{"Тверь"}) {
+		t.Errorf("встроенное предупреждение %+v", frost.Alert)
+	}
+	// Поля cap:* MeteoAlarm; onset берется из effective
+	if fog.ID != "meteoalarm-fog" || fog.Event != "Fog" || fog.Headline != "Yellow Fog Warning" ||
+		fog.Description != "Visibility below 200 m." || fog.Urgency != models.UrgencyImmediate ||
+		!fog.Onset.Equal(time.Date(2099, 1, 10, 3, 0, 0, 0, time.UTC)) || len(fog.Polygons) != 1 {
+		t.Errorf("предупреждение MeteoAlarm %+v, полигоны %v", fog.Alert, fog.Polygons)
+	}
+
+	if !reflect.DeepEqual(doc.Links, []string{"https://example.org/cap/storm.xml"}) {
+		t.Errorf("ссылки %v", doc.Links)
+	}
+}
+
+func TestParseRSS(t *testing.T) {
+	doc := parseFile(t, "rss.xml", "")
+	want := []string{"https://example.org/cap/1.xml", "https://example.org/cap/2.xml"}
+	if len(doc.Alerts) != 0 || !reflect.DeepEqual(doc.Links, want) {
+		t.Errorf("документ %+v, ожидались ссылки %v", doc, want)
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	if _, err := Parse([]byte(`<html><body/></html>`), ""); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ошибка %v, ожидалась ErrUnsupported", err)
+	}
+	if _, err := Parse([]byte(`не XML`), ""); err == nil {
+		t.Error("ожидалась ошибка разбора")
+	}
+}
+
+func TestCovers(t *testing.T) {
+	a := parseFile(t, "alert.xml", "ru").Alerts[0]
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		covered  bool
+	}{
+		{"Polygon", 55.7558, 37.6173, true},
+		{"Circle", 59.93, 30.36, true},
+		{"Outside", 55.0, 37.6, false},
+		{"OutsideCircle", 59.94, 31.0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			covered, hasGeometry := a.Covers(tt.lat, tt.lon)
+			if covered != tt.covered || !hasGeometry {
+				t.Errorf("Covers = %v, %v; ожидалось %v, true", covered, hasGeometry, tt.covered)
+			}
+		})
+	}
+
+	if _, hasGeometry := (Alert{}).Covers(55.7558, 37.6173); hasGeometry {
+		t.Error("у предупреждения без областей нет геометрии")
+	}
+}
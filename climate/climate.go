@@ -0,0 +1,280 @@
+// Package climate сравнивает текущую погоду с климатической нормой. Нормы
+// загружаются из CSV (суточные или месячные средние для локации) или, если
+// загруженной нормы нет, рассчитываются по собственной истории: по
+// агрегированным наблюдениям в тот же час суток (UTC) в окрестности
+// календарной даты за сохраненные прошлые годы.
+package climate
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"weather-aggregator/history"
+	"weather-aggregator/models"
+)
+
+// Источники норм
+const (
+	SourceImported = "imported"
+	SourceHistory  = "history"
+)
+
+// Fields величины, для которых считаются аномалии
+var Fields = []string{"temperature", "humidity", "pressure", "wind_speed"}
+
+func isField(name string) bool {
+	for _, f := range Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Options параметры расчета норм по истории
+type Options struct {
+	// DayWindow сколько соседних календарных дней с каждой стороны учитывать (по умолчанию 7)
+	DayWindow int
+	// HourWindow сколько соседних часов с каждой стороны учитывать (по умолчанию 1)
+	HourWindow int
+	// MinDays из скольких разных суток должна состоять норма (по умолчанию 10)
+	MinDays int
+	// MinYears за сколько прошлых лет должны быть наблюдения (по умолчанию 1)
+	MinYears int
+	// CacheTTL сколько хранить прочитанную историю локации (по умолчанию час)
+	CacheTTL time.Duration
+}
+
+// Climatology находит нормы и рассчитывает отклонения от них
+type Climatology struct {
+	table *Table
+	store *history.Store
+	opts  Options
+
+	mu    sync.Mutex
+	cache map[string]observationsEntry
+}
+
+type observationsEntry struct {
+	points []history.Point
+	read   time.Time
+}
+
+// New создает расчет аномалий по загруженным нормам table и истории store;
+// любой из них может быть nil
+func New(table *Table, store *history.Store, opts Options) *Climatology {
+	if opts.DayWindow <= 0 {
+		opts.DayWindow = 7
+	}
+	if opts.HourWindow <= 0 {
+		opts.HourWindow = 1
+	}
+	if opts.MinDays <= 0 {
+		opts.MinDays = 10
+	}
+	if opts.MinYears <= 0 {
+		opts.MinYears = 1
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = time.Hour
+	}
+	return &Climatology{table: table, store: store, opts: opts, cache: make(map[string]observationsEntry)}
+}
+
+// Anomalies отклонения средних значений погоды от нормы на момент now.
+// Величины без нормы пропускаются. Реализует aggregator.Climatology.
+func (c *Climatology) Anomalies(city, country string, weather *models.AggregatedWeather, now time.Time) []models.Anomaly {
+	now = now.UTC()
+	values := map[string]*models.AggregatedValue{
+		"temperature": weather.Temperature,
+		"humidity":    weather.Humidity,
+		"pressure":    weather.Pressure,
+		"wind_speed":  weather.WindSpeed,
+	}
+
+	var observations []history.Point
+	if c.store != nil {
+		var err error
+		if observations, err = c.observations(city, country, now); err != nil {
+			log.Printf("нормы по истории для %s недоступны: %v", city, err)
+		}
+	}
+
+	var anomalies []models.Anomaly
+	for _, field := range Fields {
+		value := values[field]
+		if value == nil {
+			continue
+		}
+
+		if normal, p, ok := c.table.Lookup(city, country, field, int(now.Month()), now.Day()); ok {
+			anomaly := newAnomaly(field, value.Average, normal.Mean, SourceImported, p)
+			if normal.StdDev > 0 {
+				classify(&anomaly, 50*(1+math.Erf((value.Average-normal.Mean)/(normal.StdDev*math.Sqrt2))))
+			}
+			anomalies = append(anomalies, anomaly)
+			continue
+		}
+
+		samples, days, years := c.sample(observations, field, now)
+		if days < c.opts.MinDays || years < c.opts.MinYears {
+			continue
+		}
+		sum := 0.0
+		for _, v := range samples {
+			sum += v
+		}
+		anomaly := newAnomaly(field, value.Average, sum/float64(len(samples)), SourceHistory,
+			fmt.Sprintf("%02d-%02d", now.Month(), now.Day()))
+		anomaly.Samples = len(samples)
+		classify(&anomaly, Percentile(samples, value.Average))
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies
+}
+
+// observations агрегированные наблюдения локации, кроме суток now; история
+// читается не чаще CacheTTL
+func (c *Climatology) observations(city, country string, now time.Time) ([]history.Point, error) {
+	key := fmt.Sprintf("%s,%s", city, country)
+
+	c.mu.Lock()
+	cached, found := c.cache[key]
+	c.mu.Unlock()
+	if found && now.Sub(cached.read) < c.opts.CacheTTL {
+		return cached.points, nil
+	}
+
+	// Текущие сутки не входят в норму, с которой их сравнивают
+	points, err := c.store.Query(history.Query{
+		City:    city,
+		Country: country,
+		To:      now.Truncate(24 * time.Hour),
+		Source:  history.SourceAggregated,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cache[key] = observationsEntry{points: points, read: now}
+	c.mu.Unlock()
+
+	return points, nil
+}
+
+// sample значения величины в окне вокруг календарной даты и часа now за
+// прошлые годы и число разных суток и лет, из которых они взяты. Наблюдения
+// текущего сезона не входят в норму: это та же погода, с которой ее сравнивают.
+func (c *Climatology) sample(observations []history.Point, field string, now time.Time) ([]float64, int, int) {
+	var samples []float64
+	days := make(map[string]bool)
+	years := make(map[int]bool)
+	for _, p := range observations {
+		v, ok := p.Values[field]
+		if !ok {
+			continue
+		}
+		t := p.Time.UTC()
+		if circular(t.YearDay(), now.YearDay(), 365) > c.opts.DayWindow ||
+			circular(t.Hour(), now.Hour(), 24) > c.opts.HourWindow {
+			continue
+		}
+		// Окно вокруг даты может переходить через новый год, поэтому год
+		// отсчитывается от now, а не по календарю
+		yearsAgo := int(math.Round(now.Sub(t).Hours() / (24 * 365.2425)))
+		if yearsAgo == 0 {
+			continue
+		}
+		samples = append(samples, v)
+		days[t.Format(time.DateOnly)] = true
+		years[yearsAgo] = true
+	}
+	return samples, len(days), len(years)
+}
+
+// circular расстояние между a и b на круге длины n
+func circular(a, b, n int) int {
+	d := a - b
+	if d < 0 {
+		d = -d
+	}
+	return min(d, n-d)
+}
+
+// Percentile доля значений samples ниже v в процентах (равные v считаются
+// наполовину)
+func Percentile(samples []float64, v float64) float64 {
+	sorted := make([]float64, len(samples))
+	copy(sorted, samples)
+	sort.Float64s(sorted)
+
+	below := sort.SearchFloat64s(sorted, v)
+	equal := 0
+	for i := below; i < len(sorted) && sorted[i] == v; i++ {
+		equal++
+	}
+	return 100 * (float64(below) + float64(equal)/2) / float64(len(sorted))
+}
+
+func newAnomaly(field string, value, normal float64, source, period string) models.Anomaly {
+	return models.Anomaly{
+		Field:     field,
+		Value:     round(value),
+		Normal:    round(normal),
+		Deviation: round(value - normal),
+		Source:    source,
+		Period:    period,
+	}
+}
+
+// descriptions описания категорий аномалий; для температуры свои
+var descriptions = map[string]map[string]string{
+	"temperature": {
+		models.AnomalyMuchBelow: "необычно холодно",
+		models.AnomalyBelow:     "холоднее обычного",
+		models.AnomalyNormal:    "около нормы",
+		models.AnomalyAbove:     "теплее обычного",
+		models.AnomalyMuchAbove: "необычно тепло",
+	},
+	"": {
+		models.AnomalyMuchBelow: "намного ниже нормы",
+		models.AnomalyBelow:     "ниже нормы",
+		models.AnomalyNormal:    "около нормы",
+		models.AnomalyAbove:     "выше нормы",
+		models.AnomalyMuchAbove: "намного выше нормы",
+	},
+}
+
+// classify задает процентиль аномалии и по нему категорию и описание
+func classify(a *models.Anomaly, percentile float64) {
+	p := math.Round(percentile*10) / 10
+	a.Percentile = &p
+
+	switch {
+	case percentile < 10:
+		a.Category = models.AnomalyMuchBelow
+	case percentile < 30:
+		a.Category = models.AnomalyBelow
+	case percentile <= 70:
+		a.Category = models.AnomalyNormal
+	case percentile <= 90:
+		a.Category = models.AnomalyAbove
+	default:
+		a.Category = models.AnomalyMuchAbove
+	}
+
+	byCategory, ok := descriptions[a.Field]
+	if !ok {
+		byCategory = descriptions[""]
+	}
+	a.Description = byCategory[a.Category]
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,156 @@
+package climate
+
+import (
+	"testing"
+	"time"
+
+	"weather-aggregator/history"
+	"weather-aggregator/models"
+)
+
+// dailyPoints агрегированные наблюдения за days суток до end в тот же час
+func dailyPoints(end time.Time, days int, temperature float64) []history.Point {
+	points := make([]history.Point, 0, days)
+	for i := 1; i <= days; i++ {
+		points = append(points, history.Point{
+			Time:   end.AddDate(0, 0, -i),
+			Source: history.SourceAggregated,
+			Values: map[string]float64{"temperature": temperature},
+		})
+	}
+	return points
+}
+
+func TestHistoryNormalRequiresPastYears(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	weather := &models.AggregatedWeather{Temperature: &models.AggregatedValue{Average: 5}}
+
+	tests := []struct {
+		name     string
+		points   []history.Point
+		opts     Options
+		wantOK   bool
+		normal   float64
+		samples  int
+		category string
+	}{
+		// Две недели этого года - та же погода, а не норма
+		{"CurrentYearOnly", dailyPoints(now, 14, 10), Options{}, false, 0, 0, ""},
+		{"PastYear", dailyPoints(now.AddDate(-1, 0, 7), 14, 10), Options{}, true, 10, 14, models.AnomalyMuchBelow},
+		{
+			"CurrentYearExcluded",
+			append(dailyPoints(now.AddDate(-1, 0, 7), 14, 10), dailyPoints(now, 5, 0)...),
+			Options{}, true, 10, 14, models.AnomalyMuchBelow,
+		},
+		{"NotEnoughYears", dailyPoints(now.AddDate(-1, 0, 7), 14, 10), Options{MinYears: 2}, false, 0, 0, ""},
+		{
+			"TwoYears",
+			append(dailyPoints(now.AddDate(-1, 0, 7), 14, 4), dailyPoints(now.AddDate(-2, 0, 7), 14, 6)...),
+			Options{MinYears: 2}, true, 5, 28, models.AnomalyNormal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := history.Open(t.TempDir(), history.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Append("Москва", "RU", tt.points...); err != nil {
+				t.Fatal(err)
+			}
+
+			anomalies := New(nil, store, tt.opts).Anomalies("Москва", "RU", weather, now)
+			if !tt.wantOK {
+				if len(anomalies) != 0 {
+					t.Fatalf("норма по истории рассчитана: %+v", anomalies)
+				}
+				return
+			}
+			if len(anomalies) != 1 {
+				t.Fatalf("аномалий %d, ожидалась одна: %+v", len(anomalies), anomalies)
+			}
+			a := anomalies[0]
+			if a.Source != SourceHistory || a.Normal != tt.normal || a.Samples != tt.samples || a.Category != tt.category {
+				t.Errorf("аномалия %+v, ожидались норма %v по %d наблюдениям, категория %s",
+					a, tt.normal, tt.samples, tt.category)
+			}
+		})
+	}
+}
+
+func TestSampleWindow(t *testing.T) {
+	// Начало января: окно ±7 дней захватывает конец декабря
+	now := time.Date(2026, 1, 3, 12, 0, 0, 0, time.UTC)
+	point := func(t time.Time, v float64) history.Point {
+		return history.Point{Time: t, Source: history.SourceAggregated, Values: map[string]float64{"temperature": v}}
+	}
+	observations := []history.Point{
+		point(time.Date(2024, 12, 28, 12, 0, 0, 0, time.UTC), 1), // прошлый сезон, конец декабря
+		point(time.Date(2025, 1, 9, 13, 0, 0, 0, time.UTC), 2),   // прошлый сезон, +6 дней, +1 час
+		point(time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), 3),   // позапрошлый сезон
+		point(time.Date(2025, 12, 30, 12, 0, 0, 0, time.UTC), 9), // текущий сезон через новый год
+		point(time.Date(2025, 1, 11, 12, 0, 0, 0, time.UTC), 9),  // вне окна дат
+		point(time.Date(2025, 1, 3, 14, 0, 0, 0, time.UTC), 9),   // вне окна часов
+		point(time.Date(2025, 7, 3, 12, 0, 0, 0, time.UTC), 9),   // другое время года
+		{Time: time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC), Values: map[string]float64{"humidity": 80}},
+	}
+
+	c := New(nil, nil, Options{})
+	samples, days, years := c.sample(observations, "temperature", now)
+	if len(samples) != 3 || samples[0] != 1 || samples[1] != 2 || samples[2] != 3 {
+		t.Errorf("выборка %v, ожидалось [1 2 3]", samples)
+	}
+	if days != 3 || years != 2 {
+		t.Errorf("суток %d, лет %d; ожидалось 3 и 2", days, years)
+	}
+}
+
+func TestHistoryNormalMinDays(t *testing.T) {
+	now := time.Date(2026, 1, 3, 12, 0, 0, 0, time.UTC)
+	weather := &models.AggregatedWeather{Temperature: &models.AggregatedValue{Average: 5}}
+	// Наблюдения прошлого сезона по 9 января; 13 суток начинаются 28 декабря
+	end := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		days   int
+		wantOK bool
+	}{
+		{"NotEnoughDays", 9, false},
+		{"EnoughDays", 10, true},
+		{"AcrossNewYear", 13, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := history.Open(t.TempDir(), history.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Append("Москва", "RU", dailyPoints(end, tt.days, 0)...); err != nil {
+				t.Fatal(err)
+			}
+
+			anomalies := New(nil, store, Options{}).Anomalies("Москва", "RU", weather, now)
+			if ok := len(anomalies) == 1; ok != tt.wantOK {
+				t.Fatalf("аномалии %+v, ожидалась норма: %v", anomalies, tt.wantOK)
+			}
+			if tt.wantOK && anomalies[0].Samples != tt.days {
+				t.Errorf("норма по %d наблюдениям, ожидалось %d", anomalies[0].Samples, tt.days)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	samples := []float64{4, 1, 3, 2, 3}
+	tests := []struct {
+		v, want float64
+	}{
+		{0, 0}, {1, 10}, {3, 60}, {3.5, 80}, {5, 100},
+	}
+	for _, tt := range tests {
+		if got := Percentile(samples, tt.v); got != tt.want {
+			t.Errorf("Percentile(%v) = %v, ожидалось %v", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package climate
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Normal климатическая норма величины: среднее и, если известно,
+// стандартное отклонение
+type Normal struct {
+	Mean   float64
+	StdDev float64 // 0 - разброс неизвестен
+}
+
+// Table загруженные нормы: локация -> величина -> период. Период - месяц и
+// день для суточной нормы или только месяц (день 0) для месячной.
+type Table struct {
+	normals map[string]map[string]map[period]Normal
+}
+
+type period struct{ month, day int }
+
+// csvColumns обязательные столбцы CSV с нормами
+var csvColumns = []string{"city", "country", "month", "day", "field", "mean", "std"}
+
+// LoadCSV читает нормы из CSV файла
+func LoadCSV(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения климатических норм: %w", err)
+	}
+	defer f.Close()
+
+	return ParseCSV(f)
+}
+
+// ParseCSV разбирает нормы в формате CSV с заголовком
+// city,country,month,day,field,mean,std (порядок столбцов любой). Пустой
+// day - месячная норма, пустой std - разброс неизвестен.
+func ParseCSV(r io.Reader) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения заголовка климатических норм: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("в климатических нормах нет столбца %s", name)
+		}
+	}
+
+	table := &Table{normals: make(map[string]map[string]map[period]Normal)}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения климатических норм: %w", err)
+		}
+		column := func(name string) string { return strings.TrimSpace(record[index[name]]) }
+
+		field := column("field")
+		if !isField(field) {
+			return nil, fmt.Errorf("строка %d: неизвестная величина %q, ожидается одна из: %s",
+				line, field, strings.Join(Fields, ", "))
+		}
+		month, err := strconv.Atoi(column("month"))
+		if err != nil || month < 1 || month > 12 {
+			return nil, fmt.Errorf("строка %d: некорректный месяц %q", line, column("month"))
+		}
+		day := 0
+		if v := column("day"); v != "" {
+			if day, err = strconv.Atoi(v); err != nil || day < 1 || day > 31 {
+				return nil, fmt.Errorf("строка %d: некорректный день %q", line, v)
+			}
+		}
+		mean, err := strconv.ParseFloat(column("mean"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: некорректное среднее %q", line, column("mean"))
+		}
+		normal := Normal{Mean: mean}
+		if v := column("std"); v != "" {
+			if normal.StdDev, err = strconv.ParseFloat(v, 64); err != nil || normal.StdDev < 0 {
+				return nil, fmt.Errorf("строка %d: некорректное стандартное отклонение %q", line, v)
+			}
+		}
+
+		key := locationKey(column("city"), column("country"))
+		if table.normals[key] == nil {
+			table.normals[key] = make(map[string]map[period]Normal)
+		}
+		if table.normals[key][field] == nil {
+			table.normals[key][field] = make(map[period]Normal)
+		}
+		table.normals[key][field][period{month, day}] = normal
+	}
+	return table, nil
+}
+
+// Lookup возвращает норму величины на дату: суточную, если она есть, иначе
+// месячную. p - период нормы (ММ-ДД или ММ).
+func (t *Table) Lookup(city, country, field string, month, day int) (normal Normal, p string, ok bool) {
+	if t == nil {
+		return Normal{}, "", false
+	}
+	byPeriod := t.normals[locationKey(city, country)][field]
+	if normal, ok := byPeriod[period{month, day}]; ok {
+		return normal, fmt.Sprintf("%02d-%02d", month, day), true
+	}
+	if normal, ok := byPeriod[period{month, 0}]; ok {
+		return normal, fmt.Sprintf("%02d", month), true
+	}
+	return Normal{}, "", false
+}
+
+// Len число загруженных норм
+func (t *Table) Len() int {
+	n := 0
+	for _, byField := range t.normals {
+		for _, byPeriod := range byField {
+			n += len(byPeriod)
+		}
+	}
+	return n
+}
+
+func locationKey(city, country string) string {
+	return strings.ToLower(strings.TrimSpace(city)) + "," + strings.ToUpper(strings.TrimSpace(country))
+}
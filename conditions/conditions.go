@@ -0,0 +1,380 @@
+// Package conditions переводит коды явлений погоды провайдеров (OpenWeatherMap,
+// WeatherAPI, met.no, NWS, METAR) в общие коды models.Condition*, выбирает
+// согласованное явление и формирует его описание на нужном языке.
+package conditions
+
+import (
+	"strings"
+
+	"weather-aggregator/metar"
+	"weather-aggregator/models"
+)
+
+// severity порядок явлений по опасности; при равном числе голосов побеждает
+// более опасное, чтобы гроза у одного из двух источников не терялась
+var severity = map[string]int{
+	models.ConditionClear:        1,
+	models.ConditionPartlyCloudy: 2,
+	models.ConditionCloudy:       3,
+	models.ConditionOvercast:     4,
+	models.ConditionHaze:         5,
+	models.ConditionFog:          6,
+	models.ConditionDrizzle:      7,
+	models.ConditionRainLight:    8,
+	models.ConditionSnowLight:    9,
+	models.ConditionRain:         10,
+	models.ConditionSnow:         11,
+	models.ConditionSleet:        12,
+	models.ConditionRainHeavy:    13,
+	models.ConditionSnowHeavy:    14,
+	models.ConditionSquall:       15,
+	models.ConditionFreezingRain: 16,
+	models.ConditionHail:         17,
+	models.ConditionThunderstorm: 18,
+	models.ConditionTornado:      19,
+}
+
+// Severity возвращает ранг опасности явления (0 - неизвестное)
+func Severity(code string) int {
+	return severity[code]
+}
+
+// descriptions описания явлений по языкам
+var descriptions = map[string]map[string]string{
+	"ru": {
+		models.ConditionClear:        "ясно",
+		models.ConditionPartlyCloudy: "переменная облачность",
+		models.ConditionCloudy:       "облачно",
+		models.ConditionOvercast:     "пасмурно",
+		models.ConditionHaze:         "дымка",
+		models.ConditionFog:          "туман",
+		models.ConditionDrizzle:      "морось",
+		models.ConditionRainLight:    "небольшой дождь",
+		models.ConditionRain:         "дождь",
+		models.ConditionRainHeavy:    "сильный дождь",
+		models.ConditionFreezingRain: "ледяной дождь",
+		models.ConditionSleet:        "дождь со снегом",
+		models.ConditionSnowLight:    "небольшой снег",
+		models.ConditionSnow:         "снег",
+		models.ConditionSnowHeavy:    "сильный снег",
+		models.ConditionHail:         "град",
+		models.ConditionThunderstorm: "гроза",
+		models.ConditionSquall:       "шквал",
+		models.ConditionTornado:      "смерч",
+	},
+	"en": {
+		models.ConditionClear:        "clear",
+		models.ConditionPartlyCloudy: "partly cloudy",
+		models.ConditionCloudy:       "cloudy",
+		models.ConditionOvercast:     "overcast",
+		models.ConditionHaze:         "haze",
+		models.ConditionFog:          "fog",
+		models.ConditionDrizzle:      "drizzle",
+		models.ConditionRainLight:    "light rain",
+		models.ConditionRain:         "rain",
+		models.ConditionRainHeavy:    "heavy rain",
+		models.ConditionFreezingRain: "freezing rain",
+		models.ConditionSleet:        "sleet",
+		models.ConditionSnowLight:    "light snow",
+		models.ConditionSnow:         "snow",
+		models.ConditionSnowHeavy:    "heavy snow",
+		models.ConditionHail:         "hail",
+		models.ConditionThunderstorm: "thunderstorm",
+		models.ConditionSquall:       "squalls",
+		models.ConditionTornado:      "tornado",
+	},
+}
+
+// Describe возвращает описание явления на языке lang ("ru", "en"; по
+// умолчанию русский). Для неизвестного кода возвращается пустая строка.
+func Describe(code, lang string) string {
+	texts, ok := descriptions[strings.ToLower(lang)]
+	if !ok {
+		texts = descriptions["ru"]
+	}
+	return texts[code]
+}
+
+// Consensus выбирает явление, которое сообщило больше источников; при
+// равенстве - более опасное. Пустые коды не учитываются.
+func Consensus(codes []string) string {
+	votes := make(map[string]int)
+	for _, code := range codes {
+		if code != "" {
+			votes[code]++
+		}
+	}
+
+	best := ""
+	for code, n := range votes {
+		if best == "" || n > votes[best] ||
+			(n == votes[best] && (severity[code] > severity[best] ||
+				(severity[code] == severity[best] && code < best))) {
+			best = code
+		}
+	}
+	return best
+}
+
+// FromOpenWeather переводит идентификатор weather[].id OpenWeatherMap
+func FromOpenWeather(id int) string {
+	switch {
+	case id >= 200 && id < 300:
+		return models.ConditionThunderstorm
+	case id >= 300 && id < 310:
+		return models.ConditionDrizzle
+	case id >= 310 && id < 400:
+		// Морось с дождем
+		return models.ConditionRainLight
+	case id == 511:
+		return models.ConditionFreezingRain
+	case id == 500 || id == 520:
+		return models.ConditionRainLight
+	case id == 501 || id == 521 || id == 531:
+		return models.ConditionRain
+	case id >= 502 && id <= 504, id == 522:
+		return models.ConditionRainHeavy
+	case id == 600 || id == 620:
+		return models.ConditionSnowLight
+	case id == 601 || id == 621:
+		return models.ConditionSnow
+	case id == 602 || id == 622:
+		return models.ConditionSnowHeavy
+	case id >= 611 && id <= 616:
+		return models.ConditionSleet
+	case id == 741:
+		return models.ConditionFog
+	case id == 771:
+		return models.ConditionSquall
+	case id == 781:
+		return models.ConditionTornado
+	case id >= 700 && id < 800:
+		// Дымка, дым, мгла, песок, пыль, пепел
+		return models.ConditionHaze
+	case id == 800:
+		return models.ConditionClear
+	case id == 801 || id == 802:
+		return models.ConditionPartlyCloudy
+	case id == 803:
+		return models.ConditionCloudy
+	case id == 804:
+		return models.ConditionOvercast
+	}
+	return ""
+}
+
+// weatherAPICodes коды condition.code WeatherAPI
+var weatherAPICodes = map[int]string{
+	1000: models.ConditionClear,
+	1003: models.ConditionPartlyCloudy,
+	1006: models.ConditionCloudy,
+	1009: models.ConditionOvercast,
+	1030: models.ConditionHaze,
+	1063: models.ConditionRainLight,
+	1066: models.ConditionSnowLight,
+	1069: models.ConditionSleet,
+	1072: models.ConditionFreezingRain,
+	1087: models.ConditionThunderstorm,
+	1114: models.ConditionSnow,
+	1117: models.ConditionSnowHeavy,
+	1135: models.ConditionFog,
+	1147: models.ConditionFog,
+	1150: models.ConditionDrizzle,
+	1153: models.ConditionDrizzle,
+	1168: models.ConditionFreezingRain,
+	1171: models.ConditionFreezingRain,
+	1180: models.ConditionRainLight,
+	1183: models.ConditionRainLight,
+	1186: models.ConditionRain,
+	1189: models.ConditionRain,
+	1192: models.ConditionRainHeavy,
+	1195: models.ConditionRainHeavy,
+	1198: models.ConditionFreezingRain,
+	1201: models.ConditionFreezingRain,
+	1204: models.ConditionSleet,
+	1207: models.ConditionSleet,
+	1210: models.ConditionSnowLight,
+	1213: models.ConditionSnowLight,
+	1216: models.ConditionSnow,
+	1219: models.ConditionSnow,
+	1222: models.ConditionSnowHeavy,
+	1225: models.ConditionSnowHeavy,
+	1237: models.ConditionSleet,
+	1240: models.ConditionRainLight,
+	1243: models.ConditionRain,
+	1246: models.ConditionRainHeavy,
+	1249: models.ConditionSleet,
+	1252: models.ConditionSleet,
+	1255: models.ConditionSnowLight,
+	1258: models.ConditionSnow,
+	1261: models.ConditionSleet,
+	1264: models.ConditionSleet,
+	1273: models.ConditionThunderstorm,
+	1276: models.ConditionThunderstorm,
+	1279: models.ConditionThunderstorm,
+	1282: models.ConditionThunderstorm,
+}
+
+// FromWeatherAPI переводит condition.code WeatherAPI
+func FromWeatherAPI(code int) string {
+	return weatherAPICodes[code]
+}
+
+// metNoSymbols базовые symbol_code met.no без ливневых вариантов
+var metNoSymbols = map[string]string{
+	"clearsky":     models.ConditionClear,
+	"fair":         models.ConditionPartlyCloudy,
+	"partlycloudy": models.ConditionPartlyCloudy,
+	"cloudy":       models.ConditionOvercast,
+	"fog":          models.ConditionFog,
+	"lightrain":    models.ConditionRainLight,
+	"rain":         models.ConditionRain,
+	"heavyrain":    models.ConditionRainHeavy,
+	"lightsleet":   models.ConditionSleet,
+	"sleet":        models.ConditionSleet,
+	"heavysleet":   models.ConditionSleet,
+	"lightsnow":    models.ConditionSnowLight,
+	"snow":         models.ConditionSnow,
+	"heavysnow":    models.ConditionSnowHeavy,
+}
+
+// FromMetNo переводит symbol_code met.no, например "lightrainshowers_day"
+func FromMetNo(symbol string) string {
+	base, _, _ := strings.Cut(symbol, "_")
+	if strings.Contains(base, "thunder") {
+		return models.ConditionThunderstorm
+	}
+	return metNoSymbols[strings.TrimSuffix(base, "showers")]
+}
+
+// nwsIcons коды иконок api.weather.gov
+var nwsIcons = map[string]string{
+	"skc":             models.ConditionClear,
+	"few":             models.ConditionPartlyCloudy,
+	"sct":             models.ConditionPartlyCloudy,
+	"bkn":             models.ConditionCloudy,
+	"ovc":             models.ConditionOvercast,
+	"snow":            models.ConditionSnow,
+	"blizzard":        models.ConditionSnowHeavy,
+	"rain_snow":       models.ConditionSleet,
+	"rain_sleet":      models.ConditionSleet,
+	"snow_sleet":      models.ConditionSleet,
+	"sleet":           models.ConditionSleet,
+	"fzra":            models.ConditionFreezingRain,
+	"rain_fzra":       models.ConditionFreezingRain,
+	"snow_fzra":       models.ConditionFreezingRain,
+	"rain":            models.ConditionRain,
+	"rain_showers":    models.ConditionRain,
+	"rain_showers_hi": models.ConditionRainLight,
+	"tsra":            models.ConditionThunderstorm,
+	"tsra_sct":        models.ConditionThunderstorm,
+	"tsra_hi":         models.ConditionThunderstorm,
+	"tornado":         models.ConditionTornado,
+	"hurricane":       models.ConditionSquall,
+	"tropical_storm":  models.ConditionSquall,
+	"dust":            models.ConditionHaze,
+	"smoke":           models.ConditionHaze,
+	"haze":            models.ConditionHaze,
+	"fog":             models.ConditionFog,
+}
+
+// FromNWSIcon переводит адрес иконки наблюдения NWS, например
+// ".../icons/land/day/tsra,40/ovc?size=medium": берется первое явление
+func FromNWSIcon(icon string) string {
+	path, _, _ := strings.Cut(icon, "?")
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if (part != "day" && part != "night") || i+1 >= len(parts) {
+			continue
+		}
+		name, _, _ := strings.Cut(parts[i+1], ",")
+		// Ветреная погода: wind_skc, wind_ovc и т.п.
+		name = strings.TrimPrefix(name, "wind_")
+		return nwsIcons[name]
+	}
+	return ""
+}
+
+// metarCover облачность по группам METAR
+var metarCover = map[string]string{
+	"SKC": models.ConditionClear, "CLR": models.ConditionClear,
+	"NSC": models.ConditionClear, "NCD": models.ConditionClear,
+	"FEW": models.ConditionPartlyCloudy, "SCT": models.ConditionPartlyCloudy,
+	"BKN": models.ConditionCloudy,
+	"OVC": models.ConditionOvercast, "VV": models.ConditionOvercast,
+}
+
+// FromMETAR определяет явление по сводке: самое опасное из явлений погоды,
+// а без них - по самому плотному слою облачности
+func FromMETAR(report *metar.Report) string {
+	best := ""
+	for _, p := range report.Weather {
+		// Явления в окрестностях (VC) не относятся к самой станции
+		if p.Intensity == "VC" {
+			continue
+		}
+		if code := fromPhenomenon(p); severity[code] > severity[best] {
+			best = code
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	if report.CAVOK {
+		return models.ConditionClear
+	}
+	for _, layer := range report.Clouds {
+		if code := metarCover[layer.Cover]; severity[code] > severity[best] {
+			best = code
+		}
+	}
+	return best
+}
+
+// fromPhenomenon переводит одну группу явлений METAR
+func fromPhenomenon(p metar.Phenomenon) string {
+	has := func(code string) bool {
+		for _, precipitation := range p.Precipitation {
+			if precipitation == code {
+				return true
+			}
+		}
+		return false
+	}
+	intensity := func(light, moderate, heavy string) string {
+		switch p.Intensity {
+		case "-":
+			return light
+		case "+":
+			return heavy
+		}
+		return moderate
+	}
+
+	switch {
+	case p.Other == "FC":
+		return models.ConditionTornado
+	case p.Descriptor == "TS":
+		return models.ConditionThunderstorm
+	case has("GR") || has("GS"):
+		return models.ConditionHail
+	case p.Descriptor == "FZ" && (has("RA") || has("DZ")):
+		return models.ConditionFreezingRain
+	case has("PL") || (has("RA") && has("SN")):
+		return models.ConditionSleet
+	case has("SN") || has("SG"):
+		return intensity(models.ConditionSnowLight, models.ConditionSnow, models.ConditionSnowHeavy)
+	case has("RA"):
+		return intensity(models.ConditionRainLight, models.ConditionRain, models.ConditionRainHeavy)
+	case has("DZ"):
+		return models.ConditionDrizzle
+	case p.Other == "SQ":
+		return models.ConditionSquall
+	case p.Obscuration == "FG":
+		return models.ConditionFog
+	case p.Obscuration != "" || p.Other == "SS" || p.Other == "DS":
+		return models.ConditionHaze
+	}
+	return ""
+}
@@ -4,16 +4,69 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	OpenWeatherAPIKey string
-	WeatherAPIKey     string
-	ServerPort        string
-	CacheDuration     int // минуты
-	LogLevel          string
+	OpenWeatherAPIKey  string
+	WeatherAPIKey      string
+	OpenWeatherURL     string   // базовый адрес API OpenWeatherMap (пусто - боевой)
+	WeatherAPIURL      string   // базовый адрес WeatherAPI (пусто - боевой)
+	MetNoUserAgent     string   // User-Agent для api.met.no (пусто - провайдер отключен)
+	MetNoURL           string   // базовый адрес api.met.no (пусто - боевой)
+	NWSUserAgent       string   // User-Agent для api.weather.gov (пусто - провайдер отключен)
+	NWSURL             string   // базовый адрес api.weather.gov (пусто - боевой)
+	NWSGridCacheHours  int      // срок кеширования сопоставления точки с сеткой NWS
+	METAREnabled       bool     // включить провайдер METAR
+	METARSourceURL     string   // шаблон адреса сводок с {icao}
+	METARMaxDistance   float64  // максимальное расстояние от города до станции, км
+	GeocoderURL        string   // адрес геокодера Open-Meteo ("off" - только встроенная таблица)
+	PWSConfig          string   // путь к JSON со списком собственных метеостанций
+	MQTTConfig         string   // путь к JSON с брокером и топиками MQTT датчиков
+	AlertsEnabled      bool     // собирать предупреждения об опасной погоде
+	OpenWeatherOneCall bool     // запрашивать предупреждения One Call API 3.0 (нужна подписка)
+	CAPFeedURLs        []string // адреса лент или документов CAP 1.2
+	CAPLanguage        string   // предпочтительный язык текстов CAP
+	IconBaseURL        string   // адрес сервера для icon_url; пустой - относительный адрес
+	HistoryDir         string   // каталог истории погоды; пустой - история не ведется
+	HistoryRetention   int      // срок хранения истории в днях; 0 - бессрочно
+	HistoryDownsample  int      // через сколько часов прореживать историю; 0 - не прореживать
+	HistoryStep        int      // интервал прореженных точек в минутах
+	WatchLocations     []string // города для фонового обновления в режиме сервера ("Город:СТРАНА")
+	WatchInterval      int      // интервал фонового обновления в минутах
+	ForecastInterval   int      // как часто сохранять прогнозы провайдеров для города, минуты; 0 - не сохранять
+	ForecastMaxLead    int      // на сколько часов вперед сохранять прогнозы
+	ScoresWindow       int      // за сколько суток оценивать точность прогнозов
+	Strategy           string   // стратегия агрегации: mean или weighted
+	BiasReference      string   // эталонный источник для поправки провайдеров; пустой - без поправок
+	BiasWindow         int      // за сколько суток рассчитывать поправки
+	BiasMinSamples     int      // сколько сопоставлений с эталоном нужно для поправки
+	ClimateNormals     string   // путь к CSV с климатическими нормами
+	ClimateFromHistory bool     // рассчитывать нормы по истории, если загруженных нет
+	ClimateMinDays     int      // из скольких суток истории должна состоять норма
+	ClimateMinYears    int      // за сколько прошлых лет истории должна состоять норма
+	ServerPort         string
+	CacheDuration      int // минуты
+	LogLevel           string
+	AdminToken         string // токен для /admin/* эндпоинтов (пусто - эндпоинты отключены)
+	ChaosEnabled       bool   // оборачивать провайдеры декоратором сбоев
+	ChaosConfig        string // путь к JSON конфигурации сбоев
+
+	// Middlewares цепочка middleware для всех провайдеров (PROVIDER_MIDDLEWARES),
+	// ProviderMiddlewares - для конкретного провайдера (<ПРОВАЙДЕР>_MIDDLEWARES),
+	// ключ - имя провайдера в нижнем регистре, например "openweather"
+	Middlewares         []string
+	ProviderMiddlewares map[string][]string
+}
+
+// MiddlewaresFor возвращает цепочку middleware для провайдера
+func (c *Config) MiddlewaresFor(provider string) []string {
+	if mws, ok := c.ProviderMiddlewares[strings.ToLower(provider)]; ok {
+		return mws
+	}
+	return c.Middlewares
 }
 
 func Load() (*Config, error) {
@@ -21,21 +74,93 @@ func Load() (*Config, error) {
 	godotenv.Load()
 
 	config := &Config{
-		OpenWeatherAPIKey: getEnv("OPENWEATHER_API_KEY", ""),
-		WeatherAPIKey:     getEnv("WEATHERAPI_API_KEY", ""),
-		ServerPort:        getEnv("SERVER_PORT", "8080"),
-		CacheDuration:     getEnvAsInt("CACHE_DURATION", 10),
-		LogLevel:          getEnv("LOG_LEVEL", "info"),
+		OpenWeatherAPIKey:  getEnv("OPENWEATHER_API_KEY", ""),
+		WeatherAPIKey:      getEnv("WEATHERAPI_API_KEY", ""),
+		OpenWeatherURL:     getEnv("OPENWEATHER_BASE_URL", ""),
+		WeatherAPIURL:      getEnv("WEATHERAPI_BASE_URL", ""),
+		MetNoUserAgent:     getEnv("METNO_USER_AGENT", ""),
+		MetNoURL:           getEnv("METNO_BASE_URL", ""),
+		NWSUserAgent:       getEnv("NWS_USER_AGENT", ""),
+		NWSURL:             getEnv("NWS_BASE_URL", ""),
+		NWSGridCacheHours:  getEnvAsInt("NWS_GRID_CACHE_HOURS", 168),
+		METAREnabled:       getEnvAsBool("METAR_ENABLED", false),
+		METARSourceURL:     getEnv("METAR_SOURCE_URL", ""),
+		METARMaxDistance:   float64(getEnvAsInt("METAR_MAX_DISTANCE_KM", 50)),
+		GeocoderURL:        getEnv("GEOCODER_URL", ""),
+		PWSConfig:          getEnv("PWS_CONFIG", ""),
+		MQTTConfig:         getEnv("MQTT_CONFIG", ""),
+		AlertsEnabled:      getEnvAsBool("ALERTS_ENABLED", true),
+		OpenWeatherOneCall: getEnvAsBool("OPENWEATHER_ONECALL", false),
+		CAPFeedURLs:        getEnvAsList("CAP_FEED_URLS"),
+		CAPLanguage:        getEnv("CAP_LANGUAGE", "ru"),
+		IconBaseURL:        getEnv("ICON_BASE_URL", ""),
+		HistoryDir:         getEnv("HISTORY_DIR", ""),
+		HistoryRetention:   getEnvAsInt("HISTORY_RETENTION_DAYS", 90),
+		HistoryDownsample:  getEnvAsInt("HISTORY_DOWNSAMPLE_AFTER_HOURS", 7*24),
+		HistoryStep:        getEnvAsInt("HISTORY_DOWNSAMPLE_MINUTES", 60),
+		WatchLocations:     getEnvAsList("WATCH_LOCATIONS"),
+		WatchInterval:      getEnvAsInt("WATCH_INTERVAL_MINUTES", 15),
+		ForecastInterval:   getEnvAsInt("FORECAST_INTERVAL_MINUTES", 0),
+		ForecastMaxLead:    getEnvAsInt("FORECAST_MAX_LEAD_HOURS", 72),
+		ScoresWindow:       getEnvAsInt("SCORES_WINDOW_DAYS", 30),
+		Strategy:           getEnv("AGGREGATION_STRATEGY", "mean"),
+		BiasReference:      getEnv("BIAS_REFERENCE", ""),
+		BiasWindow:         getEnvAsInt("BIAS_WINDOW_DAYS", 14),
+		BiasMinSamples:     getEnvAsInt("BIAS_MIN_SAMPLES", 24),
+		ClimateNormals:     getEnv("CLIMATE_NORMALS", ""),
+		ClimateFromHistory: getEnvAsBool("CLIMATE_FROM_HISTORY", false),
+		ClimateMinDays:     getEnvAsInt("CLIMATE_MIN_DAYS", 10),
+		ClimateMinYears:    getEnvAsInt("CLIMATE_MIN_YEARS", 1),
+		ServerPort:         getEnv("SERVER_PORT", "8080"),
+		CacheDuration:      getEnvAsInt("CACHE_DURATION", 10),
+		LogLevel:           getEnv("LOG_LEVEL", "info"),
+		AdminToken:         getEnv("ADMIN_TOKEN", ""),
+		ChaosEnabled:       getEnvAsBool("CHAOS_ENABLED", false),
+		ChaosConfig:        getEnv("CHAOS_CONFIG", ""),
+		Middlewares:        getEnvAsList("PROVIDER_MIDDLEWARES"),
 	}
 
-	// Проверяем наличие хотя бы одного API ключа
-	if config.OpenWeatherAPIKey == "" && config.WeatherAPIKey == "" {
-		return nil, fmt.Errorf("необходим хотя бы один API ключ (OpenWeather или WeatherAPI)")
+	config.ProviderMiddlewares = make(map[string][]string)
+	for _, env := range os.Environ() {
+		key, _, _ := strings.Cut(env, "=")
+		if key == "PROVIDER_MIDDLEWARES" || !strings.HasSuffix(key, "_MIDDLEWARES") {
+			continue
+		}
+		provider := strings.ToLower(strings.TrimSuffix(key, "_MIDDLEWARES"))
+		config.ProviderMiddlewares[provider] = getEnvAsList(key)
+	}
+
+	// Проверяем наличие хотя бы одного источника данных
+	if config.OpenWeatherAPIKey == "" && config.WeatherAPIKey == "" &&
+		config.MetNoUserAgent == "" && config.NWSUserAgent == "" && !config.METAREnabled &&
+		config.PWSConfig == "" && config.MQTTConfig == "" {
+		return nil, fmt.Errorf("необходим хотя бы один API ключ (OpenWeather или WeatherAPI), METNO_USER_AGENT, NWS_USER_AGENT, METAR_ENABLED, PWS_CONFIG или MQTT_CONFIG")
 	}
 
 	return config, nil
 }
 
+// ClientConfig настройки команд CLI, которым не нужны провайдеры: обращение
+// к запущенному серверу, астрономический расчет и чтение истории
+type ClientConfig struct {
+	ServerURL   string
+	AdminToken  string
+	GeocoderURL string
+	HistoryDir  string
+}
+
+// LoadClient загружает настройки клиента; ключи API для этого не нужны
+func LoadClient() *ClientConfig {
+	godotenv.Load()
+
+	return &ClientConfig{
+		ServerURL:   getEnv("ADMIN_SERVER_URL", "http://localhost:"+getEnv("SERVER_PORT", "8080")),
+		AdminToken:  getEnv("ADMIN_TOKEN", ""),
+		GeocoderURL: getEnv("GEOCODER_URL", ""),
+		HistoryDir:  getEnv("HISTORY_DIR", ""),
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -55,3 +180,27 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return intValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return boolValue
+}
+
+// getEnvAsList читает список значений через запятую
+func getEnvAsList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
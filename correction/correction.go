@@ -0,0 +1,238 @@
+// Package correction поправляет систематические ошибки провайдеров. По
+// истории наблюдений для каждой локации, провайдера и величины считается
+// среднее расхождение провайдера с эталонным источником (например, своей
+// метеостанцией) в одни и те же моменты; перед агрегацией оно вычитается из
+// ответа провайдера.
+package correction
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"weather-aggregator/history"
+	"weather-aggregator/models"
+)
+
+// fields поправляемые величины
+var fields = []struct {
+	name     string
+	get      func(d *models.WeatherData) **float64
+	min, max float64
+}{
+	{"temperature", func(d *models.WeatherData) **float64 { return &d.Temperature }, math.Inf(-1), math.Inf(1)},
+	{"humidity", func(d *models.WeatherData) **float64 { return &d.Humidity }, 0, 100},
+	{"pressure", func(d *models.WeatherData) **float64 { return &d.Pressure }, 0, math.Inf(1)},
+	{"wind_speed", func(d *models.WeatherData) **float64 { return &d.WindSpeed }, 0, math.Inf(1)},
+}
+
+// Options параметры расчета поправок
+type Options struct {
+	// Window за сколько последних суток сравнивать наблюдения (по умолчанию 14)
+	Window time.Duration
+	// Tolerance максимальное расхождение времени наблюдений провайдера и эталона (по умолчанию 30 минут)
+	Tolerance time.Duration
+	// MinSamples сколько сопоставлений нужно, чтобы поправка применялась (по умолчанию 24)
+	MinSamples int
+	// CacheTTL сколько хранить рассчитанные поправки (по умолчанию час)
+	CacheTTL time.Duration
+}
+
+// Offset среднее расхождение провайдера с эталоном
+type Offset struct {
+	Value   float64
+	Samples int
+}
+
+// Corrector рассчитывает по истории и кеширует поправки провайдеров
+type Corrector struct {
+	store     *history.Store
+	reference string
+	opts      Options
+
+	mu    sync.Mutex
+	cache map[string]offsetsEntry
+}
+
+type offsetsEntry struct {
+	offsets    map[string]map[string]Offset
+	calculated time.Time
+}
+
+// NewCorrector создает расчет поправок относительно эталонного источника
+// reference - имени провайдера или станции, как оно записано в истории
+func NewCorrector(store *history.Store, reference string, opts Options) *Corrector {
+	if opts.Window <= 0 {
+		opts.Window = 14 * 24 * time.Hour
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 30 * time.Minute
+	}
+	if opts.MinSamples <= 0 {
+		opts.MinSamples = 24
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = time.Hour
+	}
+	return &Corrector{store: store, reference: reference, opts: opts, cache: make(map[string]offsetsEntry)}
+}
+
+// Offsets возвращает поправки для города за окно до now: провайдер -> величина
+// -> расхождение. Поправки по меньше чем MinSamples сопоставлениям не
+// возвращаются. Результат кешируется на CacheTTL.
+func (c *Corrector) Offsets(city, country string, now time.Time) (map[string]map[string]Offset, error) {
+	key := fmt.Sprintf("%s,%s", city, country)
+
+	c.mu.Lock()
+	cached, found := c.cache[key]
+	c.mu.Unlock()
+	if found && now.Sub(cached.calculated) < c.opts.CacheTTL {
+		return cached.offsets, nil
+	}
+
+	points, err := c.store.Query(history.Query{
+		City:    city,
+		Country: country,
+		From:    now.Add(-c.opts.Window),
+		To:      now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := Learn(points, c.reference, c.opts.Tolerance)
+	for provider, byField := range offsets {
+		for field, offset := range byField {
+			if offset.Samples < c.opts.MinSamples {
+				delete(byField, field)
+			}
+		}
+		if len(byField) == 0 {
+			delete(offsets, provider)
+		}
+	}
+
+	c.mu.Lock()
+	c.cache[key] = offsetsEntry{offsets: offsets, calculated: now}
+	c.mu.Unlock()
+
+	return offsets, nil
+}
+
+// Correct вычитает поправки из ответов провайдеров. Исправленные ответы -
+// копии, исходные не изменяются. Эталон не поправляется. Реализует
+// aggregator.Corrector.
+func (c *Corrector) Correct(city, country string, data []*models.WeatherData) ([]*models.WeatherData, []models.BiasCorrection) {
+	offsets, err := c.Offsets(city, country, time.Now())
+	if err != nil || len(offsets) == 0 {
+		return data, nil
+	}
+
+	corrected := make([]*models.WeatherData, len(data))
+	var corrections []models.BiasCorrection
+	for i, d := range data {
+		corrected[i] = d
+		byField, ok := offsets[d.Provider]
+		if !ok || d.Provider == c.reference {
+			continue
+		}
+
+		copied := *d
+		for _, f := range fields {
+			offset, ok := byField[f.name]
+			value := *f.get(&copied)
+			if !ok || offset.Value == 0 || value == nil {
+				continue
+			}
+			v := round(math.Min(math.Max(*value-offset.Value, f.min), f.max))
+			*f.get(&copied) = &v
+			corrections = append(corrections, models.BiasCorrection{
+				Provider:  d.Provider,
+				Field:     f.name,
+				Raw:       *value,
+				Corrected: v,
+				Offset:    offset.Value,
+				Samples:   offset.Samples,
+				Reference: c.reference,
+			})
+		}
+		corrected[i] = &copied
+	}
+	return corrected, corrections
+}
+
+// Learn сопоставляет наблюдения провайдеров с ближайшими по времени (не
+// дальше tolerance) наблюдениями эталона и возвращает среднее расхождение
+// провайдер минус эталон по каждому провайдеру и величине. Повторы одного
+// наблюдения (та же точка источника на то же время) учитываются один раз.
+func Learn(points []history.Point, reference string, tolerance time.Duration) map[string]map[string]Offset {
+	type pointKey struct {
+		source string
+		time   time.Time
+	}
+	seen := make(map[pointKey]bool, len(points))
+	unique := make([]history.Point, 0, len(points))
+	for _, p := range points {
+		key := pointKey{p.Source, p.Time.UTC()}
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, p)
+		}
+	}
+	points = unique
+
+	var refs []history.Point
+	for _, p := range points {
+		if p.Source == reference {
+			refs = append(refs, p)
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].Time.Before(refs[j].Time) })
+
+	type sum struct {
+		total float64
+		count int
+	}
+	sums := make(map[string]map[string]*sum)
+	for _, p := range points {
+		if p.Source == reference || p.Source == history.SourceAggregated {
+			continue
+		}
+		ref, ok := history.Nearest(refs, p.Time, tolerance)
+		if !ok {
+			continue
+		}
+		for _, f := range fields {
+			value, ok1 := p.Values[f.name]
+			actual, ok2 := ref.Values[f.name]
+			if !ok1 || !ok2 {
+				continue
+			}
+			if sums[p.Source] == nil {
+				sums[p.Source] = make(map[string]*sum)
+			}
+			s, ok := sums[p.Source][f.name]
+			if !ok {
+				s = &sum{}
+				sums[p.Source][f.name] = s
+			}
+			s.total += value - actual
+			s.count++
+		}
+	}
+
+	offsets := make(map[string]map[string]Offset, len(sums))
+	for provider, byField := range sums {
+		offsets[provider] = make(map[string]Offset, len(byField))
+		for field, s := range byField {
+			offsets[provider][field] = Offset{Value: round(s.total / float64(s.count)), Samples: s.count}
+		}
+	}
+	return offsets
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,149 @@
+package correction
+
+import (
+	"testing"
+	"time"
+
+	"weather-aggregator/history"
+	"weather-aggregator/models"
+)
+
+var base = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+func point(source string, at time.Duration, values map[string]float64) history.Point {
+	return history.Point{Time: base.Add(at), Source: source, Values: values}
+}
+
+func TestLearn(t *testing.T) {
+	points := []history.Point{
+		point("Station", 0, map[string]float64{"temperature": 10, "humidity": 60}),
+		point("Station", time.Hour, map[string]float64{"temperature": 12}),
+		// Ближайшее наблюдение эталона через 10 минут
+		point("A", 10*time.Minute, map[string]float64{"temperature": 11, "humidity": 70}),
+		point("A", 50*time.Minute, map[string]float64{"temperature": 15}),
+		// Эталон дальше допуска
+		point("A", 3*time.Hour, map[string]float64{"temperature": 40}),
+		// Агрегированный результат не провайдер
+		point(history.SourceAggregated, 0, map[string]float64{"temperature": 30}),
+		// Величины без пары у эталона и вне списка не учитываются
+		point("B", time.Hour, map[string]float64{"humidity": 50, "visibility": 3}),
+	}
+
+	offsets := Learn(points, "Station", 30*time.Minute)
+	want := map[string]map[string]Offset{
+		"A": {
+			"temperature": {Value: 2, Samples: 2},
+			"humidity":    {Value: 10, Samples: 1},
+		},
+	}
+	if len(offsets) != len(want) {
+		t.Fatalf("поправки %v, ожидалось %v", offsets, want)
+	}
+	for provider, byField := range want {
+		if len(offsets[provider]) != len(byField) {
+			t.Errorf("%s: поправки %v, ожидалось %v", provider, offsets[provider], byField)
+		}
+		for field, offset := range byField {
+			if got := offsets[provider][field]; got != offset {
+				t.Errorf("%s/%s: %+v, ожидалось %+v", provider, field, got, offset)
+			}
+		}
+	}
+	if _, ok := offsets["Station"]; ok {
+		t.Error("эталон получил поправку относительно себя")
+	}
+}
+
+func TestLearnSkipsRepeatedPoints(t *testing.T) {
+	// METAR и станции повторяют одно наблюдение до прихода нового
+	var points []history.Point
+	for i := 0; i < 10; i++ {
+		points = append(points,
+			point("Station", 0, map[string]float64{"temperature": 10}),
+			point("METAR", 5*time.Minute, map[string]float64{"temperature": 13}),
+		)
+	}
+	points = append(points, point("METAR", 5*time.Minute+time.Second, map[string]float64{"temperature": 11}))
+
+	offsets := Learn(points, "Station", 30*time.Minute)
+	if got := offsets["METAR"]["temperature"]; got.Samples != 2 || got.Value != 2 {
+		t.Errorf("поправка %+v, ожидалось 2 по двум разным наблюдениям", got)
+	}
+}
+
+// newCorrector хранилище с hours часами наблюдений эталона и провайдера A,
+// который завышает температуру на 2 и занижает влажность на 5
+func newCorrector(t *testing.T, hours int, opts Options) *Corrector {
+	t.Helper()
+	store, err := history.Open(t.TempDir(), history.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Truncate(time.Minute)
+	for h := 1; h <= hours; h++ {
+		at := now.Add(-time.Duration(h) * time.Hour)
+		err := store.Append("Москва", "RU",
+			history.Point{Time: at, Source: "Station", Values: map[string]float64{"temperature": 10, "humidity": 90}},
+			history.Point{Time: at, Source: "A", Values: map[string]float64{"temperature": 12, "humidity": 85}},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewCorrector(store, "Station", opts)
+}
+
+func TestOffsetsMinSamples(t *testing.T) {
+	offsets, err := newCorrector(t, 5, Options{MinSamples: 6}).Offsets("Москва", "RU", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offsets) != 0 {
+		t.Errorf("поправки по 5 сопоставлениям при MinSamples 6: %v", offsets)
+	}
+
+	offsets, err = newCorrector(t, 6, Options{MinSamples: 6}).Offsets("Москва", "RU", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := offsets["A"]["temperature"]; got.Value != 2 || got.Samples != 6 {
+		t.Errorf("поправка %+v, ожидалось 2 по 6 сопоставлениям", got)
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	corrector := newCorrector(t, 6, Options{MinSamples: 6})
+	a := &models.WeatherData{Provider: "A", Temperature: models.Float(20), Humidity: models.Float(97), Pressure: models.Float(1010)}
+	station := &models.WeatherData{Provider: "Station", Temperature: models.Float(18), Humidity: models.Float(99)}
+	other := &models.WeatherData{Provider: "B", Temperature: models.Float(19)}
+	data := []*models.WeatherData{a, station, other}
+
+	corrected, corrections := corrector.Correct("Москва", "RU", data)
+
+	if *a.Temperature != 20 || *a.Humidity != 97 {
+		t.Error("исходный ответ провайдера изменен")
+	}
+	if corrected[0] == a {
+		t.Fatal("исправленный ответ не копия")
+	}
+	if corrected[1] != station || corrected[2] != other {
+		t.Error("эталон и провайдер без поправок должны остаться как есть")
+	}
+	// Влажность 97 + 5 ограничивается сотней
+	if *corrected[0].Temperature != 18 || *corrected[0].Humidity != 100 || *corrected[0].Pressure != 1010 {
+		t.Errorf("исправленный ответ: T %v, RH %v, P %v", *corrected[0].Temperature, *corrected[0].Humidity, *corrected[0].Pressure)
+	}
+
+	want := map[string]models.BiasCorrection{
+		"temperature": {Provider: "A", Field: "temperature", Raw: 20, Corrected: 18, Offset: 2, Samples: 6, Reference: "Station"},
+		"humidity":    {Provider: "A", Field: "humidity", Raw: 97, Corrected: 100, Offset: -5, Samples: 6, Reference: "Station"},
+	}
+	if len(corrections) != len(want) {
+		t.Fatalf("поправки %+v, ожидалось %+v", corrections, want)
+	}
+	for _, c := range corrections {
+		if c != want[c.Field] {
+			t.Errorf("поправка %+v, ожидалось %+v", c, want[c.Field])
+		}
+	}
+}
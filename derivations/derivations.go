@@ -0,0 +1,164 @@
+// Package derivations рассчитывает производные метеорологические величины по
+// температуре (°C), относительной влажности (%) и скорости ветра (м/с):
+// точку росы, индекс жары, ветро-холодовой индекс, ощущаемую температуру
+// Стедмана, humidex, температуру смоченного термометра и абсолютную влажность.
+//
+// Провайдеры считают "ощущается как" по разным формулам, поэтому агрегатор
+// рассчитывает эти величины сам по усредненным измерениям и указывает формулу.
+package derivations
+
+import (
+	"math"
+
+	"weather-aggregator/models"
+)
+
+// Описания формул, которые возвращаются вместе со значениями
+const (
+	FormulaDewPoint            = "Магнус (Alduchov, Eskridge 1996)"
+	FormulaHeatIndex           = "Rothfusz с поправками NWS; при T ≥ 27°C"
+	FormulaWindChill           = "JAG/TI (Environment Canada, NWS 2001); при T ≤ 10°C и ветре > 4.8 км/ч"
+	FormulaApparentTemperature = "Стедман в редакции Австралийского бюро метеорологии, без учета солнечной радиации"
+	FormulaHumidex             = "Humidex (Environment Canada) по точке росы; при T ≥ 20°C"
+	FormulaWetBulb             = "Stull 2011; при влажности 5-99% и T от -20 до 50°C"
+	FormulaAbsoluteHumidity    = "по давлению насыщенного пара (Bolton 1980)"
+)
+
+// Compute рассчитывает производные величины по доступным измерениям.
+// Величина, для которой не хватает измерений или которая вне области
+// применимости своей формулы, не заполняется. Без температуры возвращается nil.
+func Compute(temp, humidity, windSpeed *float64) *models.Derived {
+	if temp == nil {
+		return nil
+	}
+	t := *temp
+	derived := &models.Derived{}
+
+	if windSpeed != nil {
+		if v, ok := WindChill(t, *windSpeed); ok {
+			derived.WindChill = value(v, "°C", FormulaWindChill)
+		}
+	}
+
+	if humidity == nil {
+		return derived
+	}
+	rh := *humidity
+
+	if v, ok := DewPoint(t, rh); ok {
+		derived.DewPoint = value(v, "°C", FormulaDewPoint)
+	}
+	if v, ok := HeatIndex(t, rh); ok {
+		derived.HeatIndex = value(v, "°C", FormulaHeatIndex)
+	}
+	// Отсутствующий ветер считается штилем, как и при расчете FeelsLike провайдерами
+	wind := 0.0
+	if windSpeed != nil {
+		wind = *windSpeed
+	}
+	derived.ApparentTemperature = value(ApparentTemperature(t, rh, wind), "°C", FormulaApparentTemperature)
+	if v, ok := Humidex(t, rh); ok {
+		derived.Humidex = value(v, "°C", FormulaHumidex)
+	}
+	if v, ok := WetBulb(t, rh); ok {
+		derived.WetBulb = value(v, "°C", FormulaWetBulb)
+	}
+	derived.AbsoluteHumidity = value(AbsoluteHumidity(t, rh), "г/м³", FormulaAbsoluteHumidity)
+
+	return derived
+}
+
+func value(v float64, unit, formula string) *models.DerivedValue {
+	return &models.DerivedValue{
+		Value:   math.Round(v*10) / 10,
+		Unit:    unit,
+		Formula: formula,
+	}
+}
+
+// DewPoint точка росы по формуле Магнуса. ok = false при нулевой влажности:
+// логарифм от нуля не определен, точки росы нет.
+func DewPoint(temp, humidity float64) (float64, bool) {
+	if humidity <= 0 {
+		return 0, false
+	}
+	const a, b = 17.625, 243.04
+	gamma := math.Log(humidity/100) + a*temp/(b+temp)
+	return b * gamma / (a - gamma), true
+}
+
+// HeatIndex индекс жары NWS (регрессия Rothfusz с поправками для низкой и
+// высокой влажности). ok = false ниже 27°C, где индекс не отличается от
+// температуры воздуха.
+func HeatIndex(temp, humidity float64) (float64, bool) {
+	if temp < 27 {
+		return temp, false
+	}
+
+	t := temp*9/5 + 32
+	rh := humidity
+	hi := -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh -
+		0.00683783*t*t - 0.05481717*rh*rh + 0.00122874*t*t*rh +
+		0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+	switch {
+	case rh < 13 && t >= 80 && t <= 112:
+		hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+	case rh > 85 && t >= 80 && t <= 87:
+		hi += (rh - 85) / 10 * (87 - t) / 5
+	}
+
+	return (hi - 32) * 5 / 9, true
+}
+
+// WindChill ветро-холодовой индекс JAG/TI. ok = false при температуре выше
+// 10°C или ветре слабее 4.8 км/ч, где формула не применяется.
+func WindChill(temp, windSpeed float64) (float64, bool) {
+	kmh := windSpeed * 3.6
+	if temp > 10 || kmh <= 4.8 {
+		return temp, false
+	}
+	v := math.Pow(kmh, 0.16)
+	return 13.12 + 0.6215*temp - 11.37*v + 0.3965*temp*v, true
+}
+
+// ApparentTemperature ощущаемая температура по формуле Стедмана (в редакции
+// Австралийского бюро метеорологии) без учета солнечной радиации
+func ApparentTemperature(temp, humidity, windSpeed float64) float64 {
+	vapourPressure := humidity / 100 * 6.105 * math.Exp(17.27*temp/(237.7+temp))
+	return temp + 0.33*vapourPressure - 0.70*windSpeed - 4.00
+}
+
+// Humidex канадский индекс влажности и тепла. ok = false ниже 20°C:
+// Environment Canada его не сообщает, и без точки росы.
+func Humidex(temp, humidity float64) (float64, bool) {
+	if temp < 20 {
+		return temp, false
+	}
+	dewPoint, ok := DewPoint(temp, humidity)
+	if !ok {
+		return temp, false
+	}
+	vapourPressure := 6.11 * math.Exp(5417.7530*(1/273.16-1/(273.15+dewPoint)))
+	return temp + 0.5555*(vapourPressure-10), true
+}
+
+// WetBulb температура смоченного термометра по эмпирической формуле Stull
+// (2011) для давления у уровня моря. ok = false вне области, на которой
+// формула подобрана.
+func WetBulb(temp, humidity float64) (float64, bool) {
+	if humidity < 5 || humidity > 99 || temp < -20 || temp > 50 {
+		return 0, false
+	}
+	tw := temp*math.Atan(0.151977*math.Sqrt(humidity+8.313659)) +
+		math.Atan(temp+humidity) - math.Atan(humidity-1.676331) +
+		0.00391838*math.Pow(humidity, 1.5)*math.Atan(0.023101*humidity) -
+		4.686035
+	return tw, true
+}
+
+// AbsoluteHumidity абсолютная влажность, г/м³
+func AbsoluteHumidity(temp, humidity float64) float64 {
+	saturation := 6.112 * math.Exp(17.67*temp/(temp+243.5))
+	return saturation * humidity * 2.1674 / (273.15 + temp)
+}
@@ -0,0 +1,63 @@
+package derivations
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestComputeZeroHumidity(t *testing.T) {
+	// Сухой воздух из ответа провайдера: точки росы нет, значения,
+	// рассчитанные через нее, не должны стать NaN
+	for _, temp := range []float64{-5, 25, 35} {
+		derived := Compute(ptr(temp), ptr(0), ptr(3))
+		if derived == nil {
+			t.Fatalf("T=%v: Compute вернул nil", temp)
+		}
+		if derived.DewPoint != nil {
+			t.Errorf("T=%v: точка росы %v при нулевой влажности", temp, derived.DewPoint.Value)
+		}
+		if derived.Humidex != nil {
+			t.Errorf("T=%v: humidex %v при нулевой влажности", temp, derived.Humidex.Value)
+		}
+		if derived.WetBulb != nil {
+			t.Errorf("T=%v: температура смоченного термометра %v при нулевой влажности", temp, derived.WetBulb.Value)
+		}
+		if derived.ApparentTemperature == nil || derived.AbsoluteHumidity == nil {
+			t.Errorf("T=%v: величины, не зависящие от точки росы, не рассчитаны", temp)
+		}
+		if _, err := json.Marshal(derived); err != nil {
+			t.Errorf("T=%v: результат не сериализуется: %v", temp, err)
+		}
+	}
+}
+
+func TestDewPoint(t *testing.T) {
+	tests := []struct {
+		temp, humidity float64
+		want           float64
+		ok             bool
+	}{
+		{20, 100, 20, true},
+		{20, 50, 9.3, true},
+		{-10, 80, -12.8, true},
+		{20, 0, 0, false},
+		{20, -1, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := DewPoint(tt.temp, tt.humidity)
+		if ok != tt.ok {
+			t.Errorf("DewPoint(%v, %v) ok = %v, ожидалось %v", tt.temp, tt.humidity, ok, tt.ok)
+			continue
+		}
+		if ok && math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("DewPoint(%v, %v) = %.2f, ожидалось %.1f", tt.temp, tt.humidity, got, tt.want)
+		}
+	}
+
+	if _, ok := Humidex(30, 0); ok {
+		t.Error("Humidex рассчитан при нулевой влажности")
+	}
+}
@@ -0,0 +1,214 @@
+// Package geo определяет координаты городов для провайдеров, которые
+// принимают только широту и долготу (met.no, api.weather.gov и др.).
+package geo
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound возвращается, если координаты города определить не удалось
+var ErrNotFound = errors.New("координаты города не найдены")
+
+// Location город с координатами
+type Location struct {
+	Name    string  `json:"name"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+// Geocoder определяет координаты города
+type Geocoder interface {
+	Resolve(ctx context.Context, city, country string) (*Location, error)
+}
+
+//go:embed cities.json
+var citiesJSON []byte
+
+// Table геокодер по встроенной таблице крупных городов; работает без сети
+type Table struct {
+	index map[string]Location
+}
+
+// NewTable создает геокодер по встроенной таблице
+func NewTable() *Table {
+	var cities []struct {
+		Location
+		Aliases []string `json:"aliases"`
+	}
+	if err := json.Unmarshal(citiesJSON, &cities); err != nil {
+		panic(fmt.Sprintf("geo: некорректная встроенная таблица городов: %v", err))
+	}
+
+	t := &Table{index: make(map[string]Location)}
+	for _, c := range cities {
+		t.Add(c.Location, c.Aliases...)
+	}
+	return t
+}
+
+// Add добавляет город (и его альтернативные названия) в таблицу
+func (t *Table) Add(loc Location, aliases ...string) {
+	for _, name := range append([]string{loc.Name}, aliases...) {
+		t.index[key(name, loc.Country)] = loc
+	}
+}
+
+func (t *Table) Resolve(ctx context.Context, city, country string) (*Location, error) {
+	if loc, ok := t.index[key(city, country)]; ok {
+		return &loc, nil
+	}
+	return nil, fmt.Errorf("%w: %s, %s", ErrNotFound, city, country)
+}
+
+// OpenMeteo геокодер через бесплатный API geocoding-api.open-meteo.com (без ключа)
+type OpenMeteo struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewOpenMeteo создает геокодер Open-Meteo; пустой baseURL - боевой адрес
+func NewOpenMeteo(baseURL string) *OpenMeteo {
+	if baseURL == "" {
+		baseURL = "https://geocoding-api.open-meteo.com"
+	}
+	return &OpenMeteo{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (g *OpenMeteo) Resolve(ctx context.Context, city, country string) (*Location, error) {
+	query := url.Values{}
+	query.Set("name", city)
+	query.Set("count", "1")
+	query.Set("language", "ru")
+	if country != "" {
+		query.Set("countryCode", strings.ToUpper(country))
+	}
+
+	reqURL := fmt.Sprintf("%s/v1/search?%s", g.baseURL, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка HTTP запроса геокодера: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ошибка геокодера: статус %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Results []struct {
+			Name        string  `json:"name"`
+			Latitude    float64 `json:"latitude"`
+			Longitude   float64 `json:"longitude"`
+			CountryCode string  `json:"country_code"`
+		} `json:"results"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга JSON геокодера: %w", err)
+	}
+
+	if len(result.Results) == 0 {
+		return nil, fmt.Errorf("%w: %s, %s", ErrNotFound, city, country)
+	}
+
+	r := result.Results[0]
+	return &Location{
+		Name:    r.Name,
+		Country: r.CountryCode,
+		Lat:     r.Latitude,
+		Lon:     r.Longitude,
+	}, nil
+}
+
+// Chain опрашивает геокодеры по очереди и кеширует найденные координаты
+type Chain struct {
+	geocoders []Geocoder
+	mu        sync.RWMutex
+	cache     map[string]Location
+}
+
+// NewChain создает цепочку геокодеров с кешем
+func NewChain(geocoders ...Geocoder) *Chain {
+	return &Chain{
+		geocoders: geocoders,
+		cache:     make(map[string]Location),
+	}
+}
+
+// NewDefault встроенная таблица, затем Open-Meteo (если remoteURL не "off")
+func NewDefault(remoteURL string) *Chain {
+	if remoteURL == "off" {
+		return NewChain(NewTable())
+	}
+	return NewChain(NewTable(), NewOpenMeteo(remoteURL))
+}
+
+func (c *Chain) Resolve(ctx context.Context, city, country string) (*Location, error) {
+	k := key(city, country)
+
+	c.mu.RLock()
+	loc, ok := c.cache[k]
+	c.mu.RUnlock()
+	if ok {
+		return &loc, nil
+	}
+
+	var lastErr error
+	for _, g := range c.geocoders {
+		found, err := g.Resolve(ctx, city, country)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		c.mu.Lock()
+		c.cache[k] = *found
+		c.mu.Unlock()
+		return found, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("%w: %s, %s", ErrNotFound, city, country)
+	}
+	return nil, lastErr
+}
+
+// Distance расстояние между точками по большому кругу в километрах
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// key ключ поиска города без учета регистра
+func key(city, country string) string {
+	return strings.ToLower(strings.TrimSpace(city)) + "," + strings.ToUpper(strings.TrimSpace(country))
+}
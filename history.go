@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"weather-aggregator/config"
+	"weather-aggregator/history"
+	"weather-aggregator/models"
+)
+
+// historyMaxPoints число интервалов ряда, если шаг не указан
+const historyMaxPoints = 48
+
+// historyQuery параметры выборки ряда из истории
+type historyQuery struct {
+	city, country string
+	from, to      time.Time
+	step          time.Duration
+	fields        []string
+}
+
+// parseHistoryQuery разбирает параметры выборки. from и to - RFC3339, дата
+// ГГГГ-ММ-ДД или давность ("24h", "7d"); по умолчанию последние сутки.
+// Пустой шаг или "auto" - не больше historyMaxPoints интервалов.
+func parseHistoryQuery(city, country, from, to, step, fields string, now time.Time) (historyQuery, error) {
+	q := historyQuery{city: city, country: country, to: now}
+	if q.country == "" {
+		q.country = "RU"
+	}
+
+	var err error
+	if to != "" {
+		if q.to, err = parseHistoryTime(to, now); err != nil {
+			return q, fmt.Errorf("некорректный to: %w", err)
+		}
+	}
+	q.from = q.to.Add(-24 * time.Hour)
+	if from != "" {
+		if q.from, err = parseHistoryTime(from, now); err != nil {
+			return q, fmt.Errorf("некорректный from: %w", err)
+		}
+	}
+	if !q.from.Before(q.to) {
+		return q, fmt.Errorf("начало интервала должно быть раньше конца")
+	}
+
+	if step == "" || step == "auto" {
+		q.step = history.AutoStep(q.to.Sub(q.from), historyMaxPoints)
+	} else {
+		if q.step, err = parseDuration(step); err != nil {
+			return q, fmt.Errorf("некорректный step: %w", err)
+		}
+		if q.step < time.Minute {
+			return q, fmt.Errorf("шаг должен быть не меньше минуты")
+		}
+	}
+
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !isHistoryField(field) {
+			return q, fmt.Errorf("неизвестная величина %q, доступны: %s", field, strings.Join(history.Fields, ", "))
+		}
+		q.fields = append(q.fields, field)
+	}
+	return q, nil
+}
+
+// parseHistoryTime разбирает момент времени: RFC3339, дату в местном поясе
+// или давность относительно now
+func parseHistoryTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	ago, err := parseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ожидается RFC3339, ГГГГ-ММ-ДД или давность вида 24h, 7d: %q", value)
+	}
+	return now.Add(-ago), nil
+}
+
+// parseDuration разбирает длительность Go с дополнительной единицей "d" (сутки)
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("некорректная длительность %q", value)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("некорректная длительность %q", value)
+	}
+	return d, nil
+}
+
+// formatDuration длительность без нулевых младших единиц: "30m", "1h", "1h30m"
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func isHistoryField(field string) bool {
+	for _, f := range history.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// querySeries выбирает агрегированные точки и объединяет их в интервалы
+func querySeries(s *history.Store, q historyQuery) (*models.HistoryResponse, error) {
+	points, err := s.Query(history.Query{
+		City:    q.city,
+		Country: q.country,
+		From:    q.from,
+		To:      q.to,
+		Source:  history.SourceAggregated,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	series := history.Series(points, q.step, q.fields)
+	fields := q.fields
+	if len(fields) == 0 {
+		fields = history.SeriesFields(series)
+	}
+
+	return &models.HistoryResponse{
+		City:    q.city,
+		Country: q.country,
+		From:    q.from,
+		To:      q.to,
+		Step:    formatDuration(q.step),
+		Fields:  fields,
+		Points:  series,
+	}, nil
+}
+
+// newHistoryCmd создает команду просмотра истории погоды
+func newHistoryCmd() *cobra.Command {
+	var historyCmd = &cobra.Command{
+		Use:   "history [город]",
+		Short: "История погоды из HISTORY_DIR",
+		Long: "Выводит ряд агрегированных значений из истории погоды: минимум, среднее и\n" +
+			"максимум за каждый интервал. В табличном виде показывает тренды\n" +
+			"температуры и давления.",
+		Args: cobra.ExactArgs(1),
+		// Чтению истории не нужны ключи API и агрегатор
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			country, _ := cmd.Flags().GetString("country")
+			since, _ := cmd.Flags().GetString("since")
+			until, _ := cmd.Flags().GetString("until")
+			step, _ := cmd.Flags().GetString("step")
+			fields, _ := cmd.Flags().GetString("fields")
+			format, _ := cmd.Flags().GetString("format")
+
+			historyCLI(args[0], country, since, until, step, fields, format)
+		},
+	}
+
+	historyCmd.Flags().StringP("country", "c", "RU", "Код страны (например, RU, US)")
+	historyCmd.Flags().String("since", "24h", "Начало: давность (24h, 7d), дата ГГГГ-ММ-ДД или RFC3339")
+	historyCmd.Flags().String("until", "", "Конец в том же формате (по умолчанию сейчас)")
+	historyCmd.Flags().String("step", "auto", "Интервал ряда, например 30m, 1h, 1d")
+	historyCmd.Flags().String("fields", "", "Величины через запятую (по умолчанию все)")
+	historyCmd.Flags().StringP("format", "f", "table", "Формат вывода (table, csv, json)")
+
+	return historyCmd
+}
+
+// historyCLI читает и выводит ряд из истории
+func historyCLI(city, country, since, until, step, fields, format string) {
+	dir := config.LoadClient().HistoryDir
+	if dir == "" {
+		log.Fatalf("История не ведется: задайте HISTORY_DIR")
+	}
+	s, err := history.Open(dir, history.Options{})
+	if err != nil {
+		log.Fatalf("Ошибка открытия истории: %v", err)
+	}
+
+	q, err := parseHistoryQuery(city, country, since, until, step, fields, time.Now())
+	if err != nil {
+		log.Fatalf("Ошибка: %v", err)
+	}
+	resp, err := querySeries(s, q)
+	if err != nil {
+		log.Fatalf("Ошибка: %v", err)
+	}
+
+	switch format {
+	case "json":
+		data, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(data))
+	case "csv":
+		writeHistoryCSV(resp)
+	case "table":
+		printHistoryTable(resp)
+	default:
+		log.Fatalf("Неизвестный формат %q: ожидается table, csv или json", format)
+	}
+}
+
+// writeHistoryCSV выводит ряд в CSV: по три колонки (min, avg, max) на величину
+func writeHistoryCSV(resp *models.HistoryResponse) {
+	w := csv.NewWriter(os.Stdout)
+	header := []string{"time", "samples", "condition"}
+	for _, field := range resp.Fields {
+		header = append(header, field+"_min", field+"_avg", field+"_max")
+	}
+	w.Write(header)
+
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, p := range resp.Points {
+		row := []string{p.Time.Format(time.RFC3339), strconv.Itoa(p.Samples), p.Condition}
+		for _, field := range resp.Fields {
+			if stat, ok := p.Values[field]; ok {
+				row = append(row, format(stat.Min), format(stat.Avg), format(stat.Max))
+			} else {
+				row = append(row, "", "", "")
+			}
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatalf("Ошибка вывода CSV: %v", err)
+	}
+}
+
+// historyColumns колонки таблицы: заголовок и формат среднего значения
+var historyColumns = map[string]struct{ title, format string }{
+	"temperature":   {"Темп,°C", "%.1f"},
+	"feels_like":    {"Ощущ,°C", "%.1f"},
+	"humidity":      {"Влаж,%", "%.0f"},
+	"pressure":      {"Давл,hPa", "%.0f"},
+	"wind_speed":    {"Ветер,м/с", "%.1f"},
+	"wind_gust":     {"Порывы", "%.1f"},
+	"dew_point":     {"Роса,°C", "%.1f"},
+	"cloud_cover":   {"Облач,%", "%.0f"},
+	"visibility":    {"Видим,км", "%.1f"},
+	"uv_index":      {"УФ", "%.1f"},
+	"precipitation": {"Осадки", "%.1f"},
+	"rain":          {"Дождь", "%.1f"},
+	"snow":          {"Снег", "%.1f"},
+	"aqi":           {"AQI", "%.0f"},
+}
+
+// printHistoryTable выводит тренды температуры и давления и таблицу средних
+func printHistoryTable(resp *models.HistoryResponse) {
+	fmt.Printf("📈 %s, %s: %s - %s, шаг %s\n", resp.City, resp.Country,
+		resp.From.Local().Format("02.01 15:04"), resp.To.Local().Format("02.01 15:04"), resp.Step)
+	fmt.Println(strings.Repeat("=", 40))
+	if len(resp.Points) == 0 {
+		fmt.Println("Нет данных за этот период")
+		return
+	}
+
+	step, _ := time.ParseDuration(resp.Step)
+	start := resp.From.Truncate(step)
+	for _, trend := range []struct{ field, title, unit string }{
+		{"temperature", "Температура", "°C"},
+		{"pressure", "Давление", " hPa"},
+	} {
+		// Интервалы без точек остаются пропусками, чтобы тренд не сжимался по времени
+		values := make([]float64, int(resp.To.Sub(start)/step)+1)
+		for i := range values {
+			values[i] = math.NaN()
+		}
+		lo, hi, found := math.Inf(1), math.Inf(-1), false
+		for _, p := range resp.Points {
+			stat, ok := p.Values[trend.field]
+			i := int(p.Time.Sub(start) / step)
+			if !ok || i < 0 || i >= len(values) {
+				continue
+			}
+			values[i] = stat.Avg
+			lo, hi, found = math.Min(lo, stat.Min), math.Max(hi, stat.Max), true
+		}
+		if found {
+			fmt.Printf("%-12s %s  %.1f..%.1f%s\n", trend.title, sparkline(values), lo, hi, trend.unit)
+		}
+	}
+	fmt.Println()
+
+	fmt.Printf("%-12s", "Время")
+	for _, field := range resp.Fields {
+		fmt.Printf(" %10s", columnTitle(field))
+	}
+	fmt.Println()
+	for _, p := range resp.Points {
+		fmt.Printf("%-12s", p.Time.Local().Format("02.01 15:04"))
+		for _, field := range resp.Fields {
+			stat, ok := p.Values[field]
+			if !ok {
+				fmt.Printf(" %10s", "—")
+				continue
+			}
+			format := "%.1f"
+			if col, ok := historyColumns[field]; ok {
+				format = col.format
+			}
+			fmt.Printf(" %10s", fmt.Sprintf(format, stat.Avg))
+		}
+		fmt.Println()
+	}
+}
+
+func columnTitle(field string) string {
+	if col, ok := historyColumns[field]; ok {
+		return col.title
+	}
+	return field
+}
+
+// sparkline рисует ряд символами блоков; NaN - пропуск
+func sparkline(values []float64) string {
+	const bars = "▁▂▃▄▅▆▇█"
+	levels := []rune(bars)
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			sb.WriteRune(' ')
+		case hi == lo:
+			sb.WriteRune(levels[len(levels)/2])
+		default:
+			i := int((v - lo) / (hi - lo) * float64(len(levels)-1))
+			sb.WriteRune(levels[i])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,219 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CompactStats итог обслуживания хранилища
+type CompactStats struct {
+	Removed     int // удалено файлов суток по сроку хранения
+	Downsampled int // прорежено файлов суток
+}
+
+// Compact удаляет сутки старше срока хранения и прореживает сутки старше
+// DownsampleAfter до одной точки на DownsampleStep для каждого источника.
+// Прореженные сутки повторно не обрабатываются.
+func (s *Store) Compact(now time.Time) (CompactStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stats CompactStats
+	locations, err := os.ReadDir(s.dir)
+	if err != nil {
+		return stats, fmt.Errorf("ошибка чтения истории: %w", err)
+	}
+
+	for _, location := range locations {
+		if !location.IsDir() {
+			continue
+		}
+		days, err := s.days(filepath.Join(s.dir, location.Name()))
+		if err != nil {
+			return stats, err
+		}
+		// Прогнозы нужны только для сравнения с наблюдениями и не прореживаются
+		forecasts, err := s.days(filepath.Join(s.dir, location.Name(), forecastDir))
+		if err != nil {
+			return stats, err
+		}
+		for _, day := range forecasts {
+			if s.opts.Retention > 0 && !day.date.Add(24*time.Hour).After(now.Add(-s.opts.Retention)) {
+				if err := os.Remove(day.path); err != nil {
+					return stats, fmt.Errorf("ошибка удаления истории: %w", err)
+				}
+				stats.Removed++
+			}
+		}
+
+		for _, day := range days {
+			// Сутки целиком старше порога, если порог позже их конца
+			end := day.date.Add(24 * time.Hour)
+			switch {
+			case s.opts.Retention > 0 && !end.After(now.Add(-s.opts.Retention)):
+				if err := os.Remove(day.path); err != nil {
+					return stats, fmt.Errorf("ошибка удаления истории: %w", err)
+				}
+				stats.Removed++
+
+			case s.opts.DownsampleAfter > 0 && !day.downsampled && !end.After(now.Add(-s.opts.DownsampleAfter)):
+				if err := s.downsampleFile(day.path); err != nil {
+					return stats, err
+				}
+				stats.Downsampled++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// downsampleFile заменяет файл суток прореженным
+func (s *Store) downsampleFile(path string) error {
+	points, err := readPoints(path)
+	if err != nil {
+		return err
+	}
+	target := strings.TrimSuffix(path, rawExt) + downsampledExt
+	// Запоздавшие точки уже прореженных суток объединяются с ними
+	if _, err := os.Stat(target); err == nil {
+		existing, err := readPoints(target)
+		if err != nil {
+			return err
+		}
+		points = append(existing, points...)
+	}
+	points = Downsample(points, s.opts.DownsampleStep)
+
+	tmp := target + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("ошибка прореживания истории: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	// Неполный временный файл не должен заменить исходные сутки
+	for _, p := range points {
+		if err := enc.Encode(p); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("ошибка прореживания истории: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("ошибка прореживания истории: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("ошибка прореживания истории: %w", err)
+	}
+
+	// Сначала появляется прореженный файл, затем удаляется исходный: при
+	// сбое между шагами точки удваиваются, но не теряются
+	if err := os.Rename(tmp, target); err != nil {
+		return fmt.Errorf("ошибка прореживания истории: %w", err)
+	}
+	return os.Remove(path)
+}
+
+// Downsample объединяет точки каждого источника в интервалы step: значения
+// усредняются с учетом числа исходных точек, сохраняются минимум и максимум,
+// явление берется самое частое. Время точки - начало интервала.
+func Downsample(points []Point, step time.Duration) []Point {
+	type bucketKey struct {
+		source string
+		start  time.Time
+	}
+	type bucket struct {
+		point      Point
+		sums       map[string]float64
+		weights    map[string]int
+		conditions map[string]int
+		// Направление ветра усредняется как вектор, иначе среднее 350° и 10° дает 180°
+		windX, windY float64
+	}
+
+	buckets := make(map[bucketKey]*bucket)
+	var order []bucketKey
+
+	for _, p := range points {
+		key := bucketKey{source: p.Source, start: p.Time.Truncate(step)}
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{
+				point: Point{
+					Time:   key.start,
+					Source: p.Source,
+					Values: make(map[string]float64),
+					Min:    make(map[string]float64),
+					Max:    make(map[string]float64),
+				},
+				sums:       make(map[string]float64),
+				weights:    make(map[string]int),
+				conditions: make(map[string]int),
+			}
+			buckets[key] = b
+			order = append(order, key)
+		}
+
+		n := p.samples()
+		b.point.Samples += n
+		if p.Condition != "" {
+			b.conditions[p.Condition] += n
+		}
+		for name, v := range p.Values {
+			lo, hi := v, v
+			if m, ok := p.Min[name]; ok {
+				lo = m
+			}
+			if m, ok := p.Max[name]; ok {
+				hi = m
+			}
+			if cur, ok := b.point.Min[name]; !ok || lo < cur {
+				b.point.Min[name] = lo
+			}
+			if cur, ok := b.point.Max[name]; !ok || hi > cur {
+				b.point.Max[name] = hi
+			}
+			if name == "wind_direction" {
+				b.windX += float64(n) * math.Cos(v*math.Pi/180)
+				b.windY += float64(n) * math.Sin(v*math.Pi/180)
+			}
+			b.sums[name] += v * float64(n)
+			b.weights[name] += n
+		}
+	}
+
+	result := make([]Point, 0, len(order))
+	for _, key := range order {
+		b := buckets[key]
+		for name, sum := range b.sums {
+			b.point.Values[name] = sum / float64(b.weights[name])
+		}
+		if _, ok := b.weights["wind_direction"]; ok {
+			b.point.Values["wind_direction"] = math.Mod(math.Atan2(b.windY, b.windX)*180/math.Pi+360, 360)
+			// Минимум и максимум угла не имеют смысла
+			delete(b.point.Min, "wind_direction")
+			delete(b.point.Max, "wind_direction")
+		}
+		best := 0
+		for condition, n := range b.conditions {
+			if n > best || (n == best && condition < b.point.Condition) {
+				best = n
+				b.point.Condition = condition
+			}
+		}
+		result = append(result, b.point)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return result
+}
@@ -0,0 +1,122 @@
+package history
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"weather-aggregator/models"
+)
+
+func TestCompactRetention(t *testing.T) {
+	store := openStore(t, Options{Retention: 3 * 24 * time.Hour})
+	now := day
+	for _, d := range []int{-4, -3, -2, 0} {
+		at := day.AddDate(0, 0, d).Add(time.Hour)
+		if err := store.Append("Москва", "RU", point("A", at, float64(d))); err != nil {
+			t.Fatal(err)
+		}
+		err := store.RecordForecast("Москва", "RU", "A", at, []models.ForecastPoint{{Time: at, Temperature: models.Float(1)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := store.Compact(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Целиком старше трех суток только -4: они кончились ровно на границе
+	if stats.Removed != 2 || stats.Downsampled != 0 {
+		t.Errorf("статистика %+v, ожидалось удаление суток наблюдений и суток прогнозов", stats)
+	}
+	points, err := store.Query(Query{City: "Москва", Country: "RU"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 || points[0].Values["temperature"] != -3 {
+		t.Errorf("после удаления остались %+v", points)
+	}
+	forecasts, err := store.QueryForecasts(Query{City: "Москва", Country: "RU"})
+	if err != nil || len(forecasts) != 3 {
+		t.Errorf("после удаления остались прогнозы %+v, %v", forecasts, err)
+	}
+}
+
+func TestCompactDownsample(t *testing.T) {
+	store := openStore(t, Options{DownsampleAfter: 24 * time.Hour, DownsampleStep: time.Hour})
+	old := day.AddDate(0, 0, -2)
+	err := store.Append("Москва", "RU",
+		Point{Time: old.Add(10 * time.Minute), Source: "A", Condition: "rain",
+			Values: map[string]float64{"temperature": 1, "wind_direction": 350}},
+		Point{Time: old.Add(20 * time.Minute), Source: "A", Condition: "rain",
+			Values: map[string]float64{"temperature": 2, "wind_direction": 10}},
+		Point{Time: old.Add(50 * time.Minute), Source: "A", Condition: "snow",
+			Values: map[string]float64{"temperature": 6}},
+		point("B", old.Add(30*time.Minute), 10),
+		point("A", old.Add(90*time.Minute), 20),
+		// Свежие сутки не прореживаются
+		point("A", day.Add(time.Hour), 30),
+		point("A", day.Add(time.Hour+time.Minute), 31),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := store.Compact(day.Add(12 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Downsampled != 1 || stats.Removed != 0 {
+		t.Fatalf("статистика %+v, ожидалось прореживание одних суток", stats)
+	}
+	location := filepath.Join(store.dir, locationKey("Москва", "RU"))
+	if _, err := os.Stat(filepath.Join(location, old.Format(time.DateOnly)+rawExt)); !os.IsNotExist(err) {
+		t.Errorf("исходный файл прореженных суток не удален: %v", err)
+	}
+
+	points, err := store.Query(Query{City: "Москва", Country: "RU", To: day})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("прореженных точек %d, ожидалось 3: %+v", len(points), points)
+	}
+	a := points[0]
+	if a.Source != "A" || !a.Time.Equal(old) || a.Samples != 3 || a.Condition != "rain" {
+		t.Errorf("точка A %+v", a)
+	}
+	if a.Values["temperature"] != 3 || a.Min["temperature"] != 1 || a.Max["temperature"] != 6 {
+		t.Errorf("температура A: среднее %v, min %v, max %v; ожидалось 3, 1, 6",
+			a.Values["temperature"], a.Min["temperature"], a.Max["temperature"])
+	}
+	// 350° и 10° в среднем дают север, а не юг
+	if d := a.Values["wind_direction"]; math.Min(d, 360-d) > 0.01 {
+		t.Errorf("направление ветра %v, ожидалось 0", d)
+	}
+	if _, ok := a.Min["wind_direction"]; ok {
+		t.Error("у направления ветра не должно быть минимума")
+	}
+
+	// Повторное прореживание учитывает число исходных точек
+	if err := store.Append("Москва", "RU", point("A", old.Add(5*time.Minute), 7)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Compact(day.Add(12 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	points, err = store.Query(Query{City: "Москва", Country: "RU", Source: "A", To: old.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 || points[0].Samples != 4 || points[0].Values["temperature"] != 4 || points[0].Max["temperature"] != 7 {
+		t.Errorf("после запоздавшей точки %+v, ожидалось 4 исходные точки со средним 4", points)
+	}
+
+	recent, err := store.Query(Query{City: "Москва", Country: "RU", From: day})
+	if err != nil || len(recent) != 2 {
+		t.Errorf("свежие точки %+v, %v", recent, err)
+	}
+}
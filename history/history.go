@@ -0,0 +1,429 @@
+// Package history хранит историю погоды в файлах без внешней базы данных:
+// каждый агрегированный результат и ответ каждого провайдера записывается
+// точкой временного ряда. Точки лежат в JSON Lines по одному файлу на
+// локацию и сутки (UTC):
+//
+//	{dir}/{локация}/2026-10-18.jsonl
+//
+// Старые сутки прореживаются до одной точки на интервал (min/avg/max) и
+// удаляются по истечении срока хранения, см. Store.Compact.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"weather-aggregator/models"
+)
+
+// SourceAggregated источник точек агрегированного результата; точки
+// провайдеров подписаны их именами
+const SourceAggregated = "aggregated"
+
+// Расширения файлов: исходные точки и прореженные сутки
+const (
+	rawExt         = ".jsonl"
+	downsampledExt = ".ds.jsonl"
+)
+
+// forecastDir подкаталог локации с прогнозами провайдеров
+const forecastDir = "forecasts"
+
+// Point точка временного ряда. У прореженной точки Values - средние за
+// интервал, Min и Max - экстремумы, Samples - число исходных точек. У точки
+// прогноза Time - момент, на который он составлен, Issued - когда получен.
+type Point struct {
+	Time      time.Time          `json:"time"`
+	Issued    *time.Time         `json:"issued,omitempty"`
+	Source    string             `json:"source"`
+	Values    map[string]float64 `json:"values"`
+	Min       map[string]float64 `json:"min,omitempty"`
+	Max       map[string]float64 `json:"max,omitempty"`
+	Condition string             `json:"condition,omitempty"`
+	Samples   int                `json:"samples,omitempty"`
+}
+
+// samples число исходных точек, из которых получена точка
+func (p Point) samples() int {
+	if p.Samples == 0 {
+		return 1
+	}
+	return p.Samples
+}
+
+// Options сроки хранения и прореживания
+type Options struct {
+	// Retention сколько хранить точки; 0 - бессрочно
+	Retention time.Duration
+	// DownsampleAfter сутки старше этого прореживаются; 0 - не прореживать
+	DownsampleAfter time.Duration
+	// DownsampleStep интервал прореженных точек (по умолчанию час)
+	DownsampleStep time.Duration
+}
+
+// Store файловое хранилище истории
+type Store struct {
+	dir  string
+	opts Options
+
+	mu sync.Mutex
+	// latest время последней записанной точки провайдера: каталог локации и
+	// источник -> время наблюдения
+	latest map[string]time.Time
+}
+
+// Open открывает хранилище в каталоге dir, создавая его при необходимости
+func Open(dir string, opts Options) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога истории: %w", err)
+	}
+	if opts.DownsampleStep <= 0 {
+		opts.DownsampleStep = time.Hour
+	}
+	return &Store{dir: dir, opts: opts, latest: make(map[string]time.Time)}, nil
+}
+
+// Record записывает агрегированный результат и исходные ответы провайдеров.
+// Точка провайдера стоит на времени наблюдения, поэтому наблюдение, которое
+// провайдер повторяет до прихода нового (NWS, METAR, станции), записывается
+// один раз.
+func (s *Store) Record(city, country string, aggregated *models.AggregatedWeather, raw []*models.WeatherData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, locationKey(city, country))
+	points := make([]Point, 0, len(raw)+1)
+	if aggregated != nil {
+		points = append(points, aggregatedPoint(aggregated))
+	}
+	for _, d := range raw {
+		p := providerPoint(d)
+		if s.recorded(dir, p) {
+			continue
+		}
+		points = append(points, p)
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	if err := appendPoints(dir, points); err != nil {
+		return err
+	}
+	for _, p := range points {
+		if p.Source != SourceAggregated {
+			s.latest[dir+"/"+p.Source] = p.Time.UTC()
+		}
+	}
+	return nil
+}
+
+// recorded проверяет, что у источника точки уже записана точка не раньше нее.
+// После запуска время последней точки источника читается из файла суток.
+func (s *Store) recorded(dir string, p Point) bool {
+	key := dir + "/" + p.Source
+	last, ok := s.latest[key]
+	if !ok {
+		path := filepath.Join(dir, p.Time.UTC().Format(time.DateOnly)+rawExt)
+		if points, err := readPoints(path); err == nil {
+			for _, existing := range points {
+				if existing.Source == p.Source && existing.Time.After(last) {
+					last = existing.Time
+				}
+			}
+		}
+		s.latest[key] = last
+	}
+	return !p.Time.After(last)
+}
+
+// Append дописывает точки в файлы суток, к которым они относятся
+func (s *Store) Append(city, country string, points ...Point) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return appendPoints(filepath.Join(s.dir, locationKey(city, country)), points)
+}
+
+// RecordForecast сохраняет прогноз провайдера, полученный в момент issued.
+// Прогнозы лежат отдельно от наблюдений, в подкаталоге forecasts локации, по
+// файлу на сутки, на которые составлен прогноз.
+func (s *Store) RecordForecast(city, country, provider string, issued time.Time, forecast []models.ForecastPoint) error {
+	issued = issued.UTC()
+	points := make([]Point, 0, len(forecast))
+	for _, f := range forecast {
+		p := Point{
+			Time:   f.Time,
+			Issued: &issued,
+			Source: provider,
+			Values: make(map[string]float64),
+		}
+		for name, v := range map[string]*float64{
+			"temperature": f.Temperature,
+			"humidity":    f.Humidity,
+			"pressure":    f.Pressure,
+			"wind_speed":  f.WindSpeed,
+		} {
+			if v != nil {
+				p.Values[name] = *v
+			}
+		}
+		if len(p.Values) > 0 {
+			points = append(points, p)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return appendPoints(filepath.Join(s.dir, locationKey(city, country), forecastDir), points)
+}
+
+// appendPoints дописывает точки в файлы суток каталога dir
+func appendPoints(dir string, points []Point) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("ошибка создания каталога истории: %w", err)
+	}
+
+	byDay := make(map[string][]Point)
+	for _, p := range points {
+		p.Time = p.Time.UTC()
+		day := p.Time.Format(time.DateOnly)
+		byDay[day] = append(byDay[day], p)
+	}
+
+	for day, dayPoints := range byDay {
+		f, err := os.OpenFile(filepath.Join(dir, day+rawExt), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("ошибка открытия файла истории: %w", err)
+		}
+		w := bufio.NewWriter(f)
+		enc := json.NewEncoder(w)
+		for _, p := range dayPoints {
+			if err := enc.Encode(p); err != nil {
+				f.Close()
+				return fmt.Errorf("ошибка записи истории: %w", err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			f.Close()
+			return fmt.Errorf("ошибка записи истории: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("ошибка записи истории: %w", err)
+		}
+	}
+	return nil
+}
+
+// Query параметры выборки
+type Query struct {
+	City    string
+	Country string
+	From    time.Time
+	To      time.Time
+	// Source источник точек: SourceAggregated, имя провайдера или пусто - все
+	Source string
+}
+
+// Query возвращает точки локации в интервале [From, To), упорядоченные по времени
+func (s *Store) Query(q Query) ([]Point, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.query(filepath.Join(s.dir, locationKey(q.City, q.Country)), q)
+}
+
+// QueryForecasts возвращает прогнозы, составленные на моменты в интервале
+// [From, To), упорядоченные по времени
+func (s *Store) QueryForecasts(q Query) ([]Point, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.query(filepath.Join(s.dir, locationKey(q.City, q.Country), forecastDir), q)
+}
+
+// query выбирает точки из файлов суток каталога dir
+func (s *Store) query(dir string, q Query) ([]Point, error) {
+	days, err := s.days(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []Point
+	for _, day := range days {
+		// Файл суток может содержать точки только этих суток
+		if !q.From.IsZero() && day.date.Add(24*time.Hour).Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && !day.date.Before(q.To) {
+			continue
+		}
+
+		dayPoints, err := readPoints(day.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range dayPoints {
+			if q.Source != "" && p.Source != q.Source {
+				continue
+			}
+			if (!q.From.IsZero() && p.Time.Before(q.From)) || (!q.To.IsZero() && !p.Time.Before(q.To)) {
+				continue
+			}
+			points = append(points, p)
+		}
+	}
+
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points, nil
+}
+
+// dayFile файл суток
+type dayFile struct {
+	date        time.Time
+	path        string
+	downsampled bool
+}
+
+// days перечисляет файлы суток каталога локации по возрастанию даты
+func (s *Store) days(dir string) ([]dayFile, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения истории: %w", err)
+	}
+
+	var days []dayFile
+	for _, e := range entries {
+		name := e.Name()
+		downsampled := strings.HasSuffix(name, downsampledExt)
+		base := strings.TrimSuffix(strings.TrimSuffix(name, downsampledExt), rawExt)
+		date, err := time.Parse(time.DateOnly, base)
+		if err != nil || e.IsDir() {
+			continue
+		}
+		days = append(days, dayFile{date: date, path: filepath.Join(dir, name), downsampled: downsampled})
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].date.Before(days[j].date) })
+	return days, nil
+}
+
+// readPoints читает точки из файла; поврежденные строки (например, оборванная
+// при аварийной остановке запись) пропускаются
+func readPoints(path string) ([]Point, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения истории: %w", err)
+	}
+	defer f.Close()
+
+	var points []Point
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var p Point
+		if json.Unmarshal(scanner.Bytes(), &p) == nil {
+			points = append(points, p)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения истории: %w", err)
+	}
+	return points, nil
+}
+
+// locationKey имя каталога локации: город и страна в нижнем регистре,
+// символы кроме букв и цифр заменены на "_"
+func locationKey(city, country string) string {
+	key := strings.ToLower(strings.TrimSpace(city) + "_" + strings.TrimSpace(country))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// aggregatedPoint точка агрегированного результата: средние значения
+func aggregatedPoint(w *models.AggregatedWeather) Point {
+	p := Point{
+		Time:      w.LastUpdated,
+		Source:    SourceAggregated,
+		Values:    make(map[string]float64),
+		Condition: w.Condition,
+	}
+	fields := map[string]*models.AggregatedValue{
+		"temperature":   w.Temperature,
+		"feels_like":    w.FeelsLike,
+		"humidity":      w.Humidity,
+		"pressure":      w.Pressure,
+		"wind_speed":    w.WindSpeed,
+		"wind_gust":     w.WindGust,
+		"dew_point":     w.DewPoint,
+		"cloud_cover":   w.CloudCover,
+		"visibility":    w.Visibility,
+		"uv_index":      w.UVIndex,
+		"precipitation": w.Precipitation,
+		"rain":          w.Rain,
+		"snow":          w.Snow,
+	}
+	for name, v := range fields {
+		if v != nil {
+			p.Values[name] = v.Average
+		}
+	}
+	if aq := w.AirQuality; aq != nil {
+		switch {
+		case aq.USEPA != nil:
+			p.Values["aqi"] = float64(aq.USEPA.Value)
+		case aq.AQI != nil:
+			p.Values["aqi"] = aq.AQI.Average
+		}
+	}
+	return p
+}
+
+// providerPoint точка ответа провайдера
+func providerPoint(d *models.WeatherData) Point {
+	p := Point{
+		Time:      d.Timestamp,
+		Source:    d.Provider,
+		Values:    make(map[string]float64),
+		Condition: d.Condition,
+	}
+	fields := map[string]*float64{
+		"temperature":    d.Temperature,
+		"feels_like":     d.FeelsLike,
+		"humidity":       d.Humidity,
+		"pressure":       d.Pressure,
+		"wind_speed":     d.WindSpeed,
+		"wind_direction": d.WindDirection,
+		"wind_gust":      d.WindGust,
+		"dew_point":      d.DewPoint,
+		"cloud_cover":    d.CloudCover,
+		"visibility":     d.Visibility,
+		"uv_index":       d.UVIndex,
+		"precipitation":  d.Precipitation,
+		"rain":           d.Rain,
+		"snow":           d.Snow,
+	}
+	for name, v := range fields {
+		if v != nil {
+			p.Values[name] = *v
+		}
+	}
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+	return p
+}
@@ -0,0 +1,203 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"weather-aggregator/models"
+)
+
+var day = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+func openStore(t *testing.T, opts Options) *Store {
+	t.Helper()
+	store, err := Open(t.TempDir(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func point(source string, at time.Time, temperature float64) Point {
+	return Point{Time: at, Source: source, Values: map[string]float64{"temperature": temperature}}
+}
+
+func TestQuery(t *testing.T) {
+	store := openStore(t, Options{})
+	// Точки нескольких суток вперемешку, в том числе в другом часовом поясе
+	moscow := time.FixedZone("MSK", 3*3600)
+	err := store.Append("Москва", "RU",
+		point("A", day.Add(25*time.Hour), 3),
+		point(SourceAggregated, day.Add(time.Hour), 1),
+		point("A", day.Add(time.Hour), 2),
+		point("B", day.Add(-time.Hour), 0),
+		point("A", day.Add(2*time.Hour).In(moscow), 4),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Другая локация не попадает в выборку
+	if err := store.Append("New York", "US", point("A", day.Add(time.Hour), 99)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []float64
+	}{
+		{"All", Query{}, []float64{0, 1, 2, 4, 3}},
+		{"FromInclusive", Query{From: day.Add(time.Hour)}, []float64{1, 2, 4, 3}},
+		{"ToExclusive", Query{To: day.Add(2 * time.Hour)}, []float64{0, 1, 2}},
+		{"Window", Query{From: day, To: day.Add(24 * time.Hour)}, []float64{1, 2, 4}},
+		{"Source", Query{Source: "A"}, []float64{2, 4, 3}},
+		{"Aggregated", Query{Source: SourceAggregated}, []float64{1}},
+		{"Empty", Query{From: day.Add(48 * time.Hour)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			q.City, q.Country = "Москва", "RU"
+			points, err := store.Query(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []float64
+			for _, p := range points {
+				got = append(got, p.Values["temperature"])
+			}
+			if !equal(got, tt.want) {
+				t.Errorf("температуры %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+
+	points, err := store.Query(Query{City: "Москва", Country: "RU", Source: "A", From: day.Add(2 * time.Hour), To: day.Add(3 * time.Hour)})
+	if err != nil || len(points) != 1 || points[0].Time.Location() != time.UTC {
+		t.Errorf("время точки должно храниться в UTC: %+v, %v", points, err)
+	}
+}
+
+func TestRecordSkipsRepeatedObservations(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	observed := day.Add(10 * time.Hour)
+	metar := &models.WeatherData{Provider: "METAR", Temperature: models.Float(5), Timestamp: observed}
+	record := func(store *Store, at time.Time, metar *models.WeatherData) {
+		t.Helper()
+		aggregated := &models.AggregatedWeather{
+			Temperature: &models.AggregatedValue{Average: 6},
+			LastUpdated: at,
+		}
+		openweather := &models.WeatherData{Provider: "OpenWeatherMap", Temperature: models.Float(7), Timestamp: at}
+		if err := store.Record("Москва", "RU", aggregated, []*models.WeatherData{metar, openweather}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// METAR возвращает одно наблюдение, пока не придет новое
+	record(store, observed.Add(10*time.Minute), metar)
+	record(store, observed.Add(20*time.Minute), metar)
+	// После перезапуска время последней точки читается из файла
+	reopened, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record(reopened, observed.Add(30*time.Minute), metar)
+	record(reopened, observed.Add(40*time.Minute), &models.WeatherData{
+		Provider: "METAR", Temperature: models.Float(4), Timestamp: observed.Add(30 * time.Minute),
+	})
+
+	count := func(source string) int {
+		points, err := reopened.Query(Query{City: "Москва", Country: "RU", Source: source})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(points)
+	}
+	if n := count("METAR"); n != 2 {
+		t.Errorf("точек METAR %d, ожидалось 2: повторное наблюдение записано снова", n)
+	}
+	if n := count("OpenWeatherMap"); n != 4 {
+		t.Errorf("точек OpenWeatherMap %d, ожидалось 4", n)
+	}
+	if n := count(SourceAggregated); n != 4 {
+		t.Errorf("агрегированных точек %d, ожидалось 4", n)
+	}
+}
+
+func TestForecasts(t *testing.T) {
+	store := openStore(t, Options{})
+	issued := day.Add(6 * time.Hour)
+	forecast := []models.ForecastPoint{
+		{Time: day.Add(7 * time.Hour), Temperature: models.Float(1), Humidity: models.Float(80)},
+		{Time: day.Add(8 * time.Hour)}, // без значений не сохраняется
+		{Time: day.Add(30 * time.Hour), WindSpeed: models.Float(3)},
+	}
+	if err := store.RecordForecast("Москва", "RU", "MET Norway", issued, forecast); err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := store.QueryForecasts(Query{City: "Москва", Country: "RU", To: day.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 {
+		t.Fatalf("прогнозов %d, ожидался 1: %+v", len(points), points)
+	}
+	p := points[0]
+	if p.Source != "MET Norway" || p.Issued == nil || !p.Issued.Equal(issued) ||
+		p.Values["temperature"] != 1 || p.Values["humidity"] != 80 || len(p.Values) != 2 {
+		t.Errorf("прогноз %+v", p)
+	}
+
+	// Прогнозы не смешиваются с наблюдениями
+	observations, err := store.Query(Query{City: "Москва", Country: "RU"})
+	if err != nil || len(observations) != 0 {
+		t.Errorf("прогнозы попали в наблюдения: %+v, %v", observations, err)
+	}
+}
+
+func TestReadPointsSkipsCorruptLines(t *testing.T) {
+	store := openStore(t, Options{})
+	if err := store.Append("Москва", "RU", point("A", day.Add(time.Hour), 1)); err != nil {
+		t.Fatal(err)
+	}
+	// Оборванная при аварийной остановке запись и мусор
+	path := filepath.Join(store.dir, locationKey("Москва", "RU"), "2026-10-18"+rawExt)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"time\":\"2026-10-18T02:00:00Z\",\"sour\n\nnot json\n")
+	f.Close()
+	if err := store.Append("Москва", "RU", point("A", day.Add(3*time.Hour), 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := store.Query(Query{City: "Москва", Country: "RU"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 || points[0].Values["temperature"] != 1 || points[1].Values["temperature"] != 3 {
+		t.Errorf("точки %+v, ожидались две целые", points)
+	}
+}
+
+func equal(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
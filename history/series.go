@@ -0,0 +1,125 @@
+package history
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"weather-aggregator/models"
+)
+
+// Fields величины агрегированных точек в порядке вывода
+var Fields = []string{
+	"temperature", "feels_like", "humidity", "pressure", "wind_speed", "wind_gust",
+	"dew_point", "cloud_cover", "visibility", "uv_index", "precipitation", "rain", "snow", "aqi",
+}
+
+// steps интервалы, из которых выбирает AutoStep
+var steps = []time.Duration{
+	5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// AutoStep наименьший круглый интервал, при котором в span помещается не
+// больше maxPoints интервалов
+func AutoStep(span time.Duration, maxPoints int) time.Duration {
+	for _, step := range steps {
+		if span <= step*time.Duration(maxPoints) {
+			return step
+		}
+	}
+	return steps[len(steps)-1]
+}
+
+// Series объединяет точки в интервалы step и возвращает минимум, среднее и
+// максимум величин fields (пусто - всех, что встречаются в точках). Точки
+// должны относиться к одному источнику.
+func Series(points []Point, step time.Duration, fields []string) []models.HistoryPoint {
+	buckets := Downsample(points, step)
+
+	series := make([]models.HistoryPoint, 0, len(buckets))
+	for _, b := range buckets {
+		hp := models.HistoryPoint{
+			Time:      b.Time,
+			Samples:   b.samples(),
+			Condition: b.Condition,
+			Values:    make(map[string]models.HistoryStat),
+		}
+		for name, avg := range b.Values {
+			if len(fields) > 0 && !contains(fields, name) {
+				continue
+			}
+			stat := models.HistoryStat{Min: avg, Avg: round(avg), Max: avg}
+			if v, ok := b.Min[name]; ok {
+				stat.Min = v
+			}
+			if v, ok := b.Max[name]; ok {
+				stat.Max = v
+			}
+			stat.Min, stat.Max = round(stat.Min), round(stat.Max)
+			hp.Values[name] = stat
+		}
+		series = append(series, hp)
+	}
+	return series
+}
+
+// SeriesFields величины, которые встречаются в ряду, в порядке Fields
+func SeriesFields(series []models.HistoryPoint) []string {
+	seen := make(map[string]bool)
+	for _, p := range series {
+		for name := range p.Values {
+			seen[name] = true
+		}
+	}
+
+	var fields []string
+	for _, name := range Fields {
+		if seen[name] {
+			fields = append(fields, name)
+			delete(seen, name)
+		}
+	}
+	// Величины вне Fields (например, записанные провайдерами) - по алфавиту
+	var rest []string
+	for name := range seen {
+		rest = append(rest, name)
+	}
+	sort.Strings(rest)
+	return append(fields, rest...)
+}
+
+// Nearest ближайшая к t точка не дальше tolerance; points упорядочены по
+// времени, как их возвращает Query
+func Nearest(points []Point, t time.Time, tolerance time.Duration) (Point, bool) {
+	i := sort.Search(len(points), func(i int) bool { return !points[i].Time.Before(t) })
+
+	best, found := Point{}, false
+	bestDiff := tolerance
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(points) {
+			continue
+		}
+		diff := points[j].Time.Sub(t)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff <= bestDiff {
+			best, bestDiff, found = points[j], diff, true
+		}
+	}
+	return best, found
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
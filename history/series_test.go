@@ -0,0 +1,42 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNearest(t *testing.T) {
+	base := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	points := []Point{
+		{Time: base, Source: "a"},
+		{Time: base.Add(20 * time.Minute), Source: "b"},
+		{Time: base.Add(time.Hour), Source: "c"},
+	}
+
+	tests := []struct {
+		name   string
+		t      time.Time
+		want   string
+		wantOK bool
+	}{
+		{"Exact", base.Add(20 * time.Minute), "b", true},
+		{"CloserBefore", base.Add(5 * time.Minute), "a", true},
+		{"CloserAfter", base.Add(15 * time.Minute), "b", true},
+		{"BeforeFirst", base.Add(-10 * time.Minute), "a", true},
+		{"AfterLast", base.Add(70 * time.Minute), "c", true},
+		{"AtTolerance", base.Add(90 * time.Minute), "c", true},
+		{"BeyondTolerance", base.Add(91 * time.Minute), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Nearest(points, tt.t, 30*time.Minute)
+			if ok != tt.wantOK || got.Source != tt.want {
+				t.Errorf("Nearest = %q, %v; ожидалось %q, %v", got.Source, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if _, ok := Nearest(nil, base, time.Hour); ok {
+		t.Error("Nearest нашел точку в пустом ряду")
+	}
+}
@@ -0,0 +1,74 @@
+// Package icons содержит встроенный набор SVG иконок для общих кодов явлений
+// погоды (models.Condition*). Иконки не зависят от того, какие провайдеры
+// ответили: у ясной погоды и переменной облачности есть дневной и ночной
+// варианты, у остальных явлений иконка одна.
+package icons
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"weather-aggregator/models"
+)
+
+//go:embed svg/*.svg
+var files embed.FS
+
+// Unknown иконка для неизвестного явления
+const Unknown = "unknown"
+
+// withNight явления, у которых днем и ночью разные иконки
+var withNight = map[string]bool{
+	models.ConditionClear:        true,
+	models.ConditionPartlyCloudy: true,
+}
+
+// Name возвращает имя иконки (без расширения) для кода явления, например
+// "clear-night" или "rain"
+func Name(condition string, night bool) string {
+	if withNight[condition] {
+		if night {
+			return condition + "-night"
+		}
+		return condition + "-day"
+	}
+	if !Exists(condition) {
+		return Unknown
+	}
+	return condition
+}
+
+// Exists проверяет, что в наборе есть иконка с таким именем
+func Exists(name string) bool {
+	_, err := fs.Stat(files, "svg/"+name+".svg")
+	return name != "" && err == nil
+}
+
+// URL возвращает адрес иконки относительно baseURL (пустой - относительно
+// корня сервера)
+func URL(baseURL, condition string, night bool) string {
+	return strings.TrimRight(baseURL, "/") + "/icons/" + Name(condition, night) + ".svg"
+}
+
+// Handler отдает иконки по адресам /icons/{имя}.svg. Набор встроен в бинарник
+// и не меняется, поэтому ответы можно кешировать надолго.
+func Handler() http.Handler {
+	root, err := fs.Sub(files, "svg")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.StripPrefix("/icons/", http.FileServer(http.FS(root)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/icons/"), ".svg")
+		if !strings.HasSuffix(r.URL.Path, ".svg") || strings.Contains(name, "/") || !Exists(name) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		fileServer.ServeHTTP(w, r)
+	})
+}
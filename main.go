@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -15,43 +17,41 @@ import (
 	"github.com/spf13/cobra"
 
 	"weather-aggregator/aggregator"
+	"weather-aggregator/climate"
 	"weather-aggregator/config"
+	"weather-aggregator/correction"
+	"weather-aggregator/geo"
+	"weather-aggregator/history"
+	"weather-aggregator/icons"
+	"weather-aggregator/mockupstream"
 	"weather-aggregator/models"
 	"weather-aggregator/providers"
+	"weather-aggregator/providers/chaos"
+	"weather-aggregator/pws"
+	"weather-aggregator/scheduler"
+	"weather-aggregator/sensors"
+	"weather-aggregator/verification"
 )
 
 var (
-	cfg *config.Config
-	agg *aggregator.Aggregator
+	cfg      *config.Config
+	agg      *aggregator.Aggregator
+	chaosCtl *chaos.Controller
+	geocoder geo.Geocoder
+	store    *history.Store
+	watcher  *scheduler.Scheduler
+	scorer   *verification.Scorer
 )
 
 func main() {
-	// Загружаем конфигурацию
-	var err error
-	cfg, err = config.Load()
-	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
-	}
-
-	// Создаем агрегатор
-	agg = aggregator.NewAggregator(cfg.CacheDuration)
-
-	// Добавляем провайдеры
-	if cfg.OpenWeatherAPIKey != "" {
-		agg.AddProvider(providers.NewOpenWeatherProvider(cfg.OpenWeatherAPIKey))
-		log.Printf("Провайдер OpenWeatherMap добавлен")
-	}
-
-	if cfg.WeatherAPIKey != "" {
-		agg.AddProvider(providers.NewWeatherAPIProvider(cfg.WeatherAPIKey))
-		log.Printf("Провайдер WeatherAPI добавлен")
-	}
-
 	// Создаем CLI команды
 	var rootCmd = &cobra.Command{
 		Use:   "weather",
 		Short: "Погодный агрегатор",
 		Long:  "Получает погоду из нескольких источников и агрегирует данные",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			setup()
+		},
 	}
 
 	// Команда для запуска сервера
@@ -98,7 +98,27 @@ func main() {
 		},
 	}
 
-	rootCmd.AddCommand(serverCmd, getCmd, providersCmd, clearCacheCmd)
+	// Команда для запуска эмулятора внешних API
+	var mockUpstreamCmd = &cobra.Command{
+		Use:   "mock-upstream",
+		Short: "Запуск локального эмулятора OpenWeatherMap и WeatherAPI",
+		Long: "Эмулирует эндпоинты /data/2.5/weather и /v1/current.json по сценарию.\n" +
+			"Укажите OPENWEATHER_BASE_URL и WEATHERAPI_BASE_URL на адрес эмулятора,\n" +
+			"чтобы работать без ключей и сети.",
+		// Эмулятору не нужны ключи API и агрегатор
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			scenario, _ := cmd.Flags().GetString("scenario")
+			addr, _ := cmd.Flags().GetString("addr")
+
+			startMockUpstream(scenario, addr)
+		},
+	}
+
+	mockUpstreamCmd.Flags().StringP("scenario", "s", "", "Путь к JSON файлу сценария")
+	mockUpstreamCmd.Flags().StringP("addr", "a", ":9090", "Адрес для прослушивания")
+
+	rootCmd.AddCommand(serverCmd, getCmd, providersCmd, clearCacheCmd, mockUpstreamCmd, newAdminCmd(), newAstroCmd(), newHistoryCmd(), newScoresCmd(), newWatchCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -106,15 +126,309 @@ func main() {
 	}
 }
 
+// setup загружает конфигурацию и создает агрегатор с провайдерами
+func setup() {
+	// Загружаем конфигурацию
+	var err error
+	cfg, err = config.Load()
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+
+	// Создаем агрегатор
+	agg = aggregator.NewAggregator(cfg.CacheDuration)
+	geocoder = geo.NewDefault(cfg.GeocoderURL)
+
+	// Декоратор сбоев для проверки устойчивости: подключается ближе всего к
+	// провайдеру, чтобы логирование и замеры видели внедренные сбои
+	var inner []providers.Middleware
+	if cfg.ChaosEnabled {
+		chaosCtl = chaos.NewController()
+		if cfg.ChaosConfig != "" {
+			if err := chaosCtl.LoadFile(cfg.ChaosConfig); err != nil {
+				log.Fatalf("Ошибка загрузки конфигурации chaos: %v", err)
+			}
+		}
+		inner = append(inner, chaosCtl.Middleware())
+		log.Printf("Внимание: включено внедрение сбоев в провайдеры (CHAOS_ENABLED)")
+	}
+
+	// Добавляем провайдеры
+	if cfg.OpenWeatherAPIKey != "" {
+		agg.AddProvider(providers.NewOpenWeatherProvider(cfg.OpenWeatherAPIKey).WithBaseURL(cfg.OpenWeatherURL).WithOneCall(cfg.OpenWeatherOneCall),
+			providerMiddlewares("openweather", inner)...)
+		log.Printf("Провайдер OpenWeatherMap добавлен")
+	}
+
+	if cfg.WeatherAPIKey != "" {
+		agg.AddProvider(providers.NewWeatherAPIProvider(cfg.WeatherAPIKey).WithBaseURL(cfg.WeatherAPIURL),
+			providerMiddlewares("weatherapi", inner)...)
+		log.Printf("Провайдер WeatherAPI добавлен")
+	}
+
+	if cfg.MetNoUserAgent != "" {
+		agg.AddProvider(providers.NewMetNoProvider(cfg.MetNoUserAgent, geocoder).WithBaseURL(cfg.MetNoURL),
+			providerMiddlewares("metno", inner)...)
+		log.Printf("Провайдер MET Norway добавлен")
+	}
+
+	if cfg.NWSUserAgent != "" {
+		gridTTL := time.Duration(cfg.NWSGridCacheHours) * time.Hour
+		agg.AddProvider(providers.NewNWSProvider(cfg.NWSUserAgent, geocoder, gridTTL).WithBaseURL(cfg.NWSURL),
+			providerMiddlewares("nws", inner)...)
+		log.Printf("Провайдер NWS добавлен")
+	}
+
+	if cfg.METAREnabled {
+		agg.AddProvider(providers.NewMETARProvider(geocoder, cfg.METARSourceURL, cfg.METARMaxDistance),
+			providerMiddlewares("metar", inner)...)
+		log.Printf("Провайдер METAR добавлен")
+	}
+
+	// Предупреждения об опасной погоде: провайдеры, которые их поддерживают,
+	// подключаются автоматически, ленты CAP - отдельными источниками
+	agg.SetAlertsEnabled(cfg.AlertsEnabled)
+	if cfg.AlertsEnabled {
+		for _, feedURL := range cfg.CAPFeedURLs {
+			agg.AddAlertSource(providers.NewCAPSource(feedURL, cfg.CAPLanguage, geocoder))
+			log.Printf("Лента предупреждений CAP %s добавлена", feedURL)
+		}
+	}
+
+	agg.SetIconBaseURL(cfg.IconBaseURL)
+	agg.SetGeocoder(geocoder)
+
+	// История: каждый свежий результат и ответы провайдеров
+	if cfg.HistoryDir != "" {
+		store, err = history.Open(cfg.HistoryDir, history.Options{
+			Retention:       time.Duration(cfg.HistoryRetention) * 24 * time.Hour,
+			DownsampleAfter: time.Duration(cfg.HistoryDownsample) * time.Hour,
+			DownsampleStep:  time.Duration(cfg.HistoryStep) * time.Minute,
+		})
+		if err != nil {
+			log.Fatalf("Ошибка открытия истории: %v", err)
+		}
+		agg.SetRecorder(store)
+		// Журнал изменений провайдеров переживает перезапуск
+		eventLog := aggregator.NewFileEventLog(filepath.Join(cfg.HistoryDir, "provider_events.jsonl"))
+		if err := agg.SetEventLog(eventLog); err != nil {
+			log.Fatalf("Ошибка загрузки журнала провайдеров: %v", err)
+		}
+		scorer = verification.NewScorer(store, verification.Options{
+			Window: time.Duration(cfg.ScoresWindow) * 24 * time.Hour,
+		})
+	}
+
+	// Взвешенная стратегия: веса провайдеров по точности их прогнозов
+	switch cfg.Strategy {
+	case aggregator.StrategyMean:
+	case aggregator.StrategyWeighted:
+		if scorer == nil {
+			log.Fatalf("Стратегия %s требует HISTORY_DIR: веса рассчитываются по истории прогнозов", cfg.Strategy)
+		}
+		agg.SetWeigher(scorer)
+		if cfg.ForecastInterval <= 0 {
+			log.Printf("FORECAST_INTERVAL_MINUTES не задан: новые прогнозы не сохраняются, " +
+				"веса рассчитываются только по уже сохраненным")
+		}
+	default:
+		log.Fatalf("Неизвестная стратегия агрегации %q: ожидается %s или %s",
+			cfg.Strategy, aggregator.StrategyMean, aggregator.StrategyWeighted)
+	}
+
+	// Поправка систематических ошибок провайдеров относительно эталона
+	if cfg.BiasReference != "" {
+		if store == nil {
+			log.Fatalf("Поправка относительно %s требует HISTORY_DIR: расхождения рассчитываются по истории", cfg.BiasReference)
+		}
+		agg.SetCorrector(correction.NewCorrector(store, cfg.BiasReference, correction.Options{
+			Window:     time.Duration(cfg.BiasWindow) * 24 * time.Hour,
+			MinSamples: cfg.BiasMinSamples,
+		}))
+		log.Printf("Поправка провайдеров относительно %s включена", cfg.BiasReference)
+	}
+
+	// Климатические нормы: загруженные из CSV, для остальных локаций и
+	// величин - рассчитанные по истории
+	var normals *climate.Table
+	if cfg.ClimateNormals != "" {
+		normals, err = climate.LoadCSV(cfg.ClimateNormals)
+		if err != nil {
+			log.Fatalf("Ошибка загрузки климатических норм: %v", err)
+		}
+		log.Printf("Загружено климатических норм: %d", normals.Len())
+	}
+	normalsHistory := store
+	if !cfg.ClimateFromHistory {
+		normalsHistory = nil
+	}
+	if normalsHistory != nil && cfg.HistoryRetention > 0 && cfg.HistoryRetention <= 365 {
+		log.Fatalf("Нормы по истории требуют HISTORY_RETENTION_DAYS больше 365 (задано %d): "+
+			"норма считается по прошлым годам", cfg.HistoryRetention)
+	}
+	if normals != nil || normalsHistory != nil {
+		agg.SetClimatology(climate.New(normals, normalsHistory, climate.Options{
+			MinDays:  cfg.ClimateMinDays,
+			MinYears: cfg.ClimateMinYears,
+		}))
+	}
+}
+
+// compactHistory раз в час удаляет устаревшую историю и прореживает старую
+func compactHistory(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		stats, err := store.Compact(time.Now())
+		if err != nil {
+			log.Printf("Ошибка обслуживания истории: %v", err)
+		} else if stats.Removed > 0 || stats.Downsampled > 0 {
+			log.Printf("История: удалено суток %d, прорежено %d", stats.Removed, stats.Downsampled)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// setupScheduler создает планировщик обновления отслеживаемых городов.
+// Обновленные результаты попадают в кеш и историю через Aggregator.Refresh.
+func setupScheduler() {
+	locations, err := scheduler.ParseLocations(cfg.WatchLocations, "RU")
+	if err != nil {
+		log.Fatalf("Ошибка в WATCH_LOCATIONS: %v", err)
+	}
+	if cfg.WatchInterval <= 0 {
+		log.Fatalf("WATCH_INTERVAL_MINUTES должен быть больше нуля")
+	}
+
+	interval := time.Duration(cfg.WatchInterval) * time.Minute
+	watcher = scheduler.New(agg, locations, interval)
+	log.Printf("Планировщик: отслеживается городов: %d, обновление раз в %s", len(locations), interval)
+}
+
+// providerMiddlewares собирает цепочку middleware провайдера из конфигурации
+func providerMiddlewares(provider string, inner []providers.Middleware) []providers.Middleware {
+	mws, err := providers.MiddlewaresByName(cfg.MiddlewaresFor(provider))
+	if err != nil {
+		log.Fatalf("Ошибка настройки middleware провайдера %s: %v", provider, err)
+	}
+	return append(mws, inner...)
+}
+
+// setupStations подключает эндпоинты загрузки WU/Ecowitt и добавляет
+// провайдер для каждой станции. Показания приходят только в сервер,
+// поэтому в остальных командах станции не участвуют.
+func setupStations(mux *http.ServeMux) {
+	pwsCfg, err := pws.LoadConfig(cfg.PWSConfig)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации станций: %v", err)
+	}
+
+	var inner []providers.Middleware
+	if chaosCtl != nil {
+		inner = append(inner, chaosCtl.Middleware())
+	}
+
+	store := pws.NewStore(pwsCfg.Stations)
+	store.Register(mux)
+
+	for _, station := range pwsCfg.Stations {
+		agg.AddProvider(providers.NewStationProvider(store, station, pwsCfg.MaxAge(), geocoder),
+			providerMiddlewares("pws", inner)...)
+		log.Printf("Провайдер метеостанции %s добавлен", station.ID)
+	}
+}
+
+// setupSensors подписывается на топики MQTT датчиков и добавляет провайдер
+// для каждой локации
+func setupSensors(ctx context.Context) {
+	sensorsCfg, err := sensors.LoadConfig(cfg.MQTTConfig)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации датчиков: %v", err)
+	}
+
+	var inner []providers.Middleware
+	if chaosCtl != nil {
+		inner = append(inner, chaosCtl.Middleware())
+	}
+
+	store := sensors.NewStore(sensorsCfg.Topics)
+	go sensors.Run(ctx, sensorsCfg, store)
+
+	for _, location := range sensorsCfg.Locations {
+		agg.AddProvider(providers.NewSensorProvider(store, location, sensorsCfg.MaxAge(), geocoder),
+			providerMiddlewares("mqtt", inner)...)
+		log.Printf("Провайдер MQTT датчиков %s добавлен", location.ID)
+	}
+}
+
 // startServer запускает HTTP сервер
 func startServer() {
 	mux := http.NewServeMux()
 
 	// Маршруты API
 	mux.HandleFunc("/api/weather", weatherHandler)
+	mux.HandleFunc("GET /api/alerts", alertsHandler)
 	mux.HandleFunc("/api/health", healthHandler)
+	mux.Handle("GET /icons/", icons.Handler())
 	mux.HandleFunc("/", homeHandler)
 
+	// Админские маршруты
+	if cfg.AdminToken == "" {
+		log.Printf("ADMIN_TOKEN не задан: эндпоинты /admin/* отвечают 403")
+	}
+	mux.HandleFunc("GET /admin/providers", adminOnly(providersAdminHandler))
+	mux.HandleFunc("POST /admin/providers/{name}/{action}", adminOnly(providerActionHandler))
+	mux.HandleFunc("DELETE /admin/providers/{name}", adminOnly(providerActionHandler))
+	if chaosCtl != nil {
+		mux.HandleFunc("/admin/chaos", adminOnly(chaosHandler))
+	}
+
+	// Ряды из истории погоды и точность прогнозов. Прогнозы сохраняются только
+	// сервером: CLI завершается раньше, чем они будут получены.
+	if store != nil {
+		mux.HandleFunc("GET /api/history", historyHandler)
+		mux.HandleFunc("GET /api/scores", scoresHandler)
+		if cfg.ForecastInterval > 0 {
+			agg.SetForecastRecording(store, time.Duration(cfg.ForecastInterval)*time.Minute,
+				time.Duration(cfg.ForecastMaxLead)*time.Hour)
+		}
+	}
+
+	// Фоновое обновление отслеживаемых городов
+	if len(cfg.WatchLocations) > 0 {
+		setupScheduler()
+		mux.HandleFunc("GET /admin/scheduler", adminOnly(schedulerHandler))
+	}
+
+	// Прием данных от собственных метеостанций
+	if cfg.PWSConfig != "" {
+		setupStations(mux)
+	}
+
+	// Фоновые задачи останавливаются вместе с сервером
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Подписка на MQTT датчики
+	if cfg.MQTTConfig != "" {
+		setupSensors(backgroundCtx)
+	}
+
+	// Обслуживание истории: срок хранения и прореживание
+	if store != nil {
+		go compactHistory(backgroundCtx)
+	}
+
+	if watcher != nil {
+		go watcher.Run(backgroundCtx)
+	}
+
 	// Статические файлы (опционально)
 	fs := http.FileServer(http.Dir("./static"))
 	mux.Handle("/static/", http.StripPrefix("/static/", fs))
@@ -188,6 +502,112 @@ func weatherHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(weather)
 }
 
+// alertsHandler обработчик запроса предупреждений об опасной погоде
+func alertsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	city := r.URL.Query().Get("city")
+	country := r.URL.Query().Get("country")
+
+	if city == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ErrorResponse{
+			Error: "Не указан город",
+		})
+		return
+	}
+
+	if country == "" {
+		country = "RU"
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	alerts, err := agg.GetAlerts(ctx, city, country)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(models.ErrorResponse{
+			Error:   "Не удалось получить предупреждения",
+			Details: err.Error(),
+		})
+		return
+	}
+	if alerts == nil {
+		alerts = []models.Alert{}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"city":    city,
+		"country": country,
+		"alerts":  alerts,
+	})
+}
+
+// historyHandler обработчик запроса ряда из истории погоды
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	params := r.URL.Query()
+	city := params.Get("city")
+	if city == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "Не указан город",
+		})
+		return
+	}
+
+	q, err := parseHistoryQuery(city, params.Get("country"), params.Get("from"), params.Get("to"),
+		params.Get("step"), params.Get("fields"), time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Некорректные параметры",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	resp, err := querySeries(store, q)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Не удалось прочитать историю",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// scoresHandler обработчик запроса точности прогнозов провайдеров
+func scoresHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	city := r.URL.Query().Get("city")
+	country := r.URL.Query().Get("country")
+	if city == "" {
+		writeJSON(w, http.StatusBadRequest, models.ErrorResponse{
+			Error: "Не указан город",
+		})
+		return
+	}
+	if country == "" {
+		country = "RU"
+	}
+
+	scores, err := scorer.Scores(city, country, time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Не удалось рассчитать точность прогнозов",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, filterScores(scores, r.URL.Query().Get("field")))
+}
+
 // healthHandler проверка здоровья сервиса
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -197,6 +617,7 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 		"timestamp":      time.Now().Format(time.RFC3339),
 		"providers":      agg.GetProviderCount(),
 		"provider_names": agg.GetProvidersInfo(),
+		"timings":        providers.DefaultTimingStats.Snapshot(),
 	})
 }
 
@@ -229,7 +650,11 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
                 <h3>API Endpoints:</h3>
                 <ul>
                     <li><code>GET /api/weather?city=Москва&country=RU</code> - получить погоду</li>
+                    <li><code>GET /api/alerts?city=Москва&country=RU</code> - предупреждения об опасной погоде</li>
+                    <li><code>GET /api/history?city=Москва&from=7d&step=1h</code> - история погоды (при заданном HISTORY_DIR)</li>
+                    <li><code>GET /api/scores?city=Москва</code> - точность прогнозов провайдеров (при заданном HISTORY_DIR)</li>
                     <li><code>GET /api/health</code> - проверка здоровья сервиса</li>
+                    <li><code>GET /icons/{явление}.svg</code> - иконки явлений погоды (поле <code>icon_url</code>)</li>
                 </ul>
             </div>
             
@@ -260,17 +685,132 @@ func getWeatherCLI(city, country, output string) {
 	// Текстовый вывод
 	fmt.Printf("🌤️  Погода в %s\n", weather.Location)
 	fmt.Println(strings.Repeat("=", 40))
-	fmt.Printf("Температура: %.1f°C (мин: %.1f°C, макс: %.1f°C)\n",
-		weather.Temperature.Average, weather.Temperature.Min, weather.Temperature.Max)
-	fmt.Printf("Ощущается как: %.1f°C\n", weather.FeelsLike.Average)
-	fmt.Printf("Влажность: %.0f%%\n", weather.Humidity.Average)
-	fmt.Printf("Давление: %.0f hPa\n", weather.Pressure.Average)
-	fmt.Printf("Скорость ветра: %.1f м/с\n", weather.WindSpeed.Average)
+	// Значения, которые не сообщил ни один источник, не выводятся
+	if t := weather.Temperature; t != nil {
+		fmt.Printf("Температура: %.1f°C (мин: %.1f°C, макс: %.1f°C, источников: %d)\n",
+			t.Average, t.Min, t.Max, t.Count)
+	}
+	if weather.FeelsLike != nil {
+		fmt.Printf("Ощущается как: %.1f°C", weather.FeelsLike.Average)
+		if derived := weather.Derived; derived != nil && derived.ApparentTemperature != nil {
+			fmt.Printf(" (по Стедману: %.1f°C)", derived.ApparentTemperature.Value)
+		}
+		fmt.Println()
+	}
+	if derived := weather.Derived; derived != nil {
+		if derived.WindChill != nil {
+			fmt.Printf("Ветро-холодовой индекс: %.1f°C\n", derived.WindChill.Value)
+		}
+		if derived.HeatIndex != nil {
+			fmt.Printf("Индекс жары: %.1f°C\n", derived.HeatIndex.Value)
+		}
+	}
+	if weather.Humidity != nil {
+		fmt.Printf("Влажность: %.0f%%\n", weather.Humidity.Average)
+	}
+	if weather.Pressure != nil {
+		fmt.Printf("Давление: %.0f hPa\n", weather.Pressure.Average)
+	}
+	if weather.WindSpeed != nil {
+		fmt.Printf("Скорость ветра: %.1f м/с\n", weather.WindSpeed.Average)
+	}
+	if weather.WindGust != nil {
+		fmt.Printf("Порывы ветра: %.1f м/с\n", weather.WindGust.Average)
+	}
+	if weather.DewPoint != nil {
+		fmt.Printf("Точка росы: %.1f°C\n", weather.DewPoint.Average)
+	}
+	if weather.CloudCover != nil {
+		fmt.Printf("Облачность: %.0f%%\n", weather.CloudCover.Average)
+	}
+	if weather.Visibility != nil {
+		fmt.Printf("Видимость: %.1f км\n", weather.Visibility.Average)
+	}
+	if weather.UVIndex != nil {
+		fmt.Printf("УФ индекс: %.1f\n", weather.UVIndex.Average)
+	}
+	if weather.Precipitation != nil {
+		fmt.Printf("Осадки: %.1f мм/ч\n", weather.Precipitation.Average)
+	}
 	fmt.Printf("Описание: %s\n", weather.Description)
+	for _, anomaly := range weather.Anomalies {
+		printAnomaly(anomaly)
+	}
+	if aq := weather.AirQuality; aq != nil {
+		var parts []string
+		switch {
+		case aq.USEPA != nil:
+			parts = append(parts, fmt.Sprintf("AQI %d (%s)", aq.USEPA.Value, aq.USEPA.Category))
+		case aq.AQI != nil:
+			// Концентраций нет, индекс сообщили сами провайдеры
+			parts = append(parts, fmt.Sprintf("AQI %.0f", aq.AQI.Average))
+		}
+		if aq.CAQI != nil {
+			parts = append(parts, fmt.Sprintf("CAQI %d (%s)", aq.CAQI.Value, aq.CAQI.Category))
+		}
+		if aq.PM25 != nil && aq.PM10 != nil {
+			parts = append(parts, fmt.Sprintf("PM2.5 %.1f, PM10 %.1f мкг/м³", aq.PM25.Average, aq.PM10.Average))
+		}
+		if len(parts) > 0 {
+			fmt.Printf("Качество воздуха: %s\n", strings.Join(parts, ", "))
+		}
+	}
+	if astro := weather.Astronomy; astro != nil {
+		fmt.Printf("Солнце: %s, Луна: %s (%.0f%%)\n", sunSummary(astro), astro.MoonPhaseName, astro.MoonIllumination)
+	}
+	for _, alert := range weather.Alerts {
+		fmt.Printf("⚠️  %s [%s]", alert.Event, alert.Severity)
+		if !alert.Expires.IsZero() {
+			fmt.Printf(" до %s", alert.Expires.Local().Format("02.01 15:04"))
+		}
+		fmt.Printf(" (%s)\n", strings.Join(alert.Sources, ", "))
+		if alert.Headline != "" && alert.Headline != alert.Event {
+			fmt.Printf("    %s\n", alert.Headline)
+		}
+	}
 	fmt.Printf("Источники: %s\n", strings.Join(weather.Providers, ", "))
+	for _, c := range weather.Corrections {
+		fmt.Printf("  %s: %s %.1f → %.1f (поправка %+.2f относительно %s по %d наблюдениям)\n",
+			c.Provider, c.Field, c.Raw, c.Corrected, -c.Offset, c.Reference, c.Samples)
+	}
+	for _, source := range weather.Sources {
+		if source.Station != "" {
+			fmt.Printf("  %s: станция %s, наблюдение в %s\n",
+				source.Provider, source.Station, source.ObservedAt.Local().Format("15:04"))
+		}
+	}
 	fmt.Printf("Обновлено: %s\n", weather.LastUpdated.Format("15:04:05"))
 }
 
+// anomalyFields название и единицы величин в выводе аномалий
+var anomalyFields = map[string]struct{ title, unit string }{
+	"temperature": {"температура", "°C"},
+	"humidity":    {"влажность", "%"},
+	"pressure":    {"давление", " hPa"},
+	"wind_speed":  {"ветер", " м/с"},
+}
+
+var monthNames = []string{"январь", "февраль", "март", "апрель", "май", "июнь",
+	"июль", "август", "сентябрь", "октябрь", "ноябрь", "декабрь"}
+
+// printAnomaly выводит отклонение величины от нормы
+func printAnomaly(anomaly models.Anomaly) {
+	field := anomalyFields[anomaly.Field]
+	period := anomaly.Period
+	if month, day, ok := strings.Cut(period, "-"); ok {
+		period = "на " + day + "." + month
+	} else if m, err := strconv.Atoi(period); err == nil && m >= 1 && m <= 12 {
+		period = "за " + monthNames[m-1]
+	}
+
+	fmt.Printf("Отклонение от нормы: %s %+.1f%s (норма %.1f%s %s", field.title,
+		anomaly.Deviation, field.unit, anomaly.Normal, field.unit, period)
+	if anomaly.Percentile != nil {
+		fmt.Printf(", процентиль %.0f, %s", *anomaly.Percentile, anomaly.Description)
+	}
+	fmt.Println(")")
+}
+
 // showProviders показывает список доступных провайдеров
 func showProviders() {
 	fmt.Println("📡 Доступные провайдеры погоды:")
@@ -288,6 +828,62 @@ func showProviders() {
 	} else {
 		fmt.Println("✗ WeatherAPI (не настроен)")
 	}
+
+	if cfg.MetNoUserAgent != "" {
+		fmt.Println("✓ MET Norway")
+	} else {
+		fmt.Println("✗ MET Norway (не настроен METNO_USER_AGENT)")
+	}
+
+	if cfg.NWSUserAgent != "" {
+		fmt.Println("✓ NWS (только США)")
+	} else {
+		fmt.Println("✗ NWS (не настроен NWS_USER_AGENT)")
+	}
+
+	if cfg.METAREnabled {
+		fmt.Println("✓ METAR")
+	} else {
+		fmt.Println("✗ METAR (не включен METAR_ENABLED)")
+	}
+}
+
+// startMockUpstream запускает эмулятор внешних API
+func startMockUpstream(scenarioPath, addr string) {
+	scenario := mockupstream.DefaultScenario()
+	if scenarioPath != "" {
+		var err error
+		scenario, err = mockupstream.LoadScenario(scenarioPath)
+		if err != nil {
+			log.Fatalf("Ошибка загрузки сценария: %v", err)
+		}
+	}
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mockupstream.NewServer(scenario),
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		log.Printf("Эмулятор внешних API запущен на %s", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Ошибка эмулятора: %v", err)
+		}
+	}()
+
+	<-quit
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatalf("Ошибка при завершении работы эмулятора: %v", err)
+	}
+
+	log.Println("Эмулятор остановлен")
 }
 
 // clearCache очищает кеш
@@ -0,0 +1,169 @@
+package metar
+
+import (
+	"strings"
+)
+
+var intensityNames = map[string]string{
+	"-":  "слабый",
+	"+":  "сильный",
+	"VC": "в окрестностях",
+}
+
+var descriptorNames = map[string]string{
+	"MI": "поземный",
+	"PR": "частичный",
+	"BC": "клочьями",
+	"DR": "поземок",
+	"BL": "метель",
+	"SH": "ливневый",
+	"TS": "гроза",
+	"FZ": "переохлажденный",
+}
+
+var phenomenonNames = map[string]string{
+	"DZ": "морось",
+	"RA": "дождь",
+	"SN": "снег",
+	"SG": "снежные зерна",
+	"IC": "ледяные иглы",
+	"PL": "ледяная крупа",
+	"GR": "град",
+	"GS": "мелкий град",
+	"UP": "осадки",
+	"BR": "дымка",
+	"FG": "туман",
+	"FU": "дым",
+	"VA": "вулканический пепел",
+	"DU": "пыль",
+	"SA": "песок",
+	"HZ": "мгла",
+	"PY": "водяная пыль",
+	"PO": "пыльные вихри",
+	"SQ": "шквал",
+	"FC": "смерч",
+	"SS": "песчаная буря",
+	"DS": "пыльная буря",
+}
+
+// Окончания названий явлений женского рода и во множественном числе: с ними
+// согласуются интенсивность и дескриптор ("слабая морось", "сильные ливни")
+var (
+	feminineNames = []string{"морось", "крупа", "дымка", "мгла", "пыль", "буря", "метель"}
+	pluralNames   = []string{"зерна", "иглы", "осадки", "вихри", "ливни"}
+)
+
+// agree согласует прилагательное мужского рода ("слабый") с названием явления
+func agree(adjective, phenomenon string) string {
+	stem, ok := strings.CutSuffix(adjective, "ый")
+	if !ok {
+		return adjective
+	}
+	for _, name := range feminineNames {
+		if strings.HasSuffix(phenomenon, name) {
+			return stem + "ая"
+		}
+	}
+	for _, name := range pluralNames {
+		if strings.HasSuffix(phenomenon, name) {
+			return stem + "ые"
+		}
+	}
+	return adjective
+}
+
+// cloudCoverRank порядок слоев облачности по балльности
+var cloudCoverRank = map[string]int{
+	"SKC": 0, "CLR": 0, "NSC": 0, "NCD": 0,
+	"FEW": 1, "SCT": 2, "BKN": 3, "OVC": 4, "VV": 5,
+}
+
+var cloudCoverNames = []string{
+	"ясно",
+	"малооблачно",
+	"переменная облачность",
+	"облачно с прояснениями",
+	"пасмурно",
+	"туман",
+}
+
+// Describe описание явления по-русски, например "слабый ливневый дождь"
+// или "гроза, сильный дождь, град"
+func (p Phenomenon) Describe() string {
+	var items []string
+	for _, code := range p.Precipitation {
+		items = append(items, phenomenonNames[code])
+	}
+	if p.Obscuration != "" {
+		items = append(items, phenomenonNames[p.Obscuration])
+	}
+	if p.Other != "" {
+		items = append(items, phenomenonNames[p.Other])
+	}
+
+	// Группа из одного дескриптора: "VCSH" - ливни в окрестностях
+	if len(items) == 0 && p.Descriptor == "SH" {
+		return strings.Join(withIntensity([]string{"ливни"}, p.Intensity), ", ")
+	}
+
+	switch p.Descriptor {
+	case "TS":
+		// Гроза - самостоятельное явление, осадки перечисляются после нее
+		return strings.Join(append([]string{"гроза"}, withIntensity(items, p.Intensity)...), ", ")
+	case "BL", "DR":
+		// Метель и поземок подразумевают снег
+		if len(p.Precipitation) == 1 && p.Precipitation[0] == "SN" && p.Obscuration == "" {
+			return strings.Join(withIntensity([]string{descriptorNames[p.Descriptor]}, p.Intensity), ", ")
+		}
+	case "BC":
+		if len(items) > 0 {
+			items[0] += " " + descriptorNames["BC"]
+		}
+		return strings.Join(withIntensity(items, p.Intensity), ", ")
+	}
+
+	if name, ok := descriptorNames[p.Descriptor]; ok && len(items) > 0 {
+		items[0] = agree(name, items[0]) + " " + items[0]
+	}
+	return strings.Join(withIntensity(items, p.Intensity), ", ")
+}
+
+// withIntensity применяет интенсивность к первому явлению группы
+func withIntensity(items []string, intensity string) []string {
+	if len(items) == 0 {
+		return items
+	}
+	switch intensity {
+	case "-", "+":
+		items[0] = agree(intensityNames[intensity], items[0]) + " " + items[0]
+	case "VC":
+		items[len(items)-1] += " " + intensityNames["VC"]
+	}
+	return items
+}
+
+// Description краткое описание погоды: явления, а при их отсутствии облачность
+func (r *Report) Description() string {
+	if len(r.Weather) > 0 {
+		descriptions := make([]string, len(r.Weather))
+		for i, p := range r.Weather {
+			descriptions[i] = p.Describe()
+		}
+		return strings.Join(descriptions, ", ")
+	}
+
+	if r.CAVOK {
+		return cloudCoverNames[0]
+	}
+
+	rank := -1
+	for _, layer := range r.Clouds {
+		if cover, ok := cloudCoverRank[layer.Cover]; ok && cover > rank {
+			rank = cover
+		}
+	}
+	if rank < 0 {
+		return ""
+	}
+	return cloudCoverNames[rank]
+}
@@ -0,0 +1,488 @@
+// Package metar декодирует авиационные сводки METAR/SPECI: ветер, видимость,
+// дальность видимости на ВПП, явления погоды, облачность, температуру и точку
+// росы, давление (QNH в hPa и altimeter в inHg), а также группы замечаний
+// RMK с уточненной температурой и давлением на уровне моря.
+package metar
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoReport возвращается для пустой сводки или сводки NIL
+var ErrNoReport = errors.New("metar: сводка отсутствует")
+
+// Коэффициенты перевода единиц
+const (
+	knotToMS   = 0.514444
+	kmhToMS    = 1 / 3.6
+	inHgToHPa  = 33.8639
+	mileToM    = 1609.344
+	footToM    = 0.3048
+	maxVisible = 10000 // "9999" и "CAVOK" означают 10 км и более
+)
+
+// Report декодированная сводка
+type Report struct {
+	Raw       string    `json:"raw"`
+	Type      string    `json:"type"` // METAR или SPECI
+	Station   string    `json:"station"`
+	Time      time.Time `json:"time"`
+	Auto      bool      `json:"auto,omitempty"`
+	Corrected bool      `json:"corrected,omitempty"`
+
+	Wind       *Wind              `json:"wind,omitempty"`
+	Visibility *Visibility        `json:"visibility,omitempty"`
+	RVR        []RunwayVisibility `json:"rvr,omitempty"`
+	Weather    []Phenomenon       `json:"weather,omitempty"`
+	Clouds     []CloudLayer       `json:"clouds,omitempty"`
+	CAVOK      bool               `json:"cavok,omitempty"`
+
+	Temperature *float64  `json:"temperature,omitempty"` // °C
+	DewPoint    *float64  `json:"dew_point,omitempty"`   // °C
+	Pressure    *Pressure `json:"pressure,omitempty"`    // QNH / altimeter
+	// SeaLevelPressure давление на уровне моря из группы SLP в замечаниях, hPa
+	SeaLevelPressure *float64 `json:"sea_level_pressure,omitempty"`
+
+	Trend    string   `json:"trend,omitempty"`
+	Remarks  string   `json:"remarks,omitempty"`
+	Unparsed []string `json:"unparsed,omitempty"`
+}
+
+// Wind ветер; скорости в м/с
+type Wind struct {
+	Direction    int     `json:"direction"` // градусы, откуда дует
+	Variable     bool    `json:"variable,omitempty"`
+	Calm         bool    `json:"calm,omitempty"`
+	Speed        float64 `json:"speed"`
+	Gust         float64 `json:"gust,omitempty"`
+	VariableFrom int     `json:"variable_from,omitempty"`
+	VariableTo   int     `json:"variable_to,omitempty"`
+	Unit         string  `json:"unit"` // единицы в исходной сводке: KT, MPS, KMH
+}
+
+// Visibility преобладающая видимость
+type Visibility struct {
+	Meters      float64 `json:"meters"`
+	LessThan    bool    `json:"less_than,omitempty"`    // M1/4SM
+	GreaterThan bool    `json:"greater_than,omitempty"` // P6SM, 9999
+}
+
+// RunwayVisibility дальность видимости на ВПП (RVR)
+type RunwayVisibility struct {
+	Runway string `json:"runway"`
+	Raw    string `json:"raw"`
+}
+
+// Phenomenon группа явлений погоды, например "-SHRA" или "+TSRAGR"
+type Phenomenon struct {
+	Raw           string   `json:"raw"`
+	Intensity     string   `json:"intensity,omitempty"` // "-", "+", "VC" или пусто (умеренная)
+	Descriptor    string   `json:"descriptor,omitempty"`
+	Precipitation []string `json:"precipitation,omitempty"`
+	Obscuration   string   `json:"obscuration,omitempty"`
+	Other         string   `json:"other,omitempty"`
+}
+
+// CloudLayer слой облачности
+type CloudLayer struct {
+	Cover  string `json:"cover"`            // FEW, SCT, BKN, OVC, VV, SKC, CLR, NSC, NCD
+	Height *int   `json:"height,omitempty"` // высота нижней границы в футах
+	Type   string `json:"type,omitempty"`   // CB или TCU
+}
+
+// HeightMeters высота нижней границы слоя в метрах
+func (c CloudLayer) HeightMeters() (float64, bool) {
+	if c.Height == nil {
+		return 0, false
+	}
+	return float64(*c.Height) * footToM, true
+}
+
+// Pressure давление в обеих принятых в сводках единицах
+type Pressure struct {
+	HPa  float64 `json:"hpa"`
+	InHg float64 `json:"inhg"`
+}
+
+var (
+	reStation     = regexp.MustCompile(`^[A-Z][A-Z0-9]{3}$`)
+	reTime        = regexp.MustCompile(`^(\d{2})(\d{2})(\d{2})Z$`)
+	reWind        = regexp.MustCompile(`^(\d{3}|VRB)(\d{2,3})(?:G(\d{2,3}))?(KT|MPS|KMH)$`)
+	reWindMissing = regexp.MustCompile(`^/{3}/{2,3}(KT|MPS|KMH)$`)
+	reWindVar     = regexp.MustCompile(`^(\d{3})V(\d{3})$`)
+	reVisMeters   = regexp.MustCompile(`^(\d{4})(NDV)?$`)
+	reVisDir      = regexp.MustCompile(`^(\d{4})(N|NE|E|SE|S|SW|W|NW)$`)
+	reVisSM       = regexp.MustCompile(`^([MP])?(\d+)?(?:(\d)/(\d{1,2}))?SM$`)
+	reVisWhole    = regexp.MustCompile(`^\d$`)
+	reRVR         = regexp.MustCompile(`^R(\d{2}[LCR]?)/(.+)$`)
+	reWeather     = regexp.MustCompile(`^(-|\+|VC)?(MI|PR|BC|DR|BL|SH|TS|FZ)?((?:DZ|RA|SN|SG|IC|PL|GR|GS|UP)*)(BR|FG|FU|VA|DU|SA|HZ|PY)?(PO|SQ|FC|SS|DS)?$`)
+	reCloud       = regexp.MustCompile(`^(FEW|SCT|BKN|OVC|VV)(\d{3}|///)(CB|TCU|///)?$`)
+	reTempDew     = regexp.MustCompile(`^(M?\d{2})/(M?\d{2}|//)?$`)
+	reQNH         = regexp.MustCompile(`^Q(\d{4})$`)
+	reAltimeter   = regexp.MustCompile(`^A(\d{4})$`)
+	reRmkTemp     = regexp.MustCompile(`^T([01])(\d{3})(?:([01])(\d{3}))?$`)
+	reRmkSLP      = regexp.MustCompile(`^SLP(\d{3})$`)
+)
+
+// Decode декодирует сводку; время привязывается к текущему месяцу
+func Decode(raw string) (*Report, error) {
+	return DecodeAt(raw, time.Now().UTC())
+}
+
+// DecodeAt декодирует сводку, восстанавливая месяц и год по опорному времени ref
+// (в сводке указаны только день, часы и минуты)
+func DecodeAt(raw string, ref time.Time) (*Report, error) {
+	raw = strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimSpace(raw), "=")), " ")
+	if raw == "" {
+		return nil, ErrNoReport
+	}
+
+	tokens := strings.Fields(raw)
+	r := &Report{Raw: raw, Type: "METAR"}
+	i := 0
+
+	if tokens[i] == "METAR" || tokens[i] == "SPECI" {
+		r.Type = tokens[i]
+		i++
+	}
+	if i < len(tokens) && tokens[i] == "COR" {
+		r.Corrected = true
+		i++
+	}
+
+	if i >= len(tokens) || !reStation.MatchString(tokens[i]) {
+		return nil, fmt.Errorf("metar: не найден код станции в %q", raw)
+	}
+	r.Station = tokens[i]
+	i++
+
+	if i >= len(tokens) {
+		return nil, fmt.Errorf("metar: нет времени наблюдения в %q", raw)
+	}
+	m := reTime.FindStringSubmatch(tokens[i])
+	if m == nil {
+		return nil, fmt.Errorf("metar: некорректное время наблюдения %q", tokens[i])
+	}
+	observed, err := observationTime(m[1], m[2], m[3], ref)
+	if err != nil {
+		return nil, err
+	}
+	r.Time = observed
+	i++
+
+	if i < len(tokens) && tokens[i] == "NIL" {
+		return nil, fmt.Errorf("%w: %s NIL", ErrNoReport, r.Station)
+	}
+
+	for ; i < len(tokens); i++ {
+		tok := tokens[i]
+
+		switch {
+		case tok == "AUTO":
+			r.Auto = true
+		case tok == "COR":
+			r.Corrected = true
+		case tok == "RMK":
+			r.Remarks = strings.Join(tokens[i+1:], " ")
+			r.decodeRemarks(tokens[i+1:])
+			return r, nil
+		case tok == "NOSIG" || tok == "BECMG" || tok == "TEMPO":
+			// Прогноз на посадку идет до замечаний
+			end := i
+			for end < len(tokens) && tokens[end] != "RMK" {
+				end++
+			}
+			r.Trend = strings.Join(tokens[i:end], " ")
+			i = end - 1
+		case tok == "CAVOK":
+			r.CAVOK = true
+			r.Visibility = &Visibility{Meters: maxVisible, GreaterThan: true}
+		case r.decodeWind(tok):
+		case reWindMissing.MatchString(tok):
+		case r.decodeWindVariable(tok):
+		case r.decodeVisibility(tokens, &i):
+		case reVisDir.MatchString(tok):
+			// Минимальная видимость по направлению: преобладающая уже учтена
+		case r.decodeRVR(tok):
+		case tok == "NSW":
+		case strings.HasPrefix(tok, "RE") && len(tok) > 2 && reWeather.MatchString(tok[2:]):
+			// Недавние явления (REРА и т.п.) на текущую погоду не влияют
+		case r.decodeCloud(tok):
+		case r.decodeTemperature(tok):
+		case r.decodePressure(tok):
+		case r.decodeWeather(tok):
+		case strings.Trim(tok, "/") == "":
+			// Отсутствующие группы автоматических станций
+		default:
+			r.Unparsed = append(r.Unparsed, tok)
+		}
+	}
+
+	return r, nil
+}
+
+// observationTime восстанавливает полную дату наблюдения по дню месяца
+func observationTime(dayStr, hourStr, minStr string, ref time.Time) (time.Time, error) {
+	day, _ := strconv.Atoi(dayStr)
+	hour, _ := strconv.Atoi(hourStr)
+	minute, _ := strconv.Atoi(minStr)
+	if day < 1 || day > 31 || hour > 23 || minute > 59 {
+		return time.Time{}, fmt.Errorf("metar: некорректное время наблюдения %s%s%sZ", dayStr, hourStr, minStr)
+	}
+
+	ref = ref.UTC()
+	t := time.Date(ref.Year(), ref.Month(), day, hour, minute, 0, 0, time.UTC)
+	// Сводка не может быть из будущего: значит, она за прошлый месяц
+	if t.After(ref.Add(24 * time.Hour)) {
+		t = time.Date(ref.Year(), ref.Month()-1, day, hour, minute, 0, 0, time.UTC)
+	}
+	return t, nil
+}
+
+func (r *Report) decodeWind(tok string) bool {
+	m := reWind.FindStringSubmatch(tok)
+	if m == nil {
+		return false
+	}
+
+	factor := knotToMS
+	switch m[4] {
+	case "MPS":
+		factor = 1
+	case "KMH":
+		factor = kmhToMS
+	}
+
+	speed, _ := strconv.Atoi(m[2])
+	w := &Wind{Speed: float64(speed) * factor, Unit: m[4]}
+	if m[1] == "VRB" {
+		w.Variable = true
+	} else {
+		w.Direction, _ = strconv.Atoi(m[1])
+	}
+	if m[3] != "" {
+		gust, _ := strconv.Atoi(m[3])
+		w.Gust = float64(gust) * factor
+	}
+	w.Calm = speed == 0 && w.Direction == 0
+
+	r.Wind = w
+	return true
+}
+
+func (r *Report) decodeWindVariable(tok string) bool {
+	m := reWindVar.FindStringSubmatch(tok)
+	if m == nil || r.Wind == nil {
+		return false
+	}
+	r.Wind.VariableFrom, _ = strconv.Atoi(m[1])
+	r.Wind.VariableTo, _ = strconv.Atoi(m[2])
+	return true
+}
+
+// decodeVisibility разбирает видимость в метрах ("0800") или милях ("1 1/2SM")
+func (r *Report) decodeVisibility(tokens []string, i *int) bool {
+	tok := tokens[*i]
+	if r.Visibility != nil {
+		return false
+	}
+
+	if m := reVisMeters.FindStringSubmatch(tok); m != nil {
+		meters, _ := strconv.Atoi(m[1])
+		r.Visibility = &Visibility{Meters: float64(meters), GreaterThan: meters == 9999}
+		if meters == 9999 {
+			r.Visibility.Meters = maxVisible
+		}
+		return true
+	}
+
+	// Целая часть миль отдельной группой: "1 1/2SM"
+	whole := 0.0
+	if reVisWhole.MatchString(tok) && *i+1 < len(tokens) && strings.HasSuffix(tokens[*i+1], "SM") {
+		whole, _ = strconv.ParseFloat(tok, 64)
+		*i++
+		tok = tokens[*i]
+	}
+
+	m := reVisSM.FindStringSubmatch(tok)
+	if m == nil || (m[2] == "" && m[3] == "") {
+		return false
+	}
+
+	miles := whole
+	if m[2] != "" {
+		n, _ := strconv.ParseFloat(m[2], 64)
+		miles += n
+	}
+	if m[3] != "" {
+		num, _ := strconv.ParseFloat(m[3], 64)
+		den, _ := strconv.ParseFloat(m[4], 64)
+		if den != 0 {
+			miles += num / den
+		}
+	}
+
+	r.Visibility = &Visibility{
+		Meters:      math.Round(miles * mileToM),
+		LessThan:    m[1] == "M",
+		GreaterThan: m[1] == "P",
+	}
+	return true
+}
+
+func (r *Report) decodeRVR(tok string) bool {
+	m := reRVR.FindStringSubmatch(tok)
+	if m == nil {
+		return false
+	}
+	r.RVR = append(r.RVR, RunwayVisibility{Runway: m[1], Raw: tok})
+	return true
+}
+
+func (r *Report) decodeWeather(tok string) bool {
+	m := reWeather.FindStringSubmatch(tok)
+	if m == nil || tok == "" {
+		return false
+	}
+	// Одна интенсивность без явления - не группа погоды
+	if m[2] == "" && m[3] == "" && m[4] == "" && m[5] == "" {
+		return false
+	}
+
+	p := Phenomenon{
+		Raw:         tok,
+		Intensity:   m[1],
+		Descriptor:  m[2],
+		Obscuration: m[4],
+		Other:       m[5],
+	}
+	for j := 0; j+2 <= len(m[3]); j += 2 {
+		p.Precipitation = append(p.Precipitation, m[3][j:j+2])
+	}
+
+	r.Weather = append(r.Weather, p)
+	return true
+}
+
+func (r *Report) decodeCloud(tok string) bool {
+	switch tok {
+	case "SKC", "CLR", "NSC", "NCD":
+		r.Clouds = append(r.Clouds, CloudLayer{Cover: tok})
+		return true
+	}
+
+	m := reCloud.FindStringSubmatch(tok)
+	if m == nil {
+		return false
+	}
+
+	layer := CloudLayer{Cover: m[1]}
+	if m[2] != "///" {
+		hundreds, _ := strconv.Atoi(m[2])
+		height := hundreds * 100
+		layer.Height = &height
+	}
+	if m[3] != "///" {
+		layer.Type = m[3]
+	}
+
+	r.Clouds = append(r.Clouds, layer)
+	return true
+}
+
+func (r *Report) decodeTemperature(tok string) bool {
+	m := reTempDew.FindStringSubmatch(tok)
+	if m == nil {
+		return false
+	}
+
+	temp := parseSigned(m[1])
+	r.Temperature = &temp
+	if m[2] != "" && m[2] != "//" {
+		dew := parseSigned(m[2])
+		r.DewPoint = &dew
+	}
+	return true
+}
+
+func (r *Report) decodePressure(tok string) bool {
+	if m := reQNH.FindStringSubmatch(tok); m != nil {
+		hpa, _ := strconv.ParseFloat(m[1], 64)
+		r.Pressure = &Pressure{HPa: hpa, InHg: round(hpa/inHgToHPa, 2)}
+		return true
+	}
+	if m := reAltimeter.FindStringSubmatch(tok); m != nil {
+		hundredths, _ := strconv.ParseFloat(m[1], 64)
+		inHg := hundredths / 100
+		r.Pressure = &Pressure{HPa: round(inHg*inHgToHPa, 1), InHg: inHg}
+		return true
+	}
+	return false
+}
+
+// decodeRemarks извлекает из замечаний уточненную температуру (T01560122)
+// и давление на уровне моря (SLP132)
+func (r *Report) decodeRemarks(tokens []string) {
+	for _, tok := range tokens {
+		if m := reRmkTemp.FindStringSubmatch(tok); m != nil {
+			temp := tenths(m[1], m[2])
+			r.Temperature = &temp
+			if m[3] != "" {
+				dew := tenths(m[3], m[4])
+				r.DewPoint = &dew
+			}
+			continue
+		}
+		if m := reRmkSLP.FindStringSubmatch(tok); m != nil {
+			// Указаны десятки, единицы и десятые доли: 132 -> 1013.2, 982 -> 998.2
+			v, _ := strconv.ParseFloat(m[1], 64)
+			slp := 1000 + v/10
+			if v >= 500 {
+				slp = 900 + v/10
+			}
+			r.SeaLevelPressure = &slp
+		}
+	}
+}
+
+// RelativeHumidity относительная влажность по температуре и точке росы (формула Магнуса)
+func (r *Report) RelativeHumidity() (float64, bool) {
+	if r.Temperature == nil || r.DewPoint == nil {
+		return 0, false
+	}
+	const a, b = 17.625, 243.04
+	t, td := *r.Temperature, *r.DewPoint
+	rh := 100 * math.Exp(a*td/(b+td)) / math.Exp(a*t/(b+t))
+	return math.Min(100, rh), true
+}
+
+// parseSigned разбирает температуру вида "M05"
+func parseSigned(s string) float64 {
+	negative := strings.HasPrefix(s, "M")
+	v, _ := strconv.ParseFloat(strings.TrimPrefix(s, "M"), 64)
+	if negative {
+		return -v
+	}
+	return v
+}
+
+// tenths разбирает группу замечаний: знак (0 или 1) и десятые доли градуса
+func tenths(sign, digits string) float64 {
+	v, _ := strconv.ParseFloat(digits, 64)
+	v /= 10
+	if sign == "1" {
+		return -v
+	}
+	return v
+}
+
+func round(v float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,426 @@
+package metar
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ref опорное время разбора: сводки ниже выпущены 15 января 2025
+var ref = time.Date(2025, 1, 15, 13, 0, 0, 0, time.UTC)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+
+		typ        string
+		station    string
+		time       time.Time
+		auto       bool
+		wind       *Wind
+		visibility *Visibility
+		cavok      bool
+		rvr        []string
+		weather    []string
+		clouds     []CloudLayer
+		temp, dew  *float64
+		pressure   *Pressure
+		slp        *float64
+		trend      string
+
+		description string
+	}{
+		{
+			name:        "VariableWindGustsMPS",
+			raw:         "METAR UUEE 151230Z 24007G12MPS 210V280 9999 -SHSN BKN016CB M08/M11 Q1021 NOSIG",
+			typ:         "METAR",
+			station:     "UUEE",
+			time:        time.Date(2025, 1, 15, 12, 30, 0, 0, time.UTC),
+			wind:        &Wind{Direction: 240, Speed: 7, Gust: 12, VariableFrom: 210, VariableTo: 280, Unit: "MPS"},
+			visibility:  &Visibility{Meters: 10000, GreaterThan: true},
+			weather:     []string{"-SHSN"},
+			clouds:      []CloudLayer{{Cover: "BKN", Height: height(1600), Type: "CB"}},
+			temp:        float(-8),
+			dew:         float(-11),
+			pressure:    &Pressure{HPa: 1021, InHg: 30.15},
+			trend:       "NOSIG",
+			description: "слабый ливневый снег",
+		},
+		{
+			name:        "StatuteMilesAltimeterRemarks",
+			raw:         "KJFK 151251Z 31015G25KT 10SM FEW050 SCT250 M02/M12 A3012 RMK AO2 SLP200 T10171117",
+			typ:         "METAR",
+			station:     "KJFK",
+			time:        time.Date(2025, 1, 15, 12, 51, 0, 0, time.UTC),
+			wind:        &Wind{Direction: 310, Speed: 15 * knotToMS, Gust: 25 * knotToMS, Unit: "KT"},
+			visibility:  &Visibility{Meters: 16093},
+			clouds:      []CloudLayer{{Cover: "FEW", Height: height(5000)}, {Cover: "SCT", Height: height(25000)}},
+			temp:        float(-1.7), // из T-группы замечаний точнее основной группы
+			dew:         float(-11.7),
+			pressure:    &Pressure{HPa: 1020, InHg: 30.12},
+			slp:         float(1020.0),
+			description: "переменная облачность",
+		},
+		{
+			name:        "FractionalMiles",
+			raw:         "KBOS 151254Z 04012KT 1 1/2SM -SN BR OVC008 M01/M02 A2985 RMK AO2 SLP108 P0002 T10061017",
+			typ:         "METAR",
+			station:     "KBOS",
+			time:        time.Date(2025, 1, 15, 12, 54, 0, 0, time.UTC),
+			wind:        &Wind{Direction: 40, Speed: 12 * knotToMS, Unit: "KT"},
+			visibility:  &Visibility{Meters: 2414},
+			weather:     []string{"-SN", "BR"},
+			clouds:      []CloudLayer{{Cover: "OVC", Height: height(800)}},
+			temp:        float(-0.6),
+			dew:         float(-1.7),
+			pressure:    &Pressure{HPa: 1010.8, InHg: 29.85},
+			slp:         float(1010.8),
+			description: "слабый снег, дымка",
+		},
+		{
+			name:        "LessThanQuarterMileRVR",
+			raw:         "SPECI KORD 151251Z 27008KT M1/4SM R10L/1800V2400FT +SN FZFG VV002 M04/M05 A2990",
+			typ:         "SPECI",
+			station:     "KORD",
+			time:        time.Date(2025, 1, 15, 12, 51, 0, 0, time.UTC),
+			wind:        &Wind{Direction: 270, Speed: 8 * knotToMS, Unit: "KT"},
+			visibility:  &Visibility{Meters: 402, LessThan: true},
+			rvr:         []string{"R10L/1800V2400FT"},
+			weather:     []string{"+SN", "FZFG"},
+			clouds:      []CloudLayer{{Cover: "VV", Height: height(200)}},
+			temp:        float(-4),
+			dew:         float(-5),
+			pressure:    &Pressure{HPa: 1012.5, InHg: 29.9},
+			description: "сильный снег, переохлажденный туман",
+		},
+		{
+			name:        "CAVOK",
+			raw:         "EDDF 151250Z 07004KT CAVOK 03/M04 Q1032 NOSIG",
+			typ:         "METAR",
+			station:     "EDDF",
+			time:        time.Date(2025, 1, 15, 12, 50, 0, 0, time.UTC),
+			wind:        &Wind{Direction: 70, Speed: 4 * knotToMS, Unit: "KT"},
+			visibility:  &Visibility{Meters: 10000, GreaterThan: true},
+			cavok:       true,
+			temp:        float(3),
+			dew:         float(-4),
+			pressure:    &Pressure{HPa: 1032, InHg: 30.47},
+			trend:       "NOSIG",
+			description: "ясно",
+		},
+		{
+			name:        "Auto",
+			raw:         "EGLL 151250Z AUTO 22015KT 9999 OVC012/// 11/09 Q1008 NOSIG",
+			typ:         "METAR",
+			station:     "EGLL",
+			time:        time.Date(2025, 1, 15, 12, 50, 0, 0, time.UTC),
+			auto:        true,
+			wind:        &Wind{Direction: 220, Speed: 15 * knotToMS, Unit: "KT"},
+			visibility:  &Visibility{Meters: 10000, GreaterThan: true},
+			clouds:      []CloudLayer{{Cover: "OVC", Height: height(1200)}},
+			temp:        float(11),
+			dew:         float(9),
+			pressure:    &Pressure{HPa: 1008, InHg: 29.77},
+			trend:       "NOSIG",
+			description: "пасмурно",
+		},
+		{
+			name:        "ThunderstormCBTCU",
+			raw:         "LFPG 151230Z 25018G30KT 3000 +TSRAGR SCT020CB BKN030TCU 18/16 Q1005 BECMG NSW",
+			typ:         "METAR",
+			station:     "LFPG",
+			time:        time.Date(2025, 1, 15, 12, 30, 0, 0, time.UTC),
+			wind:        &Wind{Direction: 250, Speed: 18 * knotToMS, Gust: 30 * knotToMS, Unit: "KT"},
+			visibility:  &Visibility{Meters: 3000},
+			weather:     []string{"+TSRAGR"},
+			clouds:      []CloudLayer{{Cover: "SCT", Height: height(2000), Type: "CB"}, {Cover: "BKN", Height: height(3000), Type: "TCU"}},
+			temp:        float(18),
+			dew:         float(16),
+			pressure:    &Pressure{HPa: 1005, InHg: 29.68},
+			trend:       "BECMG NSW",
+			description: "гроза, сильный дождь, град",
+		},
+		{
+			name:        "CalmFogRVR",
+			raw:         "UUDD 151230Z 00000MPS 0800 R14L/0800N FG VV001 M05/M05 Q1030",
+			typ:         "METAR",
+			station:     "UUDD",
+			time:        time.Date(2025, 1, 15, 12, 30, 0, 0, time.UTC),
+			wind:        &Wind{Calm: true, Unit: "MPS"},
+			visibility:  &Visibility{Meters: 800},
+			rvr:         []string{"R14L/0800N"},
+			weather:     []string{"FG"},
+			clouds:      []CloudLayer{{Cover: "VV", Height: height(100)}},
+			temp:        float(-5),
+			dew:         float(-5),
+			pressure:    &Pressure{HPa: 1030, InHg: 30.42},
+			description: "туман",
+		},
+		{
+			name:        "FreezingRainIcePellets",
+			raw:         "CYUL 151300Z 05010KT 3SM -FZRAPL BR OVC005 M02/M03 A2993 RMK NS8",
+			typ:         "METAR",
+			station:     "CYUL",
+			time:        time.Date(2025, 1, 15, 13, 0, 0, 0, time.UTC),
+			wind:        &Wind{Direction: 50, Speed: 10 * knotToMS, Unit: "KT"},
+			visibility:  &Visibility{Meters: 4828},
+			weather:     []string{"-FZRAPL", "BR"},
+			clouds:      []CloudLayer{{Cover: "OVC", Height: height(500)}},
+			temp:        float(-2),
+			dew:         float(-3),
+			pressure:    &Pressure{HPa: 1013.5, InHg: 29.93},
+			description: "слабый переохлажденный дождь, ледяная крупа, дымка",
+		},
+		{
+			name:        "VariableWindDescriptors",
+			raw:         "ULLI 151230Z VRB02MPS 1/2SM VCSH BCFG BLSN FEW010TCU M00/M01 Q1015",
+			typ:         "METAR",
+			station:     "ULLI",
+			time:        time.Date(2025, 1, 15, 12, 30, 0, 0, time.UTC),
+			wind:        &Wind{Variable: true, Speed: 2, Unit: "MPS"},
+			visibility:  &Visibility{Meters: 805},
+			weather:     []string{"VCSH", "BCFG", "BLSN"},
+			clouds:      []CloudLayer{{Cover: "FEW", Height: height(1000), Type: "TCU"}},
+			temp:        float(0),
+			dew:         float(-1),
+			pressure:    &Pressure{HPa: 1015, InHg: 29.97},
+			description: "ливни в окрестностях, туман клочьями, метель",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := DecodeAt(tt.raw, ref)
+			if err != nil {
+				t.Fatalf("DecodeAt: %v", err)
+			}
+
+			if r.Type != tt.typ || r.Station != tt.station || !r.Time.Equal(tt.time) {
+				t.Errorf("Type/Station/Time = %s/%s/%s, ожидается %s/%s/%s",
+					r.Type, r.Station, r.Time, tt.typ, tt.station, tt.time)
+			}
+			if r.Auto != tt.auto || r.CAVOK != tt.cavok {
+				t.Errorf("Auto/CAVOK = %v/%v, ожидается %v/%v", r.Auto, r.CAVOK, tt.auto, tt.cavok)
+			}
+			if r.Wind == nil {
+				t.Errorf("Wind не разобран")
+			} else if !windEqual(*r.Wind, *tt.wind) {
+				t.Errorf("Wind = %+v, ожидается %+v", *r.Wind, *tt.wind)
+			}
+			if !reflect.DeepEqual(r.Visibility, tt.visibility) {
+				t.Errorf("Visibility = %+v, ожидается %+v", r.Visibility, tt.visibility)
+			}
+
+			var rvr, weather []string
+			for _, v := range r.RVR {
+				rvr = append(rvr, v.Raw)
+			}
+			for _, p := range r.Weather {
+				weather = append(weather, p.Raw)
+			}
+			if !reflect.DeepEqual(rvr, tt.rvr) {
+				t.Errorf("RVR = %v, ожидается %v", rvr, tt.rvr)
+			}
+			if !reflect.DeepEqual(weather, tt.weather) {
+				t.Errorf("Weather = %v, ожидается %v", weather, tt.weather)
+			}
+			if !reflect.DeepEqual(r.Clouds, tt.clouds) {
+				t.Errorf("Clouds = %+v, ожидается %+v", r.Clouds, tt.clouds)
+			}
+
+			expectFloat(t, "Temperature", r.Temperature, tt.temp)
+			expectFloat(t, "DewPoint", r.DewPoint, tt.dew)
+			expectFloat(t, "SeaLevelPressure", r.SeaLevelPressure, tt.slp)
+			if !reflect.DeepEqual(r.Pressure, tt.pressure) {
+				t.Errorf("Pressure = %+v, ожидается %+v", r.Pressure, tt.pressure)
+			}
+			if r.Trend != tt.trend {
+				t.Errorf("Trend = %q, ожидается %q", r.Trend, tt.trend)
+			}
+			if len(r.Unparsed) > 0 {
+				t.Errorf("неразобранные группы: %v", r.Unparsed)
+			}
+			if got := r.Description(); got != tt.description {
+				t.Errorf("Description() = %q, ожидается %q", got, tt.description)
+			}
+		})
+	}
+}
+
+func TestDecodeWeatherGroups(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Phenomenon
+	}{
+		{"-DZ", Phenomenon{Intensity: "-", Precipitation: []string{"DZ"}}},
+		{"+SHRASN", Phenomenon{Intensity: "+", Descriptor: "SH", Precipitation: []string{"RA", "SN"}}},
+		{"FZDZ", Phenomenon{Descriptor: "FZ", Precipitation: []string{"DZ"}}},
+		{"-PL", Phenomenon{Intensity: "-", Precipitation: []string{"PL"}}},
+		{"TSGS", Phenomenon{Descriptor: "TS", Precipitation: []string{"GS"}}},
+		{"MIFG", Phenomenon{Descriptor: "MI", Obscuration: "FG"}},
+		{"DRSN", Phenomenon{Descriptor: "DR", Precipitation: []string{"SN"}}},
+		{"VCFG", Phenomenon{Intensity: "VC", Obscuration: "FG"}},
+		{"+FC", Phenomenon{Intensity: "+", Other: "FC"}},
+		{"HZ", Phenomenon{Obscuration: "HZ"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			r, err := DecodeAt("UUEE 151230Z 5000 "+tt.raw, ref)
+			if err != nil {
+				t.Fatalf("DecodeAt: %v", err)
+			}
+			if len(r.Weather) != 1 {
+				t.Fatalf("Weather = %+v, ожидается одна группа", r.Weather)
+			}
+			tt.want.Raw = tt.raw
+			if !reflect.DeepEqual(r.Weather[0], tt.want) {
+				t.Errorf("Weather = %+v, ожидается %+v", r.Weather[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := map[string]string{
+		"-PL":     "слабая ледяная крупа",
+		"+SHRASN": "сильный ливневый дождь, снег",
+		"FZDZ":    "переохлажденная морось",
+		"-FZDZ":   "слабая переохлажденная морось",
+		"+BLSN":   "сильная метель",
+		"+SHSN":   "сильный ливневый снег",
+		"-SG":     "слабые снежные зерна",
+		"TSGS":    "гроза, мелкий град",
+		"BLSN":    "метель",
+		"DRSN":    "поземок",
+		"VCFG":    "туман в окрестностях",
+		"+FC":     "сильный смерч",
+	}
+
+	for raw, want := range tests {
+		t.Run(raw, func(t *testing.T) {
+			r, err := DecodeAt("UUEE 151230Z 5000 "+raw, ref)
+			if err != nil {
+				t.Fatalf("DecodeAt: %v", err)
+			}
+			if got := r.Description(); got != want {
+				t.Errorf("Description() = %q, ожидается %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeNil(t *testing.T) {
+	for _, raw := range []string{"UUWW 151230Z NIL=", "METAR KJFK 151251Z NIL", "", "  =  "} {
+		r, err := DecodeAt(raw, ref)
+		if !errors.Is(err, ErrNoReport) {
+			t.Errorf("DecodeAt(%q) = %+v, %v; ожидается ErrNoReport", raw, r, err)
+		}
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	// Заголовок сводки (станция и время) испорчен: нужна ошибка
+	for _, raw := range []string{
+		"METAR",
+		"METAR SPECI",
+		"COR",
+		"uuee 151230Z 24007MPS",
+		"UUEE",
+		"UUEE 24007MPS 9999",
+		"UUEE 1512Z",
+		"UUEE 321230Z",
+		"UUEE 152430Z",
+		"UUEE 151260Z",
+		"12345 151230Z",
+	} {
+		t.Run(raw, func(t *testing.T) {
+			if r, err := DecodeAt(raw, ref); err == nil {
+				t.Errorf("ожидается ошибка, получено %+v", r)
+			}
+		})
+	}
+
+	// Испорченные группы после заголовка не должны приводить к панике
+	for _, raw := range []string{
+		"UUEE 151230Z 1",
+		"UUEE 151230Z 1 SM",
+		"UUEE 151230Z 1 1/0SM",
+		"UUEE 151230Z SM /SM M/SM P",
+		"UUEE 151230Z ///// //// ////// /////KT //////",
+		"UUEE 151230Z 210V280 99999KT G12KT R/ R24/ Q A T1 SLP RMK",
+		"UUEE 151230Z M/M MM/ 12/ /12 Q10 A29921 OVC OVC/// BKN0100",
+		"UUEE 151230Z RMK",
+		"UUEE 151230Z NOSIG",
+		"UUEE 151230Z TEMPO RMK T1 SLP9999 T01234",
+		"UUEE 151230Z " + strings.Repeat("+", 200),
+	} {
+		t.Run(raw, func(t *testing.T) {
+			r, err := DecodeAt(raw, ref)
+			if err != nil {
+				return
+			}
+			if r.Station != "UUEE" {
+				t.Errorf("Station = %q", r.Station)
+			}
+			_ = r.Description()
+			r.RelativeHumidity()
+		})
+	}
+}
+
+func TestObservationTimePreviousMonth(t *testing.T) {
+	// Сводка за 31-е, разобранная 1-го числа, относится к прошлому месяцу
+	r, err := DecodeAt("UUEE 312330Z 24007MPS 9999 M08/M11 Q1021", time.Date(2025, 2, 1, 0, 10, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("DecodeAt: %v", err)
+	}
+	if want := time.Date(2025, 1, 31, 23, 30, 0, 0, time.UTC); !r.Time.Equal(want) {
+		t.Errorf("Time = %s, ожидается %s", r.Time, want)
+	}
+}
+
+func TestRelativeHumidity(t *testing.T) {
+	r, err := DecodeAt("UUEE 151230Z 24007MPS 9999 M08/M11 Q1021", ref)
+	if err != nil {
+		t.Fatalf("DecodeAt: %v", err)
+	}
+	rh, ok := r.RelativeHumidity()
+	if !ok || math.Abs(rh-78.9) > 0.5 {
+		t.Errorf("RelativeHumidity() = %v, %v; ожидается около 78.9", rh, ok)
+	}
+
+	r, err = DecodeAt("EGLL 151250Z AUTO 22015KT 9999 OVC012 11/// Q1008", ref)
+	if err != nil {
+		t.Fatalf("DecodeAt: %v", err)
+	}
+	if _, ok := r.RelativeHumidity(); ok {
+		t.Error("RelativeHumidity() без точки росы должна возвращать false")
+	}
+}
+
+func windEqual(a, b Wind) bool {
+	const eps = 1e-6
+	return a.Direction == b.Direction && a.Variable == b.Variable && a.Calm == b.Calm &&
+		math.Abs(a.Speed-b.Speed) < eps && math.Abs(a.Gust-b.Gust) < eps &&
+		a.VariableFrom == b.VariableFrom && a.VariableTo == b.VariableTo && a.Unit == b.Unit
+}
+
+func expectFloat(t *testing.T, field string, got, want *float64) {
+	t.Helper()
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil:
+		t.Errorf("%s = %v, ожидается %v", field, got, want)
+	case math.Abs(*got-*want) > 1e-9:
+		t.Errorf("%s = %v, ожидается %v", field, *got, *want)
+	}
+}
+
+func float(v float64) *float64 { return &v }
+
+func height(feet int) *int { return &feet }
@@ -0,0 +1,62 @@
+package metar
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"weather-aggregator/geo"
+)
+
+// Station метеостанция аэродрома
+type Station struct {
+	ICAO    string  `json:"icao"`
+	Name    string  `json:"name"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+//go:embed stations.json
+var stationsJSON []byte
+
+var stations = mustLoadStations()
+
+func mustLoadStations() []Station {
+	var list []Station
+	if err := json.Unmarshal(stationsJSON, &list); err != nil {
+		panic(fmt.Sprintf("metar: некорректная встроенная таблица станций: %v", err))
+	}
+	return list
+}
+
+// Stations возвращает встроенную таблицу станций
+func Stations() []Station {
+	list := make([]Station, len(stations))
+	copy(list, stations)
+	return list
+}
+
+// LookupStation ищет станцию по коду ICAO
+func LookupStation(icao string) (Station, bool) {
+	for _, s := range stations {
+		if strings.EqualFold(s.ICAO, icao) {
+			return s, true
+		}
+	}
+	return Station{}, false
+}
+
+// NearestStation возвращает ближайшую к точке станцию и расстояние до нее в км
+func NearestStation(lat, lon float64) (Station, float64) {
+	var nearest Station
+	best := -1.0
+	for _, s := range stations {
+		d := geo.Distance(lat, lon, s.Lat, s.Lon)
+		if best < 0 || d < best {
+			nearest, best = s, d
+		}
+	}
+	return nearest, best
+}
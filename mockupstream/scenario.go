@@ -0,0 +1,340 @@
+package mockupstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"weather-aggregator/models"
+)
+
+// Имена эмулируемых провайдеров в сценарии
+const (
+	ProviderOpenWeather = "openweather"
+	ProviderWeatherAPI  = "weatherapi"
+	ProviderMetNo       = "metno"
+)
+
+// Scenario описывает поведение эмулируемых API
+type Scenario struct {
+	// Defaults используется для городов, которых нет в Cities.
+	// Если nil, неизвестные города возвращают "город не найден".
+	Defaults *Observation            `json:"defaults,omitempty"`
+	Cities   map[string]CityScenario `json:"cities"`
+	Rules    []Rule                  `json:"rules,omitempty"`
+}
+
+// CityScenario данные и задержка ответа для одного города
+type CityScenario struct {
+	Observation
+	Latency Duration `json:"latency,omitempty"`
+	// Координаты нужны провайдерам, которые запрашивают погоду по точке (met.no)
+	Lat float64 `json:"lat,omitempty"`
+	Lon float64 `json:"lon,omitempty"`
+	// Providers позволяет задать отдельные значения для конкретного провайдера,
+	// чтобы источники расходились между собой
+	Providers map[string]Observation `json:"providers,omitempty"`
+}
+
+// Observation значения погоды в метрических единицах
+type Observation struct {
+	Temperature   float64 `json:"temperature"`
+	FeelsLike     float64 `json:"feels_like"`
+	Humidity      int     `json:"humidity"`
+	Pressure      int     `json:"pressure"`
+	WindSpeed     float64 `json:"wind_speed"` // м/с
+	WindDirection int     `json:"wind_direction"`
+	Description   string  `json:"description"`
+	Condition     string  `json:"condition,omitempty"`   // код явления models.Condition*, по умолчанию "cloudy"
+	Symbol        string  `json:"symbol,omitempty"`      // symbol_code met.no, по умолчанию по condition
+	CloudCover    int     `json:"cloud_cover,omitempty"` // %
+	Visibility    float64 `json:"visibility,omitempty"`  // км, 0 - 10 км
+	UVIndex       float64 `json:"uv_index,omitempty"`
+	WindGust      float64 `json:"wind_gust,omitempty"`     // м/с, 0 - без порывов
+	Precipitation float64 `json:"precipitation,omitempty"` // мм за час; при температуре ниже 0 - снег
+	// AirQuality концентрации загрязнителей; nil - чистый воздух defaultAirQuality
+	AirQuality *AirQuality `json:"air_quality,omitempty"`
+	// Alerts действующие предупреждения (WeatherAPI alerts=yes и One Call)
+	Alerts []Alert `json:"alerts,omitempty"`
+}
+
+// Alert предупреждение об опасной погоде; действует с момента запроса
+// в течение For
+type Alert struct {
+	Event       string   `json:"event"`
+	Headline    string   `json:"headline,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Severity    string   `json:"severity,omitempty"` // Extreme, Severe, Moderate, Minor
+	Urgency     string   `json:"urgency,omitempty"`
+	Areas       string   `json:"areas,omitempty"` // через ";"
+	For         Duration `json:"for,omitempty"`   // по умолчанию 12 часов
+}
+
+// period возвращает интервал действия предупреждения от момента now
+func (a Alert) period(now time.Time) (time.Time, time.Time) {
+	d := time.Duration(a.For)
+	if d <= 0 {
+		d = 12 * time.Hour
+	}
+	return now, now.Add(d)
+}
+
+// AirQuality концентрации загрязнителей в мкг/м³
+type AirQuality struct {
+	PM25 float64 `json:"pm2_5"`
+	PM10 float64 `json:"pm10"`
+	O3   float64 `json:"o3"`
+	NO2  float64 `json:"no2"`
+	SO2  float64 `json:"so2"`
+	CO   float64 `json:"co"`
+}
+
+var defaultAirQuality = AirQuality{PM25: 5, PM10: 12, O3: 60, NO2: 15, SO2: 3, CO: 250}
+
+// providerCondition коды явления в API провайдеров
+type providerCondition struct {
+	OpenWeather int
+	WeatherAPI  int
+	MetNo       string
+}
+
+// providerConditions переводит общий код явления в коды провайдеров. Если у
+// провайдера нет такого явления, берется ближайшее (для met.no) или 0 - код
+// не передается.
+var providerConditions = map[string]providerCondition{
+	models.ConditionClear:        {800, 1000, "clearsky_day"},
+	models.ConditionPartlyCloudy: {802, 1003, "partlycloudy_day"},
+	models.ConditionCloudy:       {803, 1006, "cloudy"},
+	models.ConditionOvercast:     {804, 1009, "cloudy"},
+	models.ConditionHaze:         {721, 1030, "fog"},
+	models.ConditionFog:          {741, 1135, "fog"},
+	models.ConditionDrizzle:      {300, 1153, "lightrain"},
+	models.ConditionRainLight:    {500, 1183, "lightrain"},
+	models.ConditionRain:         {501, 1189, "rain"},
+	models.ConditionRainHeavy:    {502, 1195, "heavyrain"},
+	models.ConditionFreezingRain: {511, 1201, "sleet"},
+	models.ConditionSleet:        {611, 1207, "sleet"},
+	models.ConditionSnowLight:    {600, 1213, "lightsnow"},
+	models.ConditionSnow:         {601, 1219, "snow"},
+	models.ConditionSnowHeavy:    {602, 1225, "heavysnow"},
+	models.ConditionHail:         {0, 1264, "heavysleet"},
+	models.ConditionThunderstorm: {211, 1087, "rainandthunder"},
+	models.ConditionSquall:       {771, 0, "heavyrainshowersandthunder_day"},
+	models.ConditionTornado:      {781, 0, "heavyrainandthunder"},
+}
+
+// condition возвращает коды явления для провайдеров
+func (o Observation) condition() providerCondition {
+	if c, ok := providerConditions[o.Condition]; ok {
+		return c
+	}
+	return providerConditions[models.ConditionCloudy]
+}
+
+// visibility возвращает видимость в км
+func (o Observation) visibility() float64 {
+	if o.Visibility > 0 {
+		return o.Visibility
+	}
+	return 10
+}
+
+// dewPoint точка росы по формуле Магнуса; ok = false при нулевой влажности
+// (в том числе не заданной в сценарии), для которой формула не определена
+func (o Observation) dewPoint() (float64, bool) {
+	if o.Humidity <= 0 {
+		return 0, false
+	}
+	const a, b = 17.62, 243.12
+	gamma := math.Log(float64(o.Humidity)/100) + a*o.Temperature/(b+o.Temperature)
+	return math.Round(b*gamma/(a-gamma)*10) / 10, true
+}
+
+// airQuality возвращает концентрации наблюдения или значения по умолчанию
+func (o Observation) airQuality() AirQuality {
+	if o.AirQuality != nil {
+		return *o.AirQuality
+	}
+	return defaultAirQuality
+}
+
+// Rule скриптует сбой или задержку на интервале времени от старта сервера.
+// Срабатывают все подходящие правила по порядку: задержки складываются,
+// ответ дает первое правило со статусом. Запросы OpenWeatherMap по
+// координатам (качество воздуха, предупреждения) относятся к ближайшему
+// городу сценария.
+type Rule struct {
+	Provider    string   `json:"provider,omitempty"`    // openweather, weatherapi или metno; пусто - все провайдеры
+	City        string   `json:"city,omitempty"`        // пусто - все города; для metno - точка "lat,lon" с 4 знаками
+	After       Duration `json:"after,omitempty"`       // начало действия от старта
+	For         Duration `json:"for,omitempty"`         // длительность, 0 - бессрочно
+	Status      int      `json:"status,omitempty"`      // HTTP статус ошибки, 0 - без ошибки
+	Latency     Duration `json:"latency,omitempty"`     // дополнительная задержка
+	Probability float64  `json:"probability,omitempty"` // вероятность срабатывания от 0 до 1, 0 - всегда
+	RetryAfter  Duration `json:"retry_after,omitempty"` // заголовок Retry-After для 429/503
+	Message     string   `json:"message,omitempty"`     // текст ошибки OpenWeatherMap и WeatherAPI; metno отдает только статус
+}
+
+// Duration time.Duration с JSON представлением вида "10m", "250ms"
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n float64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("некорректная длительность %s", data)
+		}
+		// Число трактуем как секунды
+		*d = Duration(n * float64(time.Second))
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("некорректная длительность %q: %w", s, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// DefaultScenario сценарий по умолчанию: любой город отвечает одинаковыми данными
+func DefaultScenario() *Scenario {
+	return &Scenario{
+		Defaults: &Observation{
+			Temperature:   12.5,
+			FeelsLike:     11.0,
+			Humidity:      70,
+			Pressure:      1013,
+			WindSpeed:     3.5,
+			WindDirection: 180,
+			Description:   "облачно с прояснениями",
+		},
+		Cities: map[string]CityScenario{},
+	}
+}
+
+// LoadScenario читает сценарий из JSON файла
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения сценария: %w", err)
+	}
+
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга сценария: %w", err)
+	}
+
+	for i, rule := range s.Rules {
+		switch rule.Provider {
+		case "", ProviderOpenWeather, ProviderWeatherAPI, ProviderMetNo:
+		default:
+			return nil, fmt.Errorf("правило %d: неизвестный провайдер %q", i, rule.Provider)
+		}
+		if rule.Probability < 0 || rule.Probability > 1 {
+			return nil, fmt.Errorf("правило %d: вероятность должна быть от 0 до 1", i)
+		}
+	}
+
+	return &s, nil
+}
+
+// normalize приводит ключи городов к виду, по которому ведется поиск
+func (s *Scenario) normalize() {
+	cities := make(map[string]CityScenario, len(s.Cities))
+	for name, city := range s.Cities {
+		cities[normalizeCity(name)] = city
+	}
+	s.Cities = cities
+}
+
+// lookup возвращает данные города для провайдера
+func (s *Scenario) lookup(provider, city string) (Observation, time.Duration, bool) {
+	c, ok := s.Cities[normalizeCity(city)]
+	if !ok {
+		if s.Defaults == nil {
+			return Observation{}, 0, false
+		}
+		return *s.Defaults, 0, true
+	}
+
+	if obs, ok := c.Providers[provider]; ok {
+		return obs, time.Duration(c.Latency), true
+	}
+	return c.Observation, time.Duration(c.Latency), true
+}
+
+// coordinates возвращает координаты города из сценария (0, 0 - не заданы)
+func (s *Scenario) coordinates(city string) (float64, float64) {
+	c := s.Cities[normalizeCity(city)]
+	return c.Lat, c.Lon
+}
+
+// cityAt возвращает ближайший к точке город с координатами в пределах 0.1°;
+// при равном расстоянии - первый по имени
+func (s *Scenario) cityAt(lat, lon float64) (string, bool) {
+	best, bestDist := "", math.Inf(1)
+	for name, c := range s.Cities {
+		if (c.Lat == 0 && c.Lon == 0) || math.Abs(c.Lat-lat) > 0.1 || math.Abs(c.Lon-lon) > 0.1 {
+			continue
+		}
+		dist := math.Hypot(c.Lat-lat, c.Lon-lon)
+		if dist < bestDist || (dist == bestDist && name < best) {
+			best, bestDist = name, dist
+		}
+	}
+	return best, best != ""
+}
+
+// pointCity город, к которому правила относят запрос по координатам:
+// ближайший город сценария, иначе сама точка
+func (s *Scenario) pointCity(lat, lon float64) string {
+	if name, ok := s.cityAt(lat, lon); ok {
+		return name
+	}
+	return fmt.Sprintf("%.4f,%.4f", lat, lon)
+}
+
+// lookupPoint возвращает данные ближайшего к точке города (в пределах 0.1°)
+func (s *Scenario) lookupPoint(provider string, lat, lon float64) (Observation, time.Duration, bool) {
+	if name, ok := s.cityAt(lat, lon); ok {
+		return s.lookup(provider, name)
+	}
+	if s.Defaults == nil {
+		return Observation{}, 0, false
+	}
+	return *s.Defaults, 0, true
+}
+
+// active проверяет, действует ли правило для запроса в момент elapsed
+func (r Rule) active(provider, city string, elapsed time.Duration) bool {
+	if r.Provider != "" && r.Provider != provider {
+		return false
+	}
+	if r.City != "" && normalizeCity(r.City) != normalizeCity(city) {
+		return false
+	}
+	if elapsed < time.Duration(r.After) {
+		return false
+	}
+	if r.For > 0 && elapsed >= time.Duration(r.After)+time.Duration(r.For) {
+		return false
+	}
+	return true
+}
+
+// normalizeCity приводит "Москва,RU" и "москва" к одному ключу
+func normalizeCity(q string) string {
+	if i := strings.Index(q, ","); i >= 0 {
+		q = q[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(q))
+}
@@ -0,0 +1,44 @@
+package mockupstream
+
+import "testing"
+
+func TestLookupPointNearest(t *testing.T) {
+	scenario := &Scenario{Cities: map[string]CityScenario{
+		"химки":   {Observation: Observation{Temperature: 1}, Lat: 55.89, Lon: 37.44},
+		"москва":  {Observation: Observation{Temperature: 2}, Lat: 55.7558, Lon: 37.6173},
+		"reutov":  {Observation: Observation{Temperature: 3}, Lat: 55.76, Lon: 37.86},
+		"пушкино": {Observation: Observation{Temperature: 4}, Lat: 55.7558, Lon: 37.6173},
+		"nowhere": {Observation: Observation{Temperature: 5}},
+	}}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     float64
+		found    bool
+	}{
+		// Москва тоже в пределах 0.1°, но Химки ближе
+		{"Nearest", 55.84, 37.52, 1, true},
+		// Москва и Пушкино в одной точке: выбирается первый по имени, а не
+		// случайный при обходе map
+		{"Tie", 55.7558, 37.6173, 2, true},
+		{"Near", 55.78, 37.58, 2, true},
+		{"Outside", 10, 10, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				obs, _, found := scenario.lookupPoint(ProviderOpenWeather, tt.lat, tt.lon)
+				if found != tt.found || obs.Temperature != tt.want {
+					t.Fatalf("lookupPoint = %v, %v; ожидалось %v, %v", obs.Temperature, found, tt.want, tt.found)
+				}
+			}
+		})
+	}
+
+	defaults := &Observation{Temperature: 9}
+	scenario.Defaults = defaults
+	if obs, _, found := scenario.lookupPoint(ProviderOpenWeather, 10, 10); !found || obs.Temperature != 9 {
+		t.Errorf("вне городов ожидались значения по умолчанию, получено %v, %v", obs.Temperature, found)
+	}
+}
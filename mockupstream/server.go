@@ -0,0 +1,628 @@
+package mockupstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"weather-aggregator/astronomy"
+)
+
+// Server эмулирует эндпоинты OpenWeatherMap, WeatherAPI и met.no по сценарию
+type Server struct {
+	scenario *Scenario
+	started  time.Time
+	mux      *http.ServeMux
+}
+
+// NewServer создает эмулятор; отсчет времени правил начинается с момента создания
+func NewServer(scenario *Scenario) *Server {
+	// Нормализуем ключи городов, чтобы поиск не зависел от регистра
+	scenario.normalize()
+
+	s := &Server{
+		scenario: scenario,
+		started:  time.Now(),
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/data/2.5/weather", s.openWeatherHandler)
+	s.mux.HandleFunc("/data/2.5/forecast", s.openWeatherForecastHandler)
+	s.mux.HandleFunc("/data/2.5/air_pollution", s.openWeatherAirPollutionHandler)
+	s.mux.HandleFunc("/data/3.0/onecall", s.openWeatherOneCallHandler)
+	s.mux.HandleFunc("/geo/1.0/direct", s.openWeatherGeocodingHandler)
+	s.mux.HandleFunc("/v1/current.json", s.weatherAPIHandler)
+	s.mux.HandleFunc("/v1/forecast.json", s.weatherAPIHandler)
+	s.mux.HandleFunc("/weatherapi/locationforecast/2.0/compact", s.metNoHandler)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// applyRules применяет задержки и возвращает правило со сбоем, если оно сработало
+func (s *Server) applyRules(r *http.Request, provider, city string) (*Rule, bool) {
+	elapsed := time.Since(s.started)
+
+	for i := range s.scenario.Rules {
+		rule := &s.scenario.Rules[i]
+		if !rule.active(provider, city, elapsed) {
+			continue
+		}
+		if rule.Probability > 0 && rand.Float64() >= rule.Probability {
+			continue
+		}
+
+		if rule.Latency > 0 && !sleep(r, time.Duration(rule.Latency)) {
+			return nil, false
+		}
+		if rule.Status != 0 {
+			return rule, true
+		}
+	}
+
+	return nil, true
+}
+
+// openWeatherHandler эмулирует GET /data/2.5/weather
+func (s *Server) openWeatherHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	city := r.URL.Query().Get("q")
+
+	if r.URL.Query().Get("appid") == "" {
+		writeOpenWeatherError(w, http.StatusUnauthorized, "Invalid API key.")
+		return
+	}
+
+	rule, ok := s.applyRules(r, ProviderOpenWeather, city)
+	if !ok {
+		return
+	}
+	if rule != nil {
+		setRetryAfter(w, rule)
+		writeOpenWeatherError(w, rule.Status, ruleMessage(rule))
+		return
+	}
+
+	obs, latency, found := s.scenario.lookup(ProviderOpenWeather, city)
+	if !found {
+		writeOpenWeatherError(w, http.StatusNotFound, "city not found")
+		return
+	}
+	if !sleep(r, latency) {
+		return
+	}
+
+	now := time.Now()
+	lat, lon := s.scenario.coordinates(city)
+	wind := map[string]interface{}{
+		"speed": obs.WindSpeed,
+		"deg":   obs.WindDirection,
+	}
+	if obs.WindGust > 0 {
+		wind["gust"] = obs.WindGust
+	}
+	response := map[string]interface{}{
+		"name":  cityName(city),
+		"coord": map[string]interface{}{"lat": lat, "lon": lon},
+		"main": map[string]interface{}{
+			"temp":       obs.Temperature,
+			"feels_like": obs.FeelsLike,
+			"humidity":   obs.Humidity,
+			"pressure":   obs.Pressure,
+		},
+		"wind":       wind,
+		"clouds":     map[string]interface{}{"all": obs.CloudCover},
+		"visibility": int(obs.visibility() * 1000),
+		"weather": []map[string]interface{}{
+			{"id": obs.condition().OpenWeather, "description": obs.Description, "icon": "04d"},
+		},
+		"sys": map[string]interface{}{
+			"sunrise": now.Truncate(24 * time.Hour).Add(6 * time.Hour).Unix(),
+			"sunset":  now.Truncate(24 * time.Hour).Add(18 * time.Hour).Unix(),
+		},
+		"dt":  now.Unix(),
+		"cod": 200,
+	}
+	// Для города с координатами восход и закат рассчитываются, как у настоящего API
+	if lat != 0 || lon != 0 {
+		sun := astronomy.Sun(now.In(astronomy.SolarZone(lon)), lat, lon)
+		if sun.Sunrise != nil && sun.Sunset != nil {
+			response["sys"] = map[string]interface{}{
+				"sunrise": sun.Sunrise.Unix(),
+				"sunset":  sun.Sunset.Unix(),
+			}
+		}
+	}
+	// Блоки rain и snow OpenWeatherMap присылает только при осадках
+	if obs.Precipitation > 0 {
+		kind := "rain"
+		if obs.Temperature < 0 {
+			kind = "snow"
+		}
+		response[kind] = map[string]interface{}{"1h": obs.Precipitation}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// openWeatherForecastHandler эмулирует GET /data/2.5/forecast: 5 суток с шагом
+// 3 часа. Прогноз инерционный - значения текущего наблюдения.
+func (s *Server) openWeatherForecastHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	city := r.URL.Query().Get("q")
+
+	if r.URL.Query().Get("appid") == "" {
+		writeOpenWeatherError(w, http.StatusUnauthorized, "Invalid API key.")
+		return
+	}
+
+	rule, ok := s.applyRules(r, ProviderOpenWeather, city)
+	if !ok {
+		return
+	}
+	if rule != nil {
+		setRetryAfter(w, rule)
+		writeOpenWeatherError(w, rule.Status, ruleMessage(rule))
+		return
+	}
+
+	obs, latency, found := s.scenario.lookup(ProviderOpenWeather, city)
+	if !found {
+		writeOpenWeatherError(w, http.StatusNotFound, "city not found")
+		return
+	}
+	if !sleep(r, latency) {
+		return
+	}
+
+	start := time.Now().UTC().Truncate(3 * time.Hour).Add(3 * time.Hour)
+	list := make([]map[string]interface{}, 0, 40)
+	for i := 0; i < 40; i++ {
+		list = append(list, map[string]interface{}{
+			"dt": start.Add(time.Duration(i) * 3 * time.Hour).Unix(),
+			"main": map[string]interface{}{
+				"temp":     obs.Temperature,
+				"humidity": obs.Humidity,
+				"pressure": obs.Pressure,
+			},
+			"wind": map[string]interface{}{
+				"speed": obs.WindSpeed,
+				"deg":   obs.WindDirection,
+			},
+			"weather": []map[string]interface{}{
+				{"id": obs.condition().OpenWeather, "description": obs.Description, "icon": "04d"},
+			},
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cod":  "200",
+		"cnt":  len(list),
+		"list": list,
+	})
+}
+
+// openWeatherAirPollutionHandler эмулирует GET /data/2.5/air_pollution
+func (s *Server) openWeatherAirPollutionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if r.URL.Query().Get("appid") == "" {
+		writeOpenWeatherError(w, http.StatusUnauthorized, "Invalid API key.")
+		return
+	}
+
+	lat, errLat := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	lon, errLon := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
+	if errLat != nil || errLon != nil {
+		writeOpenWeatherError(w, http.StatusBadRequest, "wrong latitude or longitude")
+		return
+	}
+
+	rule, ok := s.applyRules(r, ProviderOpenWeather, s.scenario.pointCity(lat, lon))
+	if !ok {
+		return
+	}
+	if rule != nil {
+		setRetryAfter(w, rule)
+		writeOpenWeatherError(w, rule.Status, ruleMessage(rule))
+		return
+	}
+
+	obs, latency, found := s.scenario.lookupPoint(ProviderOpenWeather, lat, lon)
+	if !found {
+		writeOpenWeatherError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if !sleep(r, latency) {
+		return
+	}
+
+	aq := obs.airQuality()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"coord": map[string]interface{}{"lat": lat, "lon": lon},
+		"list": []map[string]interface{}{
+			{
+				"dt":   time.Now().Unix(),
+				"main": map[string]interface{}{"aqi": 1},
+				"components": map[string]interface{}{
+					"co":    aq.CO,
+					"no2":   aq.NO2,
+					"o3":    aq.O3,
+					"so2":   aq.SO2,
+					"pm2_5": aq.PM25,
+					"pm10":  aq.PM10,
+				},
+			},
+		},
+	})
+}
+
+// openWeatherGeocodingHandler эмулирует GET /geo/1.0/direct по координатам
+// городов сценария
+func (s *Server) openWeatherGeocodingHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	city := r.URL.Query().Get("q")
+
+	if r.URL.Query().Get("appid") == "" {
+		writeOpenWeatherError(w, http.StatusUnauthorized, "Invalid API key.")
+		return
+	}
+
+	rule, ok := s.applyRules(r, ProviderOpenWeather, city)
+	if !ok {
+		return
+	}
+	if rule != nil {
+		setRetryAfter(w, rule)
+		writeOpenWeatherError(w, rule.Status, ruleMessage(rule))
+		return
+	}
+
+	_, latency, found := s.scenario.lookup(ProviderOpenWeather, city)
+	if !found {
+		json.NewEncoder(w).Encode([]interface{}{})
+		return
+	}
+	if !sleep(r, latency) {
+		return
+	}
+
+	lat, lon := s.scenario.coordinates(city)
+	json.NewEncoder(w).Encode([]map[string]interface{}{{
+		"name":    cityName(city),
+		"country": countryName(city),
+		"lat":     lat,
+		"lon":     lon,
+	}})
+}
+
+// openWeatherOneCallHandler эмулирует предупреждения GET /data/3.0/onecall
+func (s *Server) openWeatherOneCallHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if r.URL.Query().Get("appid") == "" {
+		writeOpenWeatherError(w, http.StatusUnauthorized, "Invalid API key.")
+		return
+	}
+
+	lat, _ := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	lon, _ := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
+
+	rule, ok := s.applyRules(r, ProviderOpenWeather, s.scenario.pointCity(lat, lon))
+	if !ok {
+		return
+	}
+	if rule != nil {
+		setRetryAfter(w, rule)
+		writeOpenWeatherError(w, rule.Status, ruleMessage(rule))
+		return
+	}
+
+	obs, latency, found := s.scenario.lookupPoint(ProviderOpenWeather, lat, lon)
+	if !found {
+		writeOpenWeatherError(w, http.StatusBadRequest, "wrong latitude")
+		return
+	}
+	if !sleep(r, latency) {
+		return
+	}
+
+	now := time.Now()
+	alerts := make([]map[string]interface{}, 0, len(obs.Alerts))
+	for _, a := range obs.Alerts {
+		start, end := a.period(now)
+		alerts = append(alerts, map[string]interface{}{
+			"sender_name": "Mock Upstream",
+			"event":       a.Event,
+			"start":       start.Unix(),
+			"end":         end.Unix(),
+			"description": a.Description,
+			"tags":        []string{},
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"lat":    lat,
+		"lon":    lon,
+		"alerts": alerts,
+	})
+}
+
+// weatherAPIHandler эмулирует GET /v1/current.json и /v1/forecast.json
+// (текущие данные, предупреждения и инерционный почасовой прогноз)
+func (s *Server) weatherAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	city := r.URL.Query().Get("q")
+
+	if r.URL.Query().Get("key") == "" {
+		writeWeatherAPIError(w, http.StatusUnauthorized, 1002, "API key is invalid or not provided.")
+		return
+	}
+
+	rule, ok := s.applyRules(r, ProviderWeatherAPI, city)
+	if !ok {
+		return
+	}
+	if rule != nil {
+		setRetryAfter(w, rule)
+		writeWeatherAPIError(w, rule.Status, 9999, ruleMessage(rule))
+		return
+	}
+
+	obs, latency, found := s.scenario.lookup(ProviderWeatherAPI, city)
+	if !found {
+		writeWeatherAPIError(w, http.StatusBadRequest, 1006, "No matching location found.")
+		return
+	}
+	if !sleep(r, latency) {
+		return
+	}
+
+	current := map[string]interface{}{
+		"last_updated_epoch": time.Now().Unix(),
+		"temp_c":             obs.Temperature,
+		"feelslike_c":        obs.FeelsLike,
+		"humidity":           obs.Humidity,
+		"pressure_mb":        float64(obs.Pressure),
+		"wind_kph":           obs.WindSpeed * 3.6,
+		"wind_degree":        obs.WindDirection,
+		"gust_kph":           obs.WindGust * 3.6,
+		"cloud":              obs.CloudCover,
+		"vis_km":             obs.visibility(),
+		"uv":                 obs.UVIndex,
+		"precip_mm":          obs.Precipitation,
+		"condition": map[string]interface{}{
+			"code": obs.condition().WeatherAPI,
+			"text": obs.Description,
+			"icon": "//cdn.weatherapi.com/weather/64x64/day/116.png",
+		},
+	}
+	if dewPoint, ok := obs.dewPoint(); ok {
+		current["dewpoint_c"] = dewPoint
+	}
+	if r.URL.Query().Get("aqi") == "yes" {
+		aq := obs.airQuality()
+		current["air_quality"] = map[string]interface{}{
+			"co":             aq.CO,
+			"no2":            aq.NO2,
+			"o3":             aq.O3,
+			"so2":            aq.SO2,
+			"pm2_5":          aq.PM25,
+			"pm10":           aq.PM10,
+			"us-epa-index":   1,
+			"gb-defra-index": 1,
+		}
+	}
+
+	response := map[string]interface{}{
+		"location": map[string]interface{}{
+			"name":    cityName(city),
+			"country": countryName(city),
+		},
+		"current": current,
+	}
+	if days, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && days > 0 {
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		forecastDays := make([]map[string]interface{}, 0, days)
+		for d := 0; d < days; d++ {
+			date := today.AddDate(0, 0, d)
+			hours := make([]map[string]interface{}, 0, 24)
+			for h := 0; h < 24; h++ {
+				hours = append(hours, map[string]interface{}{
+					"time_epoch":  date.Add(time.Duration(h) * time.Hour).Unix(),
+					"temp_c":      obs.Temperature,
+					"humidity":    obs.Humidity,
+					"pressure_mb": float64(obs.Pressure),
+					"wind_kph":    obs.WindSpeed * 3.6,
+				})
+			}
+			forecastDays = append(forecastDays, map[string]interface{}{
+				"date": date.Format(time.DateOnly),
+				"hour": hours,
+			})
+		}
+		response["forecast"] = map[string]interface{}{"forecastday": forecastDays}
+	}
+	if r.URL.Query().Get("alerts") == "yes" {
+		now := time.Now()
+		alerts := make([]map[string]interface{}, 0, len(obs.Alerts))
+		for _, a := range obs.Alerts {
+			start, end := a.period(now)
+			alerts = append(alerts, map[string]interface{}{
+				"headline":    a.Headline,
+				"msgtype":     "Alert",
+				"severity":    a.Severity,
+				"urgency":     a.Urgency,
+				"areas":       a.Areas,
+				"event":       a.Event,
+				"effective":   start.Format(time.RFC3339),
+				"expires":     end.Format(time.RFC3339),
+				"desc":        a.Description,
+				"instruction": "",
+			})
+		}
+		response["alerts"] = map[string]interface{}{"alert": alerts}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// metNoHandler эмулирует GET /weatherapi/locationforecast/2.0/compact
+func (s *Server) metNoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if r.Header.Get("User-Agent") == "" || strings.HasPrefix(r.Header.Get("User-Agent"), "Go-http-client") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	lat, errLat := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	lon, errLon := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
+	if errLat != nil || errLon != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	point := fmt.Sprintf("%.4f,%.4f", lat, lon)
+
+	rule, ok := s.applyRules(r, ProviderMetNo, point)
+	if !ok {
+		return
+	}
+	if rule != nil {
+		setRetryAfter(w, rule)
+		w.WriteHeader(rule.Status)
+		return
+	}
+
+	obs, latency, found := s.scenario.lookupPoint(ProviderMetNo, lat, lon)
+	if !found {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	if !sleep(r, latency) {
+		return
+	}
+
+	// Данные "обновляются" раз в час, как у настоящего API
+	now := time.Now().UTC()
+	updated := now.Truncate(time.Hour)
+	w.Header().Set("Last-Modified", updated.Format(http.TimeFormat))
+	w.Header().Set("Expires", now.Add(time.Minute).Format(http.TimeFormat))
+
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !updated.After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	symbol := obs.Symbol
+	if symbol == "" {
+		symbol = obs.condition().MetNo
+	}
+
+	// Почасовой инерционный прогноз на двое суток начиная с текущего часа
+	timeseries := make([]map[string]interface{}, 0, 48)
+	for h := 0; h < 48; h++ {
+		timeseries = append(timeseries, map[string]interface{}{
+			"time": updated.Add(time.Duration(h) * time.Hour).Format(time.RFC3339),
+			"data": map[string]interface{}{
+				"instant": map[string]interface{}{
+					"details": map[string]interface{}{
+						"air_pressure_at_sea_level": float64(obs.Pressure),
+						"air_temperature":           obs.Temperature,
+						"cloud_area_fraction":       float64(obs.CloudCover),
+						"relative_humidity":         float64(obs.Humidity),
+						"wind_from_direction":       float64(obs.WindDirection),
+						"wind_speed":                obs.WindSpeed,
+					},
+				},
+				"next_1_hours": map[string]interface{}{
+					"summary": map[string]interface{}{"symbol_code": symbol},
+					"details": map[string]interface{}{"precipitation_amount": obs.Precipitation},
+				},
+			},
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type": "Feature",
+		"properties": map[string]interface{}{
+			"meta": map[string]interface{}{
+				"updated_at": updated.Format(time.RFC3339),
+			},
+			"timeseries": timeseries,
+		},
+	})
+}
+
+func writeOpenWeatherError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cod":     strconv.Itoa(status),
+		"message": message,
+	})
+}
+
+func writeWeatherAPIError(w http.ResponseWriter, status, code int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+func setRetryAfter(w http.ResponseWriter, rule *Rule) {
+	if rule.RetryAfter > 0 {
+		seconds := int(time.Duration(rule.RetryAfter).Round(time.Second).Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+}
+
+func ruleMessage(rule *Rule) string {
+	if rule.Message != "" {
+		return rule.Message
+	}
+	if rule.Status == http.StatusTooManyRequests {
+		return "API calls quota exceeded"
+	}
+	return http.StatusText(rule.Status)
+}
+
+// sleep ждет d или отмены запроса клиентом
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		log.Printf("mock-upstream: клиент отменил запрос %s", r.URL.Path)
+		return false
+	}
+}
+
+// cityName возвращает название города из параметра q ("Москва,RU" -> "Москва")
+func cityName(q string) string {
+	name, _, _ := strings.Cut(q, ",")
+	return name
+}
+
+// countryName возвращает код страны из параметра q
+func countryName(q string) string {
+	_, country, _ := strings.Cut(q, ",")
+	return country
+}
@@ -0,0 +1,51 @@
+package mockupstream
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRulesReachPointRequests проверяет, что сбои и задержки из правил для
+// города действуют и на запросы OpenWeatherMap по координатам и геокодирование
+func TestRulesReachPointRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"AirPollution", "/data/2.5/air_pollution?appid=x&lat=55.7558&lon=37.6173"},
+		{"OneCall", "/data/3.0/onecall?appid=x&lat=55.7558&lon=37.6173&exclude=current"},
+		{"Geocoding", "/geo/1.0/direct?appid=x&q=Москва,RU&limit=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := &Scenario{
+				Cities: map[string]CityScenario{
+					"Москва": {Lat: 55.7558, Lon: 37.6173},
+				},
+				Rules: []Rule{
+					{Provider: ProviderOpenWeather, City: "Москва", Status: http.StatusTooManyRequests},
+				},
+			}
+			server := NewServer(scenario)
+
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusTooManyRequests {
+				t.Errorf("статус %d, ожидался 429 из правила", rec.Code)
+			}
+
+			// Задержка города тоже применяется
+			scenario.Rules = nil
+			scenario.Cities["москва"] = CityScenario{Lat: 55.7558, Lon: 37.6173, Latency: Duration(50 * time.Millisecond)}
+			start := time.Now()
+			rec = httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusOK || time.Since(start) < 50*time.Millisecond {
+				t.Errorf("статус %d за %s, ожидался 200 не быстрее задержки города", rec.Code, time.Since(start))
+			}
+		})
+	}
+}
@@ -4,35 +4,261 @@ import (
 	"time"
 )
 
-// WeatherData содержит данные о погоде
+// WeatherData содержит данные о погоде. Измерения, которые источник не
+// сообщил, равны nil и не участвуют в агрегации.
 type WeatherData struct {
-	Provider      string    `json:"provider"`
-	Location      string    `json:"location"`
-	Temperature   float64   `json:"temperature"`    // в градусах Цельсия
-	FeelsLike     float64   `json:"feels_like"`     // ощущается как
-	Humidity      int       `json:"humidity"`       // влажность %
-	Pressure      int       `json:"pressure"`       // давление в hPa
-	WindSpeed     float64   `json:"wind_speed"`     // скорость ветра м/с
-	WindDirection int       `json:"wind_direction"` // направление ветра в градусах
-	Description   string    `json:"description"`
-	Icon          string    `json:"icon"`
-	Sunrise       time.Time `json:"sunrise,omitempty"`
-	Sunset        time.Time `json:"sunset,omitempty"`
-	Timestamp     time.Time `json:"timestamp"`
-	Units         string    `json:"units"` // метрическая или имперская
+	Provider      string      `json:"provider"`
+	Location      string      `json:"location"`
+	Temperature   *float64    `json:"temperature,omitempty"`    // в градусах Цельсия
+	FeelsLike     *float64    `json:"feels_like,omitempty"`     // ощущается как
+	Humidity      *float64    `json:"humidity,omitempty"`       // влажность %
+	Pressure      *float64    `json:"pressure,omitempty"`       // давление в hPa
+	WindSpeed     *float64    `json:"wind_speed,omitempty"`     // скорость ветра м/с
+	WindDirection *float64    `json:"wind_direction,omitempty"` // направление ветра в градусах
+	Description   string      `json:"description"`
+	Condition     string      `json:"condition,omitempty"` // код явления погоды (Condition*)
+	Icon          string      `json:"icon"`
+	Sunrise       *time.Time  `json:"sunrise,omitempty"`
+	Sunset        *time.Time  `json:"sunset,omitempty"`
+	Station       string      `json:"station,omitempty"`     // станция наблюдения, если источник их различает
+	AirQuality    *AirQuality `json:"air_quality,omitempty"` // качество воздуха, если источник его сообщает
+	Timestamp     time.Time   `json:"timestamp"`             // время наблюдения или получения данных
+	Units         string      `json:"units"`                 // метрическая или имперская
+
+	// Дополнительные параметры; nil - источник их не сообщил
+	WindGust      *float64 `json:"wind_gust,omitempty"`     // порывы ветра м/с
+	DewPoint      *float64 `json:"dew_point,omitempty"`     // точка росы в градусах Цельсия
+	CloudCover    *float64 `json:"cloud_cover,omitempty"`   // облачность %
+	Visibility    *float64 `json:"visibility,omitempty"`    // видимость в км
+	UVIndex       *float64 `json:"uv_index,omitempty"`      // УФ индекс
+	Precipitation *float64 `json:"precipitation,omitempty"` // осадки за последний час, мм
+	Rain          *float64 `json:"rain,omitempty"`          // дождь за последний час, мм
+	Snow          *float64 `json:"snow,omitempty"`          // снег за последний час, мм (водный эквивалент)
+}
+
+// AirQuality концентрации загрязнителей в мкг/м³. Поля, которые источник
+// не сообщил, равны nil.
+//
+// AQI - индекс источника по шкале US EPA: если есть концентрации, он
+// рассчитывается по ним (airquality.Fill), а собственный индекс провайдера
+// отбрасывается; готовый индекс используется только у источников без
+// концентраций.
+type AirQuality struct {
+	AQI  *int     `json:"aqi,omitempty"`
+	PM25 *float64 `json:"pm2_5,omitempty"`
+	PM10 *float64 `json:"pm10,omitempty"`
+	O3   *float64 `json:"o3,omitempty"`
+	NO2  *float64 `json:"no2,omitempty"`
+	SO2  *float64 `json:"so2,omitempty"`
+	CO   *float64 `json:"co,omitempty"`
+}
+
+// AirQualityIndex значение индекса качества воздуха
+type AirQualityIndex struct {
+	Value    int    `json:"value"`
+	Category string `json:"category"`
+	Dominant string `json:"dominant,omitempty"` // загрязнитель, определивший индекс
+}
+
+// AggregatedAirQuality агрегированное качество воздуха. AQI - разброс
+// индексов US EPA отдельных источников, USEPA и CAQI рассчитываются по
+// средним концентрациям.
+type AggregatedAirQuality struct {
+	AQI   *AggregatedValue `json:"aqi,omitempty"`
+	PM25  *AggregatedValue `json:"pm2_5,omitempty"`
+	PM10  *AggregatedValue `json:"pm10,omitempty"`
+	O3    *AggregatedValue `json:"o3,omitempty"`
+	NO2   *AggregatedValue `json:"no2,omitempty"`
+	SO2   *AggregatedValue `json:"so2,omitempty"`
+	CO    *AggregatedValue `json:"co,omitempty"`
+	USEPA *AirQualityIndex `json:"us_epa,omitempty"`
+	CAQI  *AirQualityIndex `json:"caqi,omitempty"`
 }
 
 // AggregatedWeather содержит агрегированные данные
 type AggregatedWeather struct {
-	Location    string          `json:"location"`
-	Temperature AggregatedValue `json:"temperature"`
-	FeelsLike   AggregatedValue `json:"feels_like"`
-	Humidity    AggregatedValue `json:"humidity"`
-	Pressure    AggregatedValue `json:"pressure"`
-	WindSpeed   AggregatedValue `json:"wind_speed"`
-	Description string          `json:"description"`
-	Providers   []string        `json:"providers"`
-	LastUpdated time.Time       `json:"last_updated"`
+	Location    string                `json:"location"`
+	Temperature *AggregatedValue      `json:"temperature,omitempty"`
+	FeelsLike   *AggregatedValue      `json:"feels_like,omitempty"`
+	Humidity    *AggregatedValue      `json:"humidity,omitempty"`
+	Pressure    *AggregatedValue      `json:"pressure,omitempty"`
+	WindSpeed   *AggregatedValue      `json:"wind_speed,omitempty"`
+	Description string                `json:"description"`
+	Condition   string                `json:"condition,omitempty"` // согласованный код явления погоды
+	IconURL     string                `json:"icon_url,omitempty"`  // иконка явления из встроенного набора
+	Astronomy   *Astronomy            `json:"astronomy,omitempty"`
+	Derived     *Derived              `json:"derived,omitempty"` // рассчитано по средним значениям, в отличие от FeelsLike провайдеров
+	AirQuality  *AggregatedAirQuality `json:"air_quality,omitempty"`
+	Alerts      []Alert               `json:"alerts,omitempty"`
+	Providers   []string              `json:"providers"`
+	Sources     []SourceInfo          `json:"sources,omitempty"`
+	Corrections []BiasCorrection      `json:"corrections,omitempty"` // поправки значений провайдеров до агрегации
+	Anomalies   []Anomaly             `json:"anomalies,omitempty"`   // отклонения от климатической нормы
+	LastUpdated time.Time             `json:"last_updated"`
+
+	// Дополнительные параметры агрегируются только по сообщившим их источникам
+	WindGust      *AggregatedValue `json:"wind_gust,omitempty"`
+	DewPoint      *AggregatedValue `json:"dew_point,omitempty"`
+	CloudCover    *AggregatedValue `json:"cloud_cover,omitempty"`
+	Visibility    *AggregatedValue `json:"visibility,omitempty"`
+	UVIndex       *AggregatedValue `json:"uv_index,omitempty"`
+	Precipitation *AggregatedValue `json:"precipitation,omitempty"`
+	Rain          *AggregatedValue `json:"rain,omitempty"`
+	Snow          *AggregatedValue `json:"snow,omitempty"`
+}
+
+// Коды явлений погоды, общие для всех провайдеров. Провайдеры переводят в
+// них собственные коды; пустая строка - явление неизвестно.
+const (
+	ConditionClear        = "clear"
+	ConditionPartlyCloudy = "partly_cloudy"
+	ConditionCloudy       = "cloudy"
+	ConditionOvercast     = "overcast"
+	ConditionHaze         = "haze" // дымка, мгла, дым, пыль
+	ConditionFog          = "fog"
+	ConditionDrizzle      = "drizzle"
+	ConditionRainLight    = "rain_light"
+	ConditionRain         = "rain"
+	ConditionRainHeavy    = "rain_heavy"
+	ConditionFreezingRain = "freezing_rain"
+	ConditionSleet        = "sleet" // дождь со снегом, ледяная крупа
+	ConditionSnowLight    = "snow_light"
+	ConditionSnow         = "snow"
+	ConditionSnowHeavy    = "snow_heavy"
+	ConditionHail         = "hail"
+	ConditionThunderstorm = "thunderstorm"
+	ConditionSquall       = "squall"
+	ConditionTornado      = "tornado"
+)
+
+// Уровни серьезности и срочности предупреждений (значения CAP 1.2 в нижнем регистре)
+const (
+	SeverityExtreme  = "extreme"
+	SeveritySevere   = "severe"
+	SeverityModerate = "moderate"
+	SeverityMinor    = "minor"
+	SeverityUnknown  = "unknown"
+
+	UrgencyImmediate = "immediate"
+	UrgencyExpected  = "expected"
+	UrgencyFuture    = "future"
+	UrgencyPast      = "past"
+	UrgencyUnknown   = "unknown"
+)
+
+// Alert предупреждение об опасной погоде
+type Alert struct {
+	ID          string    `json:"id,omitempty"`
+	Event       string    `json:"event"`
+	Headline    string    `json:"headline,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Instruction string    `json:"instruction,omitempty"`
+	Severity    string    `json:"severity"`
+	Urgency     string    `json:"urgency"`
+	Areas       []string  `json:"areas,omitempty"`
+	Onset       time.Time `json:"onset,omitempty"`
+	Expires     time.Time `json:"expires,omitempty"`
+	Source      string    `json:"source"`            // источник, чья версия предупреждения показана
+	Sources     []string  `json:"sources,omitempty"` // все источники, сообщившие о нем
+}
+
+// Astronomy положение Солнца и Луны на сутки, рассчитанное по координатам
+// города. События, которых в эти сутки нет (полярный день, Луна не восходит),
+// отсутствуют.
+type Astronomy struct {
+	Date             string     `json:"date"` // календарная дата, ГГГГ-ММ-ДД
+	SolarNoon        time.Time  `json:"solar_noon"`
+	Sunrise          *time.Time `json:"sunrise,omitempty"`
+	Sunset           *time.Time `json:"sunset,omitempty"`
+	CivilDawn        *time.Time `json:"civil_dawn,omitempty"`
+	CivilDusk        *time.Time `json:"civil_dusk,omitempty"`
+	NauticalDawn     *time.Time `json:"nautical_dawn,omitempty"`
+	NauticalDusk     *time.Time `json:"nautical_dusk,omitempty"`
+	AstronomicalDawn *time.Time `json:"astronomical_dawn,omitempty"`
+	AstronomicalDusk *time.Time `json:"astronomical_dusk,omitempty"`
+	DayLength        float64    `json:"day_length_minutes"`
+	PolarDay         bool       `json:"polar_day,omitempty"`
+	PolarNight       bool       `json:"polar_night,omitempty"`
+
+	Moonrise         *time.Time `json:"moonrise,omitempty"`
+	Moonset          *time.Time `json:"moonset,omitempty"`
+	MoonPhase        float64    `json:"moon_phase"` // 0 - новолуние, 0.5 - полнолуние
+	MoonPhaseName    string     `json:"moon_phase_name"`
+	MoonIllumination float64    `json:"moon_illumination"` // освещенная часть диска %
+	MoonAge          float64    `json:"moon_age_days"`
+
+	// Deviations расхождения восхода и заката провайдеров с расчетом
+	Deviations []AstronomyDeviation `json:"deviations,omitempty"`
+}
+
+// Derived производные величины, рассчитанные агрегатором по средним
+// температуре, влажности и ветру. Величина вне области применимости своей
+// формулы отсутствует.
+type Derived struct {
+	DewPoint            *DerivedValue `json:"dew_point,omitempty"`
+	HeatIndex           *DerivedValue `json:"heat_index,omitempty"`
+	WindChill           *DerivedValue `json:"wind_chill,omitempty"`
+	ApparentTemperature *DerivedValue `json:"apparent_temperature,omitempty"`
+	Humidex             *DerivedValue `json:"humidex,omitempty"`
+	WetBulb             *DerivedValue `json:"wet_bulb,omitempty"`
+	AbsoluteHumidity    *DerivedValue `json:"absolute_humidity,omitempty"`
+}
+
+// DerivedValue рассчитанное значение и формула, по которой оно получено
+type DerivedValue struct {
+	Value   float64 `json:"value"`
+	Unit    string  `json:"unit"`
+	Formula string  `json:"formula"`
+}
+
+// AstronomyDeviation расхождение события, сообщенного провайдером, с расчетом
+type AstronomyDeviation struct {
+	Provider string    `json:"provider"`
+	Event    string    `json:"event"` // sunrise или sunset
+	Reported time.Time `json:"reported"`
+	Minutes  float64   `json:"minutes"` // провайдер минус расчет
+}
+
+// SourceInfo откуда и насколько свежие данные дал провайдер
+type SourceInfo struct {
+	Provider   string    `json:"provider"`
+	Station    string    `json:"station,omitempty"`
+	ObservedAt time.Time `json:"observed_at"`
+}
+
+// BiasCorrection поправка систематической ошибки провайдера: из его значения
+// до агрегации вычтено среднее расхождение с эталонным источником
+type BiasCorrection struct {
+	Provider  string  `json:"provider"`
+	Field     string  `json:"field"`
+	Raw       float64 `json:"raw"`       // значение провайдера
+	Corrected float64 `json:"corrected"` // значение, участвовавшее в агрегации
+	Offset    float64 `json:"offset"`    // среднее расхождение провайдер минус эталон
+	Samples   int     `json:"samples"`   // по скольким сопоставлениям оно рассчитано
+	Reference string  `json:"reference"` // эталонный источник
+}
+
+// Категории аномалий по процентилю значения в распределении нормы
+const (
+	AnomalyMuchBelow = "much_below" // ниже 10-го процентиля
+	AnomalyBelow     = "below"      // ниже 30-го
+	AnomalyNormal    = "normal"
+	AnomalyAbove     = "above"      // выше 70-го
+	AnomalyMuchAbove = "much_above" // выше 90-го
+)
+
+// Anomaly отклонение среднего значения величины от климатической нормы
+type Anomaly struct {
+	Field       string   `json:"field"`
+	Value       float64  `json:"value"`
+	Normal      float64  `json:"normal"`
+	Deviation   float64  `json:"deviation"`            // значение минус норма
+	Percentile  *float64 `json:"percentile,omitempty"` // процентиль значения; нет, если разброс нормы неизвестен
+	Category    string   `json:"category,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Source      string   `json:"source"`            // imported - загруженные нормы, history - рассчитаны по истории
+	Period      string   `json:"period"`            // ММ-ДД для суточной нормы, ММ для месячной
+	Samples     int      `json:"samples,omitempty"` // по скольким наблюдениям рассчитана норма по истории
 }
 
 // AggregatedValue содержит агрегированное значение
@@ -40,7 +266,74 @@ type AggregatedValue struct {
 	Average float64   `json:"average"`
 	Min     float64   `json:"min"`
 	Max     float64   `json:"max"`
+	Count   int       `json:"count"` // сколько источников сообщили значение
 	Values  []float64 `json:"values,omitempty"`
+	Weights []float64 `json:"weights,omitempty"` // веса Values во взвешенном среднем
+}
+
+// HistoryResponse ряд агрегированных значений из истории погоды
+type HistoryResponse struct {
+	City    string         `json:"city"`
+	Country string         `json:"country"`
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	Step    string         `json:"step"`
+	Fields  []string       `json:"fields"`
+	Points  []HistoryPoint `json:"points"`
+}
+
+// HistoryPoint интервал ряда: статистика величин по точкам, попавшим в
+// интервал. Time - начало интервала, Samples - число исходных точек.
+type HistoryPoint struct {
+	Time      time.Time              `json:"time"`
+	Samples   int                    `json:"samples"`
+	Condition string                 `json:"condition,omitempty"`
+	Values    map[string]HistoryStat `json:"values"`
+}
+
+// HistoryStat минимум, среднее и максимум величины за интервал
+type HistoryStat struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+}
+
+// ForecastPoint прогноз провайдера на момент времени. Величины, которые
+// провайдер не прогнозирует, равны nil.
+type ForecastPoint struct {
+	Time        time.Time `json:"time"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Humidity    *float64  `json:"humidity,omitempty"`
+	Pressure    *float64  `json:"pressure,omitempty"`
+	WindSpeed   *float64  `json:"wind_speed,omitempty"`
+}
+
+// ProviderScore ошибки прогнозов провайдера для величины и заблаговременности
+// (прогноз минус наблюдение). Lead "all" - по всем заблаговременностям.
+type ProviderScore struct {
+	Provider string  `json:"provider"`
+	Field    string  `json:"field"`
+	Lead     string  `json:"lead"`
+	Count    int     `json:"count"`
+	MAE      float64 `json:"mae"`
+	Bias     float64 `json:"bias"`
+	RMSE     float64 `json:"rmse"`
+}
+
+// ScoresResponse точность прогнозов провайдеров для города. Leaderboard
+// упорядочен по MAE внутри каждой величины.
+type ScoresResponse struct {
+	City        string          `json:"city"`
+	Country     string          `json:"country"`
+	From        time.Time       `json:"from"`
+	To          time.Time       `json:"to"`
+	Leaderboard []ProviderScore `json:"leaderboard"`
+	ByLead      []ProviderScore `json:"by_lead"`
+}
+
+// Float возвращает указатель на значение измерения
+func Float(v float64) *float64 {
+	return &v
 }
 
 // WeatherRequest запрос на получение погоды
@@ -0,0 +1,288 @@
+// Package mqtt минимальный клиент MQTT 3.1.1 для подписки на топики датчиков:
+// CONNECT, SUBSCRIBE, прием PUBLISH с QoS 0 и 1, keep-alive.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message сообщение, полученное по подписке
+type Message struct {
+	Topic    string
+	Payload  []byte
+	Retained bool
+}
+
+// Options параметры подключения
+type Options struct {
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration // 0 - 60 секунд
+}
+
+// Client соединение с брокером
+type Client struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	keepAlive time.Duration
+
+	writeMu sync.Mutex
+	nextID  uint16
+
+	// сообщения, пришедшие до подтверждения подписки
+	pending []Message
+}
+
+var connackErrors = map[byte]string{
+	1: "неподдерживаемая версия протокола",
+	2: "идентификатор клиента отклонен",
+	3: "сервер недоступен",
+	4: "неверное имя пользователя или пароль",
+	5: "нет прав на подключение",
+}
+
+// Dial подключается к брокеру. Адрес - host:port или URL со схемой
+// tcp:// (mqtt://) либо ssl:// (mqtts://, tls://).
+func Dial(ctx context.Context, addr string, opts Options) (*Client, error) {
+	if opts.KeepAlive == 0 {
+		opts.KeepAlive = 60 * time.Second
+	}
+
+	conn, err := dial(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к брокеру: %w", err)
+	}
+
+	c := &Client{
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		keepAlive: opts.KeepAlive,
+	}
+
+	if err := c.connect(ctx, opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func dial(ctx context.Context, addr string) (net.Conn, error) {
+	scheme, host, found := strings.Cut(addr, "://")
+	if !found {
+		scheme, host = "tcp", addr
+	}
+
+	switch scheme {
+	case "tcp", "mqtt":
+		if !strings.Contains(host, ":") {
+			host += ":1883"
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", host)
+	case "ssl", "tls", "mqtts":
+		if !strings.Contains(host, ":") {
+			host += ":8883"
+		}
+		d := tls.Dialer{}
+		return d.DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("неподдерживаемая схема %q", scheme)
+	}
+}
+
+func (c *Client) connect(ctx context.Context, opts Options) error {
+	flags := byte(0x02) // clean session
+	if opts.Username != "" {
+		flags |= 0x80
+		if opts.Password != "" {
+			flags |= 0x40
+		}
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive/time.Second))
+	body = appendString(body, opts.ClientID)
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+		if opts.Password != "" {
+			body = appendString(body, opts.Password)
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+		defer c.conn.SetDeadline(time.Time{})
+	}
+
+	if err := c.write(Packet{Type: TypeConnect, Body: body}.Encode()); err != nil {
+		return err
+	}
+
+	p, err := ReadPacket(c.reader)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения CONNACK: %w", err)
+	}
+	if p.Type != TypeConnack || len(p.Body) != 2 {
+		return fmt.Errorf("ожидался CONNACK, получен пакет типа %d", p.Type)
+	}
+	if code := p.Body[1]; code != 0 {
+		if reason, ok := connackErrors[code]; ok {
+			return fmt.Errorf("брокер отклонил подключение: %s", reason)
+		}
+		return fmt.Errorf("брокер отклонил подключение: код %d", code)
+	}
+	return nil
+}
+
+// Subscribe подписывается на фильтры с QoS 1 и ждет подтверждения
+func (c *Client) Subscribe(filters ...string) error {
+	c.nextID++
+	id := c.nextID
+
+	body := binary.BigEndian.AppendUint16(nil, id)
+	for _, f := range filters {
+		body = appendString(body, f)
+		body = append(body, 1)
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(c.keepAlive))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	if err := c.write(Packet{Type: TypeSubscribe, Flags: 0x02, Body: body}.Encode()); err != nil {
+		return err
+	}
+
+	for {
+		p, err := ReadPacket(c.reader)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения SUBACK: %w", err)
+		}
+
+		switch p.Type {
+		case TypePublish:
+			// Сохраненные (retained) сообщения могут прийти раньше SUBACK
+			msg, err := c.handlePublish(p)
+			if err != nil {
+				return err
+			}
+			c.pending = append(c.pending, msg)
+
+		case TypeSuback:
+			if len(p.Body) < 2 || binary.BigEndian.Uint16(p.Body) != id {
+				continue
+			}
+			for i, code := range p.Body[2:] {
+				if code == 0x80 && i < len(filters) {
+					return fmt.Errorf("брокер отклонил подписку на %s", filters[i])
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// Listen читает сообщения и передает их handler, пока соединение не
+// разорвано или не отменен ctx. Всегда возвращает ошибку.
+func (c *Client) Listen(ctx context.Context, handler func(Message)) error {
+	for _, msg := range c.pending {
+		handler(msg)
+	}
+	c.pending = nil
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.ping(ctx, done)
+
+	for {
+		// Брокер отвечает на PINGREQ, поэтому тишина дольше 1.5 интервала - обрыв
+		c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+
+		p, err := ReadPacket(c.reader)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("соединение с брокером потеряно: %w", err)
+		}
+
+		switch p.Type {
+		case TypePublish:
+			msg, err := c.handlePublish(p)
+			if err != nil {
+				return err
+			}
+			handler(msg)
+		case TypePingresp, TypeSuback, TypeUnsuback:
+		default:
+			return fmt.Errorf("неожиданный пакет типа %d", p.Type)
+		}
+	}
+}
+
+// ping отправляет PINGREQ раз в интервал keep-alive; при отмене ctx
+// закрывает соединение, чтобы прервать чтение в Listen
+func (c *Client) ping(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(c.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			c.Close()
+			return
+		case <-ticker.C:
+			if err := c.write(Packet{Type: TypePingreq}.Encode()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handlePublish разбирает PUBLISH и подтверждает его при QoS 1
+func (c *Client) handlePublish(p Packet) (Message, error) {
+	pub, err := ParsePublish(p)
+	if err != nil {
+		return Message{}, err
+	}
+
+	switch pub.QoS {
+	case 0:
+	case 1:
+		ack := Packet{Type: TypePuback, Body: binary.BigEndian.AppendUint16(nil, pub.PacketID)}
+		if err := c.write(ack.Encode()); err != nil {
+			return Message{}, err
+		}
+	default:
+		return Message{}, errors.New("mqtt: QoS 2 не поддерживается")
+	}
+
+	return Message{Topic: pub.Topic, Payload: pub.Payload, Retained: pub.Retain}, nil
+}
+
+// Close отправляет DISCONNECT и закрывает соединение
+func (c *Client) Close() error {
+	c.write(Packet{Type: TypeDisconnect}.Encode())
+	return c.conn.Close()
+}
+
+func (c *Client) write(buf []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(buf)
+	return err
+}
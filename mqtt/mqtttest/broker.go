@@ -0,0 +1,201 @@
+// Package mqtttest встроенный брокер MQTT для тестов и локальной отладки:
+// принимает подключения, подписки и публикации с QoS 0, хранит retained
+// сообщения. Аутентификация и сессии не поддерживаются.
+package mqtttest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"sync"
+
+	"weather-aggregator/mqtt"
+)
+
+// Broker брокер на локальном адресе
+type Broker struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	clients  map[*client]struct{}
+	retained map[string][]byte
+	closed   bool
+}
+
+type client struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	filters []string
+}
+
+// NewBroker запускает брокер на случайном порту 127.0.0.1
+func NewBroker() (*Broker, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Broker{
+		listener: listener,
+		clients:  make(map[*client]struct{}),
+		retained: make(map[string][]byte),
+	}
+	go b.accept()
+
+	return b, nil
+}
+
+// Addr адрес брокера в формате tcp://host:port
+func (b *Broker) Addr() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+// Publish рассылает сообщение подписчикам
+func (b *Broker) Publish(topic string, payload []byte, retain bool) {
+	b.mu.Lock()
+	if retain {
+		b.retained[topic] = payload
+	}
+	var targets []*client
+	for c := range b.clients {
+		if c.subscribed(topic) {
+			targets = append(targets, c)
+		}
+	}
+	b.mu.Unlock()
+
+	msg := mqtt.Publish{Topic: topic, Payload: payload}.Encode()
+	for _, c := range targets {
+		c.send(msg)
+	}
+}
+
+// DisconnectAll разрывает все клиентские соединения (проверка переподключения)
+func (b *Broker) DisconnectAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for c := range b.clients {
+		c.conn.Close()
+	}
+}
+
+// Close останавливает брокер
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	for c := range b.clients {
+		c.conn.Close()
+	}
+	b.mu.Unlock()
+
+	return b.listener.Close()
+}
+
+func (b *Broker) accept() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.serve(&client{conn: conn})
+	}
+}
+
+func (b *Broker) serve(c *client) {
+	defer c.conn.Close()
+	reader := bufio.NewReader(c.conn)
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.clients[c] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, c)
+		b.mu.Unlock()
+	}()
+
+	for {
+		p, err := mqtt.ReadPacket(reader)
+		if err != nil {
+			return
+		}
+
+		switch p.Type {
+		case mqtt.TypeConnect:
+			c.send(mqtt.Packet{Type: mqtt.TypeConnack, Body: []byte{0, 0}}.Encode())
+
+		case mqtt.TypePublish:
+			pub, err := mqtt.ParsePublish(p)
+			if err != nil {
+				return
+			}
+			if pub.QoS == 1 {
+				c.send(mqtt.Packet{Type: mqtt.TypePuback, Body: binary.BigEndian.AppendUint16(nil, pub.PacketID)}.Encode())
+			}
+			b.Publish(pub.Topic, pub.Payload, pub.Retain)
+
+		case mqtt.TypeSubscribe:
+			if len(p.Body) < 2 {
+				return
+			}
+			ack := append([]byte(nil), p.Body[:2]...)
+			var filters []string
+			rest := p.Body[2:]
+			for len(rest) >= 3 {
+				n := int(binary.BigEndian.Uint16(rest))
+				if len(rest) < 3+n {
+					return
+				}
+				filters = append(filters, string(rest[2:2+n]))
+				rest = rest[3+n:]
+				ack = append(ack, 0) // выдаем QoS 0
+			}
+
+			b.mu.Lock()
+			c.filters = append(c.filters, filters...)
+			var retained [][]byte
+			for topic, payload := range b.retained {
+				for _, f := range filters {
+					if mqtt.Match(f, topic) {
+						retained = append(retained, mqtt.Publish{Topic: topic, Payload: payload, Retain: true}.Encode())
+						break
+					}
+				}
+			}
+			b.mu.Unlock()
+
+			c.send(mqtt.Packet{Type: mqtt.TypeSuback, Body: ack}.Encode())
+			for _, msg := range retained {
+				c.send(msg)
+			}
+
+		case mqtt.TypePingreq:
+			c.send(mqtt.Packet{Type: mqtt.TypePingresp}.Encode())
+
+		case mqtt.TypeDisconnect:
+			return
+		}
+	}
+}
+
+// subscribed вызывается под b.mu
+func (c *client) subscribed(topic string) bool {
+	for _, f := range c.filters {
+		if mqtt.Match(f, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *client) send(buf []byte) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.Write(buf)
+}
@@ -0,0 +1,187 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Типы управляющих пакетов MQTT 3.1.1
+const (
+	TypeConnect     byte = 1
+	TypeConnack     byte = 2
+	TypePublish     byte = 3
+	TypePuback      byte = 4
+	TypeSubscribe   byte = 8
+	TypeSuback      byte = 9
+	TypeUnsubscribe byte = 10
+	TypeUnsuback    byte = 11
+	TypePingreq     byte = 12
+	TypePingresp    byte = 13
+	TypeDisconnect  byte = 14
+)
+
+// maxPacketSize ограничение размера входящего пакета
+const maxPacketSize = 1 << 20
+
+var errMalformed = errors.New("mqtt: некорректный пакет")
+
+// Packet управляющий пакет: тип, флаги фиксированного заголовка и тело
+type Packet struct {
+	Type  byte
+	Flags byte
+	Body  []byte
+}
+
+// ReadPacket читает один пакет из потока
+func ReadPacket(r *bufio.Reader) (Packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return Packet{}, err
+	}
+
+	// Оставшаяся длина: до 4 байт по 7 бит
+	var length, shift int
+	for i := 0; ; i++ {
+		if i == 4 {
+			return Packet{}, errMalformed
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return Packet{}, err
+		}
+		length |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+	}
+	if length > maxPacketSize {
+		return Packet{}, fmt.Errorf("mqtt: пакет %d байт превышает лимит", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return Packet{}, err
+	}
+
+	return Packet{Type: header >> 4, Flags: header & 0x0f, Body: body}, nil
+}
+
+// Encode сериализует пакет с фиксированным заголовком
+func (p Packet) Encode() []byte {
+	buf := []byte{p.Type<<4 | p.Flags}
+
+	length := len(p.Body)
+	for {
+		b := byte(length & 0x7f)
+		length >>= 7
+		if length > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if length == 0 {
+			break
+		}
+	}
+
+	return append(buf, p.Body...)
+}
+
+// Publish разобранный пакет PUBLISH
+type Publish struct {
+	Topic    string
+	Payload  []byte
+	QoS      byte
+	Retain   bool
+	PacketID uint16
+}
+
+// ParsePublish разбирает тело пакета PUBLISH
+func ParsePublish(p Packet) (Publish, error) {
+	pub := Publish{
+		QoS:    (p.Flags >> 1) & 0x03,
+		Retain: p.Flags&0x01 != 0,
+	}
+
+	topic, rest, err := readString(p.Body)
+	if err != nil {
+		return Publish{}, err
+	}
+	pub.Topic = topic
+
+	if pub.QoS > 0 {
+		if len(rest) < 2 {
+			return Publish{}, errMalformed
+		}
+		pub.PacketID = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	pub.Payload = rest
+
+	return pub, nil
+}
+
+// Encode сериализует PUBLISH
+func (pub Publish) Encode() []byte {
+	flags := pub.QoS << 1
+	if pub.Retain {
+		flags |= 0x01
+	}
+
+	body := appendString(nil, pub.Topic)
+	if pub.QoS > 0 {
+		body = binary.BigEndian.AppendUint16(body, pub.PacketID)
+	}
+	body = append(body, pub.Payload...)
+
+	return Packet{Type: TypePublish, Flags: flags, Body: body}.Encode()
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+func readString(buf []byte) (string, []byte, error) {
+	if len(buf) < 2 {
+		return "", nil, errMalformed
+	}
+	n := int(binary.BigEndian.Uint16(buf))
+	if len(buf) < 2+n {
+		return "", nil, errMalformed
+	}
+	return string(buf[2 : 2+n]), buf[2+n:], nil
+}
+
+// Match проверяет, соответствует ли топик фильтру подписки с '+' и '#'
+func Match(filter, topic string) bool {
+	for {
+		fSeg, fRest, fMore := cut(filter)
+		tSeg, tRest, tMore := cut(topic)
+
+		switch {
+		case fSeg == "#":
+			return true
+		case fSeg != "+" && fSeg != tSeg:
+			return false
+		}
+
+		if !fMore || !tMore {
+			// "a/#" совпадает и с "a"
+			return fMore == tMore || (fMore && fRest == "#")
+		}
+		filter, topic = fRest, tRest
+	}
+}
+
+func cut(s string) (string, string, bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '/' {
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
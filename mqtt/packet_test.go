@@ -0,0 +1,151 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	// Размеры на границах байтов оставшейся длины (7, 14 и 21 бит)
+	for _, size := range []int{0, 1, 127, 128, 16383, 16384, 200000} {
+		p := Packet{Type: TypePublish, Flags: 0x0b, Body: bytes.Repeat([]byte{0xa5}, size)}
+		encoded := p.Encode()
+
+		got, err := ReadPacket(bufio.NewReader(bytes.NewReader(encoded)))
+		if err != nil {
+			t.Fatalf("размер %d: ReadPacket: %v", size, err)
+		}
+		if got.Type != p.Type || got.Flags != p.Flags || !bytes.Equal(got.Body, p.Body) {
+			t.Errorf("размер %d: получено Type=%d Flags=%#x len=%d", size, got.Type, got.Flags, len(got.Body))
+		}
+	}
+}
+
+func TestPacketEncodeLength(t *testing.T) {
+	tests := []struct {
+		size   int
+		header []byte
+	}{
+		{0, []byte{0xc0, 0x00}},
+		{127, []byte{0xc0, 0x7f}},
+		{128, []byte{0xc0, 0x80, 0x01}},
+		{16383, []byte{0xc0, 0xff, 0x7f}},
+		{16384, []byte{0xc0, 0x80, 0x80, 0x01}},
+	}
+
+	for _, tt := range tests {
+		encoded := Packet{Type: TypePingreq, Body: make([]byte, tt.size)}.Encode()
+		if !bytes.Equal(encoded[:len(tt.header)], tt.header) {
+			t.Errorf("размер %d: заголовок % x, ожидается % x", tt.size, encoded[:len(tt.header)], tt.header)
+		}
+	}
+}
+
+func TestPublishRoundTrip(t *testing.T) {
+	tests := []Publish{
+		{Topic: "zigbee2mqtt/balcony", Payload: []byte(`{"temperature":21.5}`)},
+		{Topic: "tele/garden/SENSOR", Payload: []byte(`{}`), QoS: 1, PacketID: 42},
+		{Topic: "home/датчик", Payload: []byte("22.1"), Retain: true},
+		{Topic: "a", QoS: 1, PacketID: 65535, Retain: true},
+		{Topic: strings.Repeat("t", 300), Payload: bytes.Repeat([]byte("x"), 1000)},
+	}
+
+	for _, want := range tests {
+		packet, err := ReadPacket(bufio.NewReader(bytes.NewReader(want.Encode())))
+		if err != nil {
+			t.Fatalf("%s: ReadPacket: %v", want.Topic, err)
+		}
+		if packet.Type != TypePublish {
+			t.Fatalf("%s: Type = %d", want.Topic, packet.Type)
+		}
+		got, err := ParsePublish(packet)
+		if err != nil {
+			t.Fatalf("%s: ParsePublish: %v", want.Topic, err)
+		}
+		if len(want.Payload) == 0 {
+			want.Payload = []byte{}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("получено %+v, ожидается %+v", got, want)
+		}
+	}
+}
+
+func TestReadPacketMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"Empty", nil, io.EOF},
+		{"NoLength", []byte{0x30}, io.EOF},
+		{"LengthTooLong", []byte{0x30, 0xff, 0xff, 0xff, 0xff, 0x01}, errMalformed},
+		{"TruncatedBody", []byte{0x30, 0x05, 0x00, 0x01}, io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadPacket(bufio.NewReader(bytes.NewReader(tt.data)))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ошибка %v, ожидается %v", err, tt.want)
+			}
+		})
+	}
+
+	// Длина больше лимита отклоняется до чтения тела
+	oversized := Packet{Type: TypePublish, Body: make([]byte, maxPacketSize+1)}.Encode()
+	if _, err := ReadPacket(bufio.NewReader(bytes.NewReader(oversized[:5]))); err == nil {
+		t.Error("ожидается ошибка для пакета больше лимита")
+	}
+}
+
+func TestParsePublishMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet Packet
+	}{
+		{"NoTopic", Packet{Type: TypePublish, Body: []byte{0x00}}},
+		{"ShortTopic", Packet{Type: TypePublish, Body: []byte{0x00, 0x05, 'a', 'b'}}},
+		{"NoPacketID", Packet{Type: TypePublish, Flags: 0x02, Body: []byte{0x00, 0x01, 'a', 0x00}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if pub, err := ParsePublish(tt.packet); !errors.Is(err, errMalformed) {
+				t.Errorf("ParsePublish = %+v, %v; ожидается errMalformed", pub, err)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"zigbee2mqtt/balcony", "zigbee2mqtt/balcony", true},
+		{"zigbee2mqtt/balcony", "zigbee2mqtt/kitchen", false},
+		{"zigbee2mqtt/+", "zigbee2mqtt/balcony", true},
+		{"zigbee2mqtt/+", "zigbee2mqtt/balcony/availability", false},
+		{"tele/+/SENSOR", "tele/garden/SENSOR", true},
+		{"tele/+/SENSOR", "tele/garden/STATE", false},
+		{"#", "any/topic", true},
+		{"home/#", "home", true},
+		{"home/#", "home/a/b", true},
+		{"home/#", "homes/a", false},
+		{"home/+", "home", false},
+		{"a/b", "a", false},
+		{"a", "a/b", false},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, ожидается %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
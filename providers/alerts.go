@@ -0,0 +1,228 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"weather-aggregator/capfeed"
+	"weather-aggregator/geo"
+	"weather-aggregator/models"
+)
+
+// AlertSource источник предупреждений об опасной погоде. Провайдеры погоды
+// реализуют его дополнительно, обертки (logging, chaos) передают запрос
+// дальше по цепочке.
+type AlertSource interface {
+	Name() string
+	GetAlerts(ctx context.Context, city, country string) ([]models.Alert, error)
+}
+
+// AsAlertSource возвращает провайдер как источник предупреждений, если
+// исходный провайдер их отдает. Запросы идут через внешнюю обертку, которая
+// умеет передавать предупреждения, как и в AsForecastSource.
+func AsAlertSource(p Provider) (AlertSource, bool) {
+	if _, ok := Unwrap(p).(AlertSource); !ok {
+		return nil, false
+	}
+	for {
+		if source, ok := p.(AlertSource); ok {
+			return source, true
+		}
+		// Обертку без GetAlerts пропускаем
+		p = p.(Unwrapper).Unwrap()
+	}
+}
+
+// capMaxLinks сколько CAP документов по ссылкам из ленты загружать за раз
+const capMaxLinks = 20
+
+// CAPSource загружает предупреждения CAP 1.2 из ленты по адресу и
+// отбирает относящиеся к городу
+type CAPSource struct {
+	feedURL  string
+	language string
+	client   *http.Client
+	geocoder geo.Geocoder
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	cached    []capfeed.Alert
+	fetchedAt time.Time
+}
+
+// NewCAPSource создает источник для ленты или CAP документа по адресу feedURL.
+// language - предпочтительный язык текстов предупреждений.
+func NewCAPSource(feedURL, language string, geocoder geo.Geocoder) *CAPSource {
+	return &CAPSource{
+		feedURL:  feedURL,
+		language: language,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		geocoder: geocoder,
+		cacheTTL: 5 * time.Minute,
+	}
+}
+
+func (s *CAPSource) Name() string {
+	if u, err := url.Parse(s.feedURL); err == nil && u.Host != "" {
+		return "CAP " + u.Host
+	}
+	return "CAP"
+}
+
+// GetAlerts возвращает предупреждения, области которых включают город.
+// Предупреждение без геометрии подходит, если город упомянут в описании
+// областей или области не указаны вовсе.
+func (s *CAPSource) GetAlerts(ctx context.Context, city, country string) ([]models.Alert, error) {
+	all, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var loc *geo.Location
+	if s.geocoder != nil {
+		if resolved, err := s.geocoder.Resolve(ctx, city, country); err == nil {
+			loc = resolved
+		} else if !errors.Is(err, geo.ErrNotFound) {
+			return nil, fmt.Errorf("ошибка определения координат: %w", err)
+		}
+	}
+
+	var alerts []models.Alert
+	for _, a := range all {
+		if !s.matches(a, city, loc) {
+			continue
+		}
+		alert := a.Alert
+		alert.Source = s.Name()
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+func (s *CAPSource) matches(a capfeed.Alert, city string, loc *geo.Location) bool {
+	if loc != nil {
+		if covered, hasGeometry := a.Covers(loc.Lat, loc.Lon); hasGeometry {
+			return covered
+		}
+	}
+	if len(a.Areas) == 0 {
+		return true
+	}
+	for _, area := range a.Areas {
+		if strings.Contains(strings.ToLower(area), strings.ToLower(city)) {
+			return true
+		}
+	}
+	return false
+}
+
+// load загружает ленту и документы по ссылкам; лента общая для всех городов
+// и кешируется на cacheTTL
+func (s *CAPSource) load(ctx context.Context) ([]capfeed.Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Since(s.fetchedAt) < s.cacheTTL {
+		return s.cached, nil
+	}
+
+	doc, err := s.fetch(ctx, s.feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := doc.Alerts
+	for i, link := range doc.Links {
+		if i == capMaxLinks {
+			break
+		}
+		linked, err := s.fetch(ctx, link)
+		if err != nil {
+			// Одна недоступная ссылка не должна лишать остальных предупреждений
+			continue
+		}
+		alerts = append(alerts, linked.Alerts...)
+	}
+
+	if alerts == nil {
+		alerts = []capfeed.Alert{}
+	}
+	s.cached = alerts
+	s.fetchedAt = time.Now()
+	return alerts, nil
+}
+
+func (s *CAPSource) fetch(ctx context.Context, reqURL string) (*capfeed.Document, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка HTTP запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ошибка API: статус %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ответа: %w", err)
+	}
+
+	return capfeed.Parse(body, s.language)
+}
+
+var knownAlertLevels = map[string]bool{
+	models.SeverityExtreme: true, models.SeveritySevere: true,
+	models.SeverityModerate: true, models.SeverityMinor: true,
+	models.UrgencyImmediate: true, models.UrgencyExpected: true,
+	models.UrgencyFuture: true, models.UrgencyPast: true,
+}
+
+// alertLevel приводит severity/urgency источника к значениям CAP
+func alertLevel(value, fallback string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if knownAlertLevels[value] {
+		return value
+	}
+	return fallback
+}
+
+// severityFromText оценивает серьезность по названию предупреждения для
+// источников, которые ее не сообщают (цветовые уровни и термины NWS)
+func severityFromText(event string) string {
+	text := strings.ToLower(event)
+	switch {
+	case containsAny(text, "extreme", "red warning", "red alert", "красн", "чрезвычайн"):
+		return models.SeverityExtreme
+	case containsAny(text, "warning", "orange", "оранжев", "штормов"):
+		return models.SeveritySevere
+	case containsAny(text, "watch", "advisory", "yellow", "желт", "жёлт"):
+		return models.SeverityModerate
+	case containsAny(text, "statement", "green", "зелен", "зелён"):
+		return models.SeverityMinor
+	}
+	return models.SeverityUnknown
+}
+
+func containsAny(text string, words ...string) bool {
+	for _, w := range words {
+		if strings.Contains(text, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+// Package chaos содержит декоратор providers.Provider для внедрения сбоев:
+// задержек, ошибок, зависаний, мусорных значений и сдвига времени наблюдения.
+// Используется для проверки поведения агрегатора и сервера при деградации
+// внешних API без ожидания реальных аварий.
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"weather-aggregator/models"
+	"weather-aggregator/providers"
+)
+
+// ErrInjected возвращается вместо ответа провайдера при внедренной ошибке
+var ErrInjected = errors.New("chaos: внедренная ошибка")
+
+// AllProviders ключ конфигурации, действующей для всех провайдеров без своей
+const AllProviders = "*"
+
+// Config параметры сбоев для одного провайдера. Вероятности задаются от 0 до 1.
+type Config struct {
+	Enabled bool `json:"enabled"`
+
+	// Задержка перед обращением к провайдеру, равномерно в [min, max]
+	LatencyMinMs int `json:"latency_min_ms,omitempty"`
+	LatencyMaxMs int `json:"latency_max_ms,omitempty"`
+
+	// ErrorRate доля запросов, завершающихся ErrInjected
+	ErrorRate float64 `json:"error_rate,omitempty"`
+	// TimeoutRate доля запросов, которые зависают до отмены контекста
+	TimeoutRate float64 `json:"timeout_rate,omitempty"`
+	// GarbageRate доля ответов с физически невозможными значениями
+	GarbageRate float64 `json:"garbage_rate,omitempty"`
+
+	// SkewRate доля ответов, у которых Timestamp сдвинут на TimestampSkewSec
+	SkewRate         float64 `json:"skew_rate,omitempty"`
+	TimestampSkewSec int     `json:"timestamp_skew_sec,omitempty"`
+}
+
+// Validate проверяет корректность вероятностей и задержек
+func (c Config) Validate() error {
+	rates := map[string]float64{
+		"error_rate":   c.ErrorRate,
+		"timeout_rate": c.TimeoutRate,
+		"garbage_rate": c.GarbageRate,
+		"skew_rate":    c.SkewRate,
+	}
+	for name, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s должна быть от 0 до 1", name)
+		}
+	}
+	if c.LatencyMinMs < 0 || c.LatencyMaxMs < 0 {
+		return fmt.Errorf("задержка не может быть отрицательной")
+	}
+	if c.LatencyMaxMs != 0 && c.LatencyMaxMs < c.LatencyMinMs {
+		return fmt.Errorf("latency_max_ms меньше latency_min_ms")
+	}
+	return nil
+}
+
+// Controller хранит конфигурации сбоев по именам провайдеров и позволяет
+// менять их на лету (например, через админский эндпоинт)
+type Controller struct {
+	mu      sync.RWMutex
+	configs map[string]Config
+}
+
+// NewController создает контроллер без сбоев
+func NewController() *Controller {
+	return &Controller{
+		configs: make(map[string]Config),
+	}
+}
+
+// LoadFile загружает конфигурацию из JSON вида {"*": {...}, "WeatherAPI": {...}}
+func (c *Controller) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения конфигурации chaos: %w", err)
+	}
+
+	var configs map[string]Config
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return fmt.Errorf("ошибка парсинга конфигурации chaos: %w", err)
+	}
+
+	return c.Replace(configs)
+}
+
+// Set задает конфигурацию для провайдера (или AllProviders)
+func (c *Controller) Set(provider string, cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", provider, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.configs[provider] = cfg
+	return nil
+}
+
+// Replace атомарно заменяет все конфигурации
+func (c *Controller) Replace(configs map[string]Config) error {
+	for provider, cfg := range configs {
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", provider, err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.configs = make(map[string]Config, len(configs))
+	for provider, cfg := range configs {
+		c.configs[provider] = cfg
+	}
+	return nil
+}
+
+// Snapshot возвращает копию текущих конфигураций
+func (c *Controller) Snapshot() map[string]Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := make(map[string]Config, len(c.configs))
+	for provider, cfg := range c.configs {
+		snapshot[provider] = cfg
+	}
+	return snapshot
+}
+
+// configFor возвращает действующую конфигурацию для провайдера
+func (c *Controller) configFor(provider string) Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if cfg, ok := c.configs[provider]; ok {
+		return cfg
+	}
+	return c.configs[AllProviders]
+}
+
+// Wrap оборачивает провайдер декоратором сбоев
+func (c *Controller) Wrap(p providers.Provider) providers.Provider {
+	return &chaosProvider{Provider: p, ctl: c}
+}
+
+// Middleware возвращает декоратор сбоев в виде providers.Middleware
+func (c *Controller) Middleware() providers.Middleware {
+	return c.Wrap
+}
+
+type chaosProvider struct {
+	providers.Provider
+	ctl *Controller
+}
+
+func (p *chaosProvider) Unwrap() providers.Provider { return p.Provider }
+
+func (p *chaosProvider) GetWeather(ctx context.Context, city, country string) (*models.WeatherData, error) {
+	cfg := p.ctl.configFor(p.Name())
+	if !cfg.Enabled {
+		return p.Provider.GetWeather(ctx, city, country)
+	}
+
+	if err := fail(ctx, cfg); err != nil {
+		return nil, err
+	}
+
+	weather, err := p.Provider.GetWeather(ctx, city, country)
+	if err != nil {
+		return nil, err
+	}
+
+	// Портим копию, чтобы не затронуть данные, которые провайдер мог закешировать
+	corrupted := *weather
+	if hit(cfg.GarbageRate) {
+		garbage(&corrupted)
+	}
+	if hit(cfg.SkewRate) {
+		corrupted.Timestamp = corrupted.Timestamp.Add(time.Duration(cfg.TimestampSkewSec) * time.Second)
+	}
+
+	return &corrupted, nil
+}
+
+// GetForecast передает запрос прогноза исходному провайдеру с теми же
+// задержками, зависаниями и ошибками, что и запросы погоды
+func (p *chaosProvider) GetForecast(ctx context.Context, city, country string) ([]models.ForecastPoint, error) {
+	source, ok := providers.AsForecastSource(p.Provider)
+	if !ok {
+		return nil, fmt.Errorf("%s не отдает прогноз", p.Name())
+	}
+
+	if cfg := p.ctl.configFor(p.Name()); cfg.Enabled {
+		if err := fail(ctx, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return source.GetForecast(ctx, city, country)
+}
+
+// GetAlerts передает запрос предупреждений исходному провайдеру с теми же
+// задержками, зависаниями и ошибками, что и запросы погоды
+func (p *chaosProvider) GetAlerts(ctx context.Context, city, country string) ([]models.Alert, error) {
+	source, ok := providers.AsAlertSource(p.Provider)
+	if !ok {
+		return nil, fmt.Errorf("%s не отдает предупреждения", p.Name())
+	}
+
+	if cfg := p.ctl.configFor(p.Name()); cfg.Enabled {
+		if err := fail(ctx, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return source.GetAlerts(ctx, city, country)
+}
+
+// fail выдерживает задержку и, если выпадет, внедряет зависание или ошибку
+func fail(ctx context.Context, cfg Config) error {
+	if err := sleep(ctx, latency(cfg)); err != nil {
+		return err
+	}
+
+	if hit(cfg.TimeoutRate) {
+		// Имитируем зависший upstream: ждем отмены контекста
+		<-ctx.Done()
+		return fmt.Errorf("chaos: внедренный таймаут: %w", ctx.Err())
+	}
+
+	if hit(cfg.ErrorRate) {
+		return ErrInjected
+	}
+	return nil
+}
+
+// garbage подставляет одно из физически невозможных значений
+func garbage(d *models.WeatherData) {
+	switch rand.IntN(5) {
+	case 0:
+		d.Temperature = models.Float(math.NaN())
+	case 1:
+		d.Temperature = models.Float(1e6)
+	case 2:
+		d.Humidity = models.Float(-40)
+	case 3:
+		d.Pressure = models.Float(0)
+	default:
+		d.WindSpeed = models.Float(math.Inf(1))
+	}
+}
+
+func latency(cfg Config) time.Duration {
+	if cfg.LatencyMaxMs <= cfg.LatencyMinMs {
+		return time.Duration(cfg.LatencyMinMs) * time.Millisecond
+	}
+	spread := rand.IntN(cfg.LatencyMaxMs - cfg.LatencyMinMs + 1)
+	return time.Duration(cfg.LatencyMinMs+spread) * time.Millisecond
+}
+
+func hit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,32 @@
+package providers
+
+import (
+	"context"
+
+	"weather-aggregator/models"
+)
+
+// ForecastSource источник почасового прогноза для проверки точности
+// провайдеров. Провайдеры погоды реализуют его дополнительно, обертки
+// (logging, chaos) передают запрос дальше по цепочке.
+type ForecastSource interface {
+	Name() string
+	GetForecast(ctx context.Context, city, country string) ([]models.ForecastPoint, error)
+}
+
+// AsForecastSource возвращает провайдер как источник прогноза, если исходный
+// провайдер его отдает. Запросы идут через внешнюю обертку, которая умеет
+// передавать прогноз, поэтому на них действуют те же middleware и сбои, что
+// и на запросы погоды.
+func AsForecastSource(p Provider) (ForecastSource, bool) {
+	if _, ok := Unwrap(p).(ForecastSource); !ok {
+		return nil, false
+	}
+	for {
+		if source, ok := p.(ForecastSource); ok {
+			return source, true
+		}
+		// Обертку без GetForecast пропускаем
+		p = p.(Unwrapper).Unwrap()
+	}
+}
@@ -0,0 +1,202 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"weather-aggregator/conditions"
+	"weather-aggregator/geo"
+	"weather-aggregator/metar"
+	"weather-aggregator/models"
+)
+
+// DefaultMETARSource источник сводок по умолчанию; {icao} заменяется кодом станции
+const DefaultMETARSource = "https://aviationweather.gov/api/data/metar?ids={icao}&format=raw"
+
+// METARProvider провайдер фактической погоды по авиационным сводкам METAR.
+// Город сопоставляется с ближайшей станцией из встроенной таблицы metar.Stations.
+type METARProvider struct {
+	client        *http.Client
+	sourceURL     string
+	geocoder      geo.Geocoder
+	maxDistanceKm float64
+}
+
+// NewMETARProvider создает провайдер. sourceURL - шаблон адреса с {icao}
+// (пустой - DefaultMETARSource), maxDistanceKm - максимальное расстояние от
+// города до станции.
+func NewMETARProvider(geocoder geo.Geocoder, sourceURL string, maxDistanceKm float64) *METARProvider {
+	if sourceURL == "" {
+		sourceURL = DefaultMETARSource
+	}
+	return &METARProvider{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		sourceURL:     sourceURL,
+		geocoder:      geocoder,
+		maxDistanceKm: maxDistanceKm,
+	}
+}
+
+func (p *METARProvider) Name() string {
+	return "METAR"
+}
+
+func (p *METARProvider) IsAvailable() bool {
+	return p.geocoder != nil && strings.Contains(p.sourceURL, "{icao}")
+}
+
+func (p *METARProvider) GetWeather(ctx context.Context, city, country string) (*models.WeatherData, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("провайдер %s не настроен", p.Name())
+	}
+
+	loc, err := p.geocoder.Resolve(ctx, city, country)
+	if err != nil {
+		if errors.Is(err, geo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrCityNotFound, err)
+		}
+		return nil, fmt.Errorf("ошибка определения координат: %w", err)
+	}
+
+	station, distance := metar.NearestStation(loc.Lat, loc.Lon)
+	if distance > p.maxDistanceKm {
+		return nil, fmt.Errorf("%w: ближайшая станция %s в %.0f км", ErrUnsupportedLocation, station.ICAO, distance)
+	}
+
+	raw, err := p.fetch(ctx, station.ICAO)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := metar.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора сводки %s: %w", station.ICAO, err)
+	}
+
+	// Сводка может не содержать отдельных групп (например, автоматическая
+	// станция без датчика точки росы); отсутствующие значения остаются nil
+	var humidity *float64
+	if rh, ok := report.RelativeHumidity(); ok {
+		humidity = &rh
+	}
+
+	var pressure *float64
+	switch {
+	case report.SeaLevelPressure != nil:
+		pressure = report.SeaLevelPressure
+	case report.Pressure != nil:
+		pressure = &report.Pressure.HPa
+	}
+
+	var windSpeed, windDirection *float64
+	if report.Wind != nil {
+		windSpeed = &report.Wind.Speed
+		// При переменном ветре и штиле направления нет
+		if !report.Wind.Variable && !report.Wind.Calm {
+			windDirection = models.Float(float64(report.Wind.Direction))
+		}
+	}
+
+	if report.Temperature == nil && humidity == nil && pressure == nil && windSpeed == nil {
+		return nil, fmt.Errorf("сводка %s не содержит измерений", station.ICAO)
+	}
+
+	weather := &models.WeatherData{
+		Provider:      p.Name(),
+		Location:      fmt.Sprintf("%s, %s", city, country),
+		Temperature:   report.Temperature,
+		FeelsLike:     feelsLike(report.Temperature, humidity, windSpeed),
+		Humidity:      humidity,
+		Pressure:      pressure,
+		WindSpeed:     windSpeed,
+		WindDirection: windDirection,
+		Description:   report.Description(),
+		Condition:     conditions.FromMETAR(report),
+		Station:       station.ICAO,
+		Timestamp:     report.Time,
+		Units:         "metric",
+		DewPoint:      report.DewPoint,
+		CloudCover:    metarCloudCover(report),
+	}
+	if report.Wind != nil && report.Wind.Gust > 0 {
+		weather.WindGust = &report.Wind.Gust
+	}
+	switch {
+	case report.Visibility != nil:
+		weather.Visibility = models.Float(report.Visibility.Meters / 1000)
+	case report.CAVOK:
+		weather.Visibility = models.Float(10)
+	}
+
+	return weather, nil
+}
+
+// metarCoverPercent облачность по количеству октантов: середина диапазона
+// группы (FEW 1-2, SCT 3-4, BKN 5-7, OVC 8)
+var metarCoverPercent = map[string]float64{
+	"SKC": 0, "CLR": 0, "NSC": 0, "NCD": 0,
+	"FEW": 19, "SCT": 44, "BKN": 75, "OVC": 100, "VV": 100,
+}
+
+// metarCloudCover оценивает общую облачность по самому плотному слою; nil,
+// если сводка не сообщает облачность
+func metarCloudCover(report *metar.Report) *float64 {
+	if report.CAVOK {
+		return models.Float(0)
+	}
+
+	var cover *float64
+	for _, layer := range report.Clouds {
+		percent, ok := metarCoverPercent[layer.Cover]
+		if !ok {
+			continue
+		}
+		if cover == nil || percent > *cover {
+			cover = &percent
+		}
+	}
+	return cover
+}
+
+// fetch загружает текст последней сводки станции
+func (p *METARProvider) fetch(ctx context.Context, icao string) (string, error) {
+	reqURL := strings.ReplaceAll(p.sourceURL, "{icao}", icao)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ошибка HTTP запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ошибка API: статус %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения ответа: %w", err)
+	}
+
+	// Источники добавляют строку с датой (NOAA) или отдают несколько сводок:
+	// берем первую строку, относящуюся к станции
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, icao+" ") || strings.Contains(line, " "+icao+" ") {
+			return line, nil
+		}
+	}
+
+	return "", fmt.Errorf("нет свежей сводки для станции %s", icao)
+}
@@ -0,0 +1,20 @@
+package providers_test
+
+import (
+	"testing"
+
+	"weather-aggregator/geo"
+	"weather-aggregator/providers"
+	"weather-aggregator/providers/providertest"
+)
+
+func TestMETARConformance(t *testing.T) {
+	providertest.Suite{
+		NewProvider: func(baseURL string) providers.Provider {
+			return providers.NewMETARProvider(geo.NewTable(), baseURL+"/metar?ids={icao}", 50)
+		},
+		Upstream: providertest.METARUpstream,
+		// Сводка не содержит ощущаемой температуры
+		ComputedFeelsLike: true,
+	}.Run(t)
+}
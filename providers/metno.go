@@ -0,0 +1,386 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"weather-aggregator/conditions"
+	"weather-aggregator/derivations"
+	"weather-aggregator/geo"
+	"weather-aggregator/models"
+)
+
+// metNoCompactPath путь Locationforecast 2.0 (compact)
+const metNoCompactPath = "/weatherapi/locationforecast/2.0/compact"
+
+// MetNoProvider провайдер Норвежского метеоинститута (api.met.no).
+// Условия использования требуют идентифицирующий User-Agent, не более
+// 4 знаков после запятой в координатах и повторных запросов не раньше Expires.
+type MetNoProvider struct {
+	userAgent string
+	client    *http.Client
+	baseURL   string
+	geocoder  geo.Geocoder
+
+	mu    sync.Mutex
+	cache map[string]*metNoCacheEntry
+}
+
+// metNoCacheEntry последний ответ для точки и его срок годности
+type metNoCacheEntry struct {
+	response     metNoResponse
+	expires      time.Time
+	lastModified string
+}
+
+// metNoResponse часть ответа Locationforecast, которую использует провайдер
+type metNoResponse struct {
+	Properties struct {
+		Meta struct {
+			UpdatedAt time.Time `json:"updated_at"`
+		} `json:"meta"`
+		Timeseries []metNoTimestep `json:"timeseries"`
+	} `json:"properties"`
+}
+
+type metNoTimestep struct {
+	Time time.Time `json:"time"`
+	Data struct {
+		Instant struct {
+			Details struct {
+				AirPressureAtSeaLevel *float64 `json:"air_pressure_at_sea_level"`
+				AirTemperature        *float64 `json:"air_temperature"`
+				CloudAreaFraction     *float64 `json:"cloud_area_fraction"`
+				RelativeHumidity      *float64 `json:"relative_humidity"`
+				WindFromDirection     *float64 `json:"wind_from_direction"`
+				WindSpeed             *float64 `json:"wind_speed"`
+			} `json:"details"`
+		} `json:"instant"`
+		Next1Hours *metNoPeriod `json:"next_1_hours"`
+		Next6Hours *metNoPeriod `json:"next_6_hours"`
+	} `json:"data"`
+}
+
+type metNoPeriod struct {
+	Summary struct {
+		SymbolCode string `json:"symbol_code"`
+	} `json:"summary"`
+	Details struct {
+		PrecipitationAmount float64 `json:"precipitation_amount"`
+	} `json:"details"`
+}
+
+// NewMetNoProvider создает провайдер met.no. userAgent должен идентифицировать
+// приложение и содержать контакт, например "weather-aggregator/1.0 ops@example.com".
+func NewMetNoProvider(userAgent string, geocoder geo.Geocoder) *MetNoProvider {
+	return &MetNoProvider{
+		userAgent: userAgent,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL:  "https://api.met.no",
+		geocoder: geocoder,
+		cache:    make(map[string]*metNoCacheEntry),
+	}
+}
+
+// WithBaseURL переопределяет адрес API (например, для локального mock-upstream)
+func (p *MetNoProvider) WithBaseURL(baseURL string) *MetNoProvider {
+	if baseURL != "" {
+		p.baseURL = strings.TrimRight(baseURL, "/")
+	}
+	return p
+}
+
+func (p *MetNoProvider) Name() string {
+	return "MET Norway"
+}
+
+func (p *MetNoProvider) IsAvailable() bool {
+	return p.userAgent != "" && p.geocoder != nil
+}
+
+func (p *MetNoProvider) GetWeather(ctx context.Context, city, country string) (*models.WeatherData, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("провайдер %s не настроен", p.Name())
+	}
+
+	loc, err := p.geocoder.Resolve(ctx, city, country)
+	if err != nil {
+		if errors.Is(err, geo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrCityNotFound, err)
+		}
+		return nil, fmt.Errorf("ошибка определения координат: %w", err)
+	}
+
+	// Условия met.no: не более 4 знаков после запятой
+	lat := math.Round(loc.Lat*1e4) / 1e4
+	lon := math.Round(loc.Lon*1e4) / 1e4
+
+	forecast, err := p.fetch(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := currentTimestep(forecast.Properties.Timeseries, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("нет данных о погоде")
+	}
+
+	details := step.Data.Instant.Details
+	symbol := ""
+	if step.Data.Next1Hours != nil {
+		symbol = step.Data.Next1Hours.Summary.SymbolCode
+	} else if step.Data.Next6Hours != nil {
+		symbol = step.Data.Next6Hours.Summary.SymbolCode
+	}
+
+	weather := &models.WeatherData{
+		Provider:      p.Name(),
+		Location:      fmt.Sprintf("%s, %s", city, country),
+		Temperature:   details.AirTemperature,
+		FeelsLike:     feelsLike(details.AirTemperature, details.RelativeHumidity, details.WindSpeed),
+		Humidity:      details.RelativeHumidity,
+		Pressure:      details.AirPressureAtSeaLevel,
+		WindSpeed:     details.WindSpeed,
+		WindDirection: details.WindFromDirection,
+		Description:   MetNoSymbolDescription(symbol),
+		Condition:     conditions.FromMetNo(symbol),
+		Icon:          symbol,
+		Timestamp:     step.Time,
+		Units:         "metric",
+		CloudCover:    details.CloudAreaFraction,
+	}
+	// Осадки met.no сообщает только на ближайший час вперед
+	if step.Data.Next1Hours != nil {
+		weather.Precipitation = &step.Data.Next1Hours.Details.PrecipitationAmount
+	}
+
+	return weather, nil
+}
+
+// GetForecast возвращает почасовой прогноз из того же ответа Locationforecast,
+// что и текущая погода
+func (p *MetNoProvider) GetForecast(ctx context.Context, city, country string) ([]models.ForecastPoint, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("провайдер %s не настроен", p.Name())
+	}
+
+	loc, err := p.geocoder.Resolve(ctx, city, country)
+	if err != nil {
+		if errors.Is(err, geo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrCityNotFound, err)
+		}
+		return nil, fmt.Errorf("ошибка определения координат: %w", err)
+	}
+
+	forecast, err := p.fetch(ctx, math.Round(loc.Lat*1e4)/1e4, math.Round(loc.Lon*1e4)/1e4)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]models.ForecastPoint, 0, len(forecast.Properties.Timeseries))
+	for _, step := range forecast.Properties.Timeseries {
+		details := step.Data.Instant.Details
+		points = append(points, models.ForecastPoint{
+			Time:        step.Time,
+			Temperature: details.AirTemperature,
+			Humidity:    details.RelativeHumidity,
+			Pressure:    details.AirPressureAtSeaLevel,
+			WindSpeed:   details.WindSpeed,
+		})
+	}
+	return points, nil
+}
+
+// fetch возвращает прогноз для точки, соблюдая Expires и If-Modified-Since
+func (p *MetNoProvider) fetch(ctx context.Context, lat, lon float64) (*metNoResponse, error) {
+	cacheKey := fmt.Sprintf("%.4f,%.4f", lat, lon)
+
+	p.mu.Lock()
+	cached := p.cache[cacheKey]
+	p.mu.Unlock()
+
+	// До Expires повторно запрашивать данные запрещено
+	if cached != nil && time.Now().Before(cached.expires) {
+		return &cached.response, nil
+	}
+
+	query := url.Values{}
+	query.Set("lat", fmt.Sprintf("%.4f", lat))
+	query.Set("lon", fmt.Sprintf("%.4f", lon))
+
+	reqURL := fmt.Sprintf("%s%s?%s", p.baseURL, metNoCompactPath, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("User-Agent", p.userAgent)
+	if cached != nil && cached.lastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.lastModified)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка HTTP запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	expires := parseExpires(resp.Header.Get("Expires"))
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo:
+		if resp.StatusCode == http.StatusNonAuthoritativeInfo {
+			log.Printf("%s: API сообщает, что версия устарела (статус 203)", p.Name())
+		}
+	case http.StatusNotModified:
+		if cached == nil {
+			return nil, fmt.Errorf("ошибка API: статус 304 без закешированных данных")
+		}
+		p.store(cacheKey, &metNoCacheEntry{
+			response:     cached.response,
+			expires:      expires,
+			lastModified: cached.lastModified,
+		})
+		return &cached.response, nil
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("доступ запрещен: проверьте User-Agent")
+	case http.StatusTooManyRequests:
+		return nil, fmt.Errorf("превышен лимит запросов")
+	default:
+		return nil, fmt.Errorf("ошибка API: статус %d", resp.StatusCode)
+	}
+
+	var result metNoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+
+	p.store(cacheKey, &metNoCacheEntry{
+		response:     result,
+		expires:      expires,
+		lastModified: resp.Header.Get("Last-Modified"),
+	})
+
+	return &result, nil
+}
+
+func (p *MetNoProvider) store(key string, entry *metNoCacheEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cache[key] = entry
+}
+
+// parseExpires разбирает заголовок Expires; без него повторный запрос разрешен сразу
+func parseExpires(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	expires, err := http.ParseTime(value)
+	if err != nil {
+		return time.Time{}
+	}
+	return expires
+}
+
+// currentTimestep выбирает последний наступивший шаг прогноза: ближайший
+// следующий час может быть позже допустимого для наблюдения времени. Если все
+// шаги в будущем, берется первый.
+func currentTimestep(series []metNoTimestep, now time.Time) (metNoTimestep, bool) {
+	if len(series) == 0 {
+		return metNoTimestep{}, false
+	}
+
+	best := series[0]
+	for _, step := range series[1:] {
+		if !step.Time.After(now) && step.Time.After(best.Time) {
+			best = step
+		}
+	}
+	return best, true
+}
+
+// feelsLike ощущаемая температура по формуле Стедмана для провайдеров, которые
+// ее не сообщают: без температуры или влажности ее не рассчитать,
+// отсутствующий ветер считается штилем
+func feelsLike(temp, humidity, windSpeed *float64) *float64 {
+	if temp == nil || humidity == nil {
+		return nil
+	}
+	wind := 0.0
+	if windSpeed != nil {
+		wind = *windSpeed
+	}
+	return models.Float(derivations.ApparentTemperature(*temp, *humidity, wind))
+}
+
+// metNoSymbols описания кодов symbol_code (без суффиксов _day/_night/_polartwilight)
+var metNoSymbols = map[string]string{
+	"clearsky":                     "ясно",
+	"fair":                         "малооблачно",
+	"partlycloudy":                 "переменная облачность",
+	"cloudy":                       "пасмурно",
+	"fog":                          "туман",
+	"lightrain":                    "небольшой дождь",
+	"rain":                         "дождь",
+	"heavyrain":                    "сильный дождь",
+	"lightrainshowers":             "небольшой ливневый дождь",
+	"rainshowers":                  "ливневый дождь",
+	"heavyrainshowers":             "сильный ливень",
+	"lightrainandthunder":          "небольшой дождь с грозой",
+	"rainandthunder":               "дождь с грозой",
+	"heavyrainandthunder":          "сильный дождь с грозой",
+	"lightrainshowersandthunder":   "небольшой ливень с грозой",
+	"rainshowersandthunder":        "ливень с грозой",
+	"heavyrainshowersandthunder":   "сильный ливень с грозой",
+	"lightsleet":                   "небольшой мокрый снег",
+	"sleet":                        "мокрый снег",
+	"heavysleet":                   "сильный мокрый снег",
+	"lightsleetshowers":            "небольшой ливневый мокрый снег",
+	"sleetshowers":                 "ливневый мокрый снег",
+	"heavysleetshowers":            "сильный ливневый мокрый снег",
+	"lightsleetandthunder":         "небольшой мокрый снег с грозой",
+	"sleetandthunder":              "мокрый снег с грозой",
+	"heavysleetandthunder":         "сильный мокрый снег с грозой",
+	"lightssleetshowersandthunder": "небольшой ливневый мокрый снег с грозой",
+	"sleetshowersandthunder":       "ливневый мокрый снег с грозой",
+	"heavysleetshowersandthunder":  "сильный ливневый мокрый снег с грозой",
+	"lightsnow":                    "небольшой снег",
+	"snow":                         "снег",
+	"heavysnow":                    "сильный снег",
+	"lightsnowshowers":             "небольшой снегопад",
+	"snowshowers":                  "снегопад",
+	"heavysnowshowers":             "сильный снегопад",
+	"lightsnowandthunder":          "небольшой снег с грозой",
+	"snowandthunder":               "снег с грозой",
+	"heavysnowandthunder":          "сильный снег с грозой",
+	"lightssnowshowersandthunder":  "небольшой снегопад с грозой",
+	"snowshowersandthunder":        "снегопад с грозой",
+	"heavysnowshowersandthunder":   "сильный снегопад с грозой",
+}
+
+// MetNoSymbolDescription возвращает описание погоды для symbol_code met.no
+func MetNoSymbolDescription(symbol string) string {
+	base := MetNoSymbolBase(symbol)
+	if description, ok := metNoSymbols[base]; ok {
+		return description
+	}
+	return base
+}
+
+// MetNoSymbolBase отбрасывает суффикс времени суток: "clearsky_day" -> "clearsky"
+func MetNoSymbolBase(symbol string) string {
+	base, _, _ := strings.Cut(symbol, "_")
+	return base
+}
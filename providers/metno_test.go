@@ -0,0 +1,278 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"weather-aggregator/conditions"
+	"weather-aggregator/geo"
+	"weather-aggregator/models"
+	"weather-aggregator/providers"
+	"weather-aggregator/providers/providertest"
+)
+
+const metNoTestUserAgent = "weather-aggregator-test/1.0 test@example.com"
+
+func TestMetNoConformance(t *testing.T) {
+	providertest.Suite{
+		NewProvider: func(baseURL string) providers.Provider {
+			return providers.NewMetNoProvider(metNoTestUserAgent, geo.NewTable()).WithBaseURL(baseURL)
+		},
+		Upstream: providertest.MetNoUpstream,
+		// Compact-ответ met.no не содержит ощущаемой температуры
+		ComputedFeelsLike: true,
+	}.Run(t)
+}
+
+// metNoFixture записанный ответ Locationforecast compact, сдвинутый во
+// времени так, что шаг current приходится на начало текущего часа
+func metNoFixture(t *testing.T, current int) ([]byte, time.Time) {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/metno_compact.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	properties := doc["properties"].(map[string]interface{})
+	series := properties["timeseries"].([]interface{})
+	stepTime := func(i int) time.Time {
+		tm, err := time.Parse(time.RFC3339, series[i].(map[string]interface{})["time"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	now := time.Now().UTC().Truncate(time.Hour)
+	shift := now.Sub(stepTime(current))
+	for i := range series {
+		series[i].(map[string]interface{})["time"] = stepTime(i).Add(shift).Format(time.RFC3339)
+	}
+	properties["meta"].(map[string]interface{})["updated_at"] = now.Add(-20 * time.Minute).Format(time.RFC3339)
+
+	data, err = json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, now
+}
+
+// metNoServer запускает upstream с handler и считает запросы к нему
+func metNoServer(t *testing.T, handler http.HandlerFunc) (*providers.MetNoProvider, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return providers.NewMetNoProvider(metNoTestUserAgent, geo.NewTable()).WithBaseURL(server.URL), &requests
+}
+
+func TestMetNoFieldMapping(t *testing.T) {
+	body, current := metNoFixture(t, 0)
+	p, _ := metNoServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+
+	data, err := p.GetWeather(context.Background(), "Москва", "RU")
+	if err != nil {
+		t.Fatalf("GetWeather: %v", err)
+	}
+
+	expect := func(field string, got *float64, want float64) {
+		t.Helper()
+		if got == nil || math.Abs(*got-want) > 1e-9 {
+			t.Errorf("%s = %v, ожидается %v", field, got, want)
+		}
+	}
+	expect("Temperature", data.Temperature, -8.4)
+	expect("Humidity", data.Humidity, 87.2)
+	expect("Pressure", data.Pressure, 1021.3)
+	expect("WindSpeed", data.WindSpeed, 3.6)
+	expect("WindDirection", data.WindDirection, 292.5)
+	expect("CloudCover", data.CloudCover, 96.1)
+	expect("Precipitation", data.Precipitation, 0.2)
+	if data.FeelsLike == nil {
+		t.Error("FeelsLike не рассчитана")
+	}
+
+	if data.Icon != "lightsnowshowers_day" {
+		t.Errorf("Icon = %q, ожидается symbol_code ближайшего часа", data.Icon)
+	}
+	if data.Description != "небольшой снегопад" {
+		t.Errorf("Description = %q", data.Description)
+	}
+	if data.Condition != models.ConditionSnowLight {
+		t.Errorf("Condition = %q, ожидается %q", data.Condition, models.ConditionSnowLight)
+	}
+	if !data.Timestamp.Equal(current) {
+		t.Errorf("Timestamp = %s, ожидается текущий шаг %s", data.Timestamp, current)
+	}
+	if data.Provider != "MET Norway" || data.Units != "metric" || data.Location != "Москва, RU" {
+		t.Errorf("Provider/Units/Location = %q/%q/%q", data.Provider, data.Units, data.Location)
+	}
+}
+
+// TestMetNoNext6HoursFallback проверяет шаг дальнего прогноза, у которого нет
+// next_1_hours: символ берется из next_6_hours, осадков нет
+func TestMetNoNext6HoursFallback(t *testing.T) {
+	body, _ := metNoFixture(t, 2)
+	p, _ := metNoServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+
+	data, err := p.GetWeather(context.Background(), "Москва", "RU")
+	if err != nil {
+		t.Fatalf("GetWeather: %v", err)
+	}
+	if data.Icon != "partlycloudy_night" || data.Description != "переменная облачность" {
+		t.Errorf("Icon/Description = %q/%q, ожидается символ из next_6_hours", data.Icon, data.Description)
+	}
+	if data.Precipitation != nil {
+		t.Errorf("Precipitation = %v, ожидается nil без next_1_hours", *data.Precipitation)
+	}
+}
+
+func TestMetNoSymbolMapping(t *testing.T) {
+	tests := []struct {
+		symbol      string
+		description string
+		condition   string
+	}{
+		{"clearsky_day", "ясно", models.ConditionClear},
+		{"fair_night", "малооблачно", models.ConditionPartlyCloudy},
+		{"partlycloudy_polartwilight", "переменная облачность", models.ConditionPartlyCloudy},
+		{"cloudy", "пасмурно", models.ConditionOvercast},
+		{"fog", "туман", models.ConditionFog},
+		{"lightrain", "небольшой дождь", models.ConditionRainLight},
+		{"heavyrainshowers_day", "сильный ливень", models.ConditionRainHeavy},
+		{"sleet", "мокрый снег", models.ConditionSleet},
+		{"heavysnow", "сильный снег", models.ConditionSnowHeavy},
+		{"rainandthunder", "дождь с грозой", models.ConditionThunderstorm},
+		// Опечатка в коде - так его отдает API
+		{"lightssleetshowersandthunder_day", "небольшой ливневый мокрый снег с грозой", models.ConditionThunderstorm},
+		{"newsymbol_day", "newsymbol", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			if got := providers.MetNoSymbolDescription(tt.symbol); got != tt.description {
+				t.Errorf("MetNoSymbolDescription(%q) = %q, ожидается %q", tt.symbol, got, tt.description)
+			}
+			if got := conditions.FromMetNo(tt.symbol); got != tt.condition {
+				t.Errorf("FromMetNo(%q) = %q, ожидается %q", tt.symbol, got, tt.condition)
+			}
+		})
+	}
+}
+
+func TestMetNoRequest(t *testing.T) {
+	body, _ := metNoFixture(t, 0)
+	coordinate := regexp.MustCompile(`^-?\d+\.\d{4}$`)
+
+	p, requests := metNoServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); ua != metNoTestUserAgent {
+			t.Errorf("User-Agent = %q, ожидается %q", ua, metNoTestUserAgent)
+		}
+		if r.URL.Path != "/weatherapi/locationforecast/2.0/compact" {
+			t.Errorf("путь запроса %s", r.URL.Path)
+		}
+		// Условия met.no: не более 4 знаков после запятой
+		for _, key := range []string{"lat", "lon"} {
+			if v := r.URL.Query().Get(key); !coordinate.MatchString(v) {
+				t.Errorf("%s = %q, ожидается 4 знака после запятой", key, v)
+			}
+		}
+		w.Write(body)
+	})
+
+	if _, err := p.GetWeather(context.Background(), "Москва", "RU"); err != nil {
+		t.Fatalf("GetWeather: %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("запросов к API: %d, ожидается 1", n)
+	}
+}
+
+func TestMetNoCachedUntilExpires(t *testing.T) {
+	body, _ := metNoFixture(t, 0)
+	p, requests := metNoServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.Write(body)
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := p.GetWeather(ctx, "Москва", "RU"); err != nil {
+			t.Fatalf("GetWeather: %v", err)
+		}
+	}
+	// Прогноз берется из того же закешированного ответа
+	if _, err := p.GetForecast(ctx, "Москва", "RU"); err != nil {
+		t.Fatalf("GetForecast: %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("запросов к API до Expires: %d, ожидается 1", n)
+	}
+}
+
+func TestMetNoNotModified(t *testing.T) {
+	body, _ := metNoFixture(t, 0)
+	const lastModified = "Wed, 15 Jan 2025 10:42:31 GMT"
+
+	p, requests := metNoServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if since := r.Header.Get("If-Modified-Since"); since != "" {
+			if since != lastModified {
+				t.Errorf("If-Modified-Since = %q, ожидается Last-Modified %q", since, lastModified)
+			}
+			w.Header().Set("Expires", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		// Первый ответ сразу устаревает, чтобы следующий запрос был условным
+		w.Header().Set("Expires", time.Now().Add(-time.Second).UTC().Format(http.TimeFormat))
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write(body)
+	})
+
+	ctx := context.Background()
+	first, err := p.GetWeather(ctx, "Москва", "RU")
+	if err != nil {
+		t.Fatalf("GetWeather: %v", err)
+	}
+
+	second, err := p.GetWeather(ctx, "Москва", "RU")
+	if err != nil {
+		t.Fatalf("GetWeather после 304: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("запросов к API: %d, ожидается 2", n)
+	}
+	if second.Temperature == nil || *second.Temperature != *first.Temperature || !second.Timestamp.Equal(first.Timestamp) {
+		t.Errorf("после 304 ожидается закешированный ответ, получено %+v", second)
+	}
+
+	// 304 продлевает срок годности кеша
+	if _, err := p.GetWeather(ctx, "Москва", "RU"); err != nil {
+		t.Fatalf("GetWeather: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("запросов к API после продления Expires: %d, ожидается 2", n)
+	}
+}
@@ -0,0 +1,237 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"weather-aggregator/models"
+)
+
+// Middleware оборачивает провайдер дополнительным поведением (логирование,
+// замеры времени, повторы, кеширование и т.п.). Обертка должна сохранять
+// Name() исходного провайдера.
+type Middleware func(Provider) Provider
+
+// Chain оборачивает провайдер цепочкой middleware. Первый элемент списка
+// оказывается внешним: Chain(p, a, b) эквивалентно a(b(p)).
+func Chain(p Provider, mws ...Middleware) Provider {
+	for i := len(mws) - 1; i >= 0; i-- {
+		p = mws[i](p)
+	}
+	return p
+}
+
+// Unwrapper реализуют обертки, чтобы можно было добраться до исходного провайдера
+type Unwrapper interface {
+	Unwrap() Provider
+}
+
+// Unwrap снимает все обертки и возвращает исходный провайдер
+func Unwrap(p Provider) Provider {
+	for {
+		u, ok := p.(Unwrapper)
+		if !ok {
+			return p
+		}
+		p = u.Unwrap()
+	}
+}
+
+var (
+	middlewaresMu sync.RWMutex
+	middlewares   = map[string]Middleware{
+		"logging": Logging(log.Default()),
+		"timing":  Timing(DefaultTimingStats),
+	}
+)
+
+// RegisterMiddleware регистрирует middleware под именем, по которому его
+// можно подключить из конфигурации (PROVIDER_MIDDLEWARES, <ПРОВАЙДЕР>_MIDDLEWARES)
+func RegisterMiddleware(name string, mw Middleware) {
+	middlewaresMu.Lock()
+	defer middlewaresMu.Unlock()
+
+	middlewares[strings.ToLower(name)] = mw
+}
+
+// MiddlewaresByName возвращает зарегистрированные middleware в указанном порядке
+func MiddlewaresByName(names []string) ([]Middleware, error) {
+	middlewaresMu.RLock()
+	defer middlewaresMu.RUnlock()
+
+	result := make([]Middleware, 0, len(names))
+	for _, name := range names {
+		mw, ok := middlewares[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("неизвестный middleware %q (доступны: %s)", name, strings.Join(registeredNames(), ", "))
+		}
+		result = append(result, mw)
+	}
+	return result, nil
+}
+
+func registeredNames() []string {
+	names := make([]string, 0, len(middlewares))
+	for name := range middlewares {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Logging логирует каждый запрос к провайдеру с длительностью и результатом
+func Logging(logger *log.Logger) Middleware {
+	return func(p Provider) Provider {
+		return &loggingProvider{Provider: p, logger: logger}
+	}
+}
+
+type loggingProvider struct {
+	Provider
+	logger *log.Logger
+}
+
+func (p *loggingProvider) Unwrap() Provider { return p.Provider }
+
+func (p *loggingProvider) GetWeather(ctx context.Context, city, country string) (*models.WeatherData, error) {
+	start := time.Now()
+	weather, err := p.Provider.GetWeather(ctx, city, country)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		p.logger.Printf("%s: %s,%s - ошибка за %s: %v", p.Name(), city, country, elapsed, err)
+	} else if weather.Temperature != nil {
+		p.logger.Printf("%s: %s,%s - %.1f°C за %s", p.Name(), city, country, *weather.Temperature, elapsed)
+	} else {
+		p.logger.Printf("%s: %s,%s - без температуры за %s", p.Name(), city, country, elapsed)
+	}
+	return weather, err
+}
+
+func (p *loggingProvider) GetForecast(ctx context.Context, city, country string) ([]models.ForecastPoint, error) {
+	source, ok := AsForecastSource(p.Provider)
+	if !ok {
+		return nil, fmt.Errorf("%s не отдает прогноз", p.Name())
+	}
+
+	start := time.Now()
+	forecast, err := source.GetForecast(ctx, city, country)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		p.logger.Printf("%s: прогноз %s,%s - ошибка за %s: %v", p.Name(), city, country, elapsed, err)
+	} else {
+		p.logger.Printf("%s: прогноз %s,%s - %d точек за %s", p.Name(), city, country, len(forecast), elapsed)
+	}
+	return forecast, err
+}
+
+// TimingStats накапливает статистику времени ответа провайдеров
+type TimingStats struct {
+	mu    sync.Mutex
+	stats map[string]*TimingSnapshot
+}
+
+// TimingSnapshot статистика одного провайдера
+type TimingSnapshot struct {
+	Requests    int           `json:"requests"`
+	Errors      int           `json:"errors"`
+	Total       time.Duration `json:"-"`
+	AverageMs   float64       `json:"average_ms"`
+	MaxMs       float64       `json:"max_ms"`
+	LastMs      float64       `json:"last_ms"`
+	LastRequest time.Time     `json:"last_request"`
+}
+
+// DefaultTimingStats статистика, которую использует middleware "timing" из реестра
+var DefaultTimingStats = NewTimingStats()
+
+// NewTimingStats создает пустую статистику
+func NewTimingStats() *TimingStats {
+	return &TimingStats{
+		stats: make(map[string]*TimingSnapshot),
+	}
+}
+
+// Observe учитывает один запрос к провайдеру
+func (t *TimingStats) Observe(provider string, elapsed time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.stats[provider]
+	if !ok {
+		s = &TimingSnapshot{}
+		t.stats[provider] = s
+	}
+
+	ms := float64(elapsed) / float64(time.Millisecond)
+	s.Requests++
+	if err != nil {
+		s.Errors++
+	}
+	s.Total += elapsed
+	s.AverageMs = float64(s.Total) / float64(time.Millisecond) / float64(s.Requests)
+	s.LastMs = ms
+	if ms > s.MaxMs {
+		s.MaxMs = ms
+	}
+	s.LastRequest = time.Now()
+}
+
+// Snapshot возвращает копию статистики по провайдерам
+func (t *TimingStats) Snapshot() map[string]TimingSnapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := make(map[string]TimingSnapshot, len(t.stats))
+	for provider, s := range t.stats {
+		snapshot[provider] = *s
+	}
+	return snapshot
+}
+
+func (p *loggingProvider) GetAlerts(ctx context.Context, city, country string) ([]models.Alert, error) {
+	source, ok := AsAlertSource(p.Provider)
+	if !ok {
+		return nil, fmt.Errorf("%s не отдает предупреждения", p.Name())
+	}
+
+	start := time.Now()
+	alerts, err := source.GetAlerts(ctx, city, country)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		p.logger.Printf("%s: предупреждения %s,%s - ошибка за %s: %v", p.Name(), city, country, elapsed, err)
+	} else {
+		p.logger.Printf("%s: предупреждения %s,%s - %d за %s", p.Name(), city, country, len(alerts), elapsed)
+	}
+	return alerts, err
+}
+
+// Timing замеряет время ответа провайдера и учитывает его в stats. Запросы
+// прогноза и предупреждений проходят мимо: статистика относится только к
+// текущей погоде.
+func Timing(stats *TimingStats) Middleware {
+	return func(p Provider) Provider {
+		return &timingProvider{Provider: p, stats: stats}
+	}
+}
+
+type timingProvider struct {
+	Provider
+	stats *TimingStats
+}
+
+func (p *timingProvider) Unwrap() Provider { return p.Provider }
+
+func (p *timingProvider) GetWeather(ctx context.Context, city, country string) (*models.WeatherData, error) {
+	start := time.Now()
+	weather, err := p.Provider.GetWeather(ctx, city, country)
+	p.stats.Observe(p.Name(), time.Since(start), err)
+	return weather, err
+}
@@ -0,0 +1,360 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"weather-aggregator/conditions"
+	"weather-aggregator/geo"
+	"weather-aggregator/models"
+)
+
+// ErrUnsupportedLocation возвращается провайдером, который не обслуживает регион
+var ErrUnsupportedLocation = errors.New("провайдер не обслуживает этот регион")
+
+// nwsMaxStations сколько ближайших станций пробовать, если у ближайшей нет свежих данных
+const nwsMaxStations = 3
+
+// nwsMaxObservationAge возраст, после которого последнее наблюдение станции
+// считается устаревшим и пробуется следующая станция
+const nwsMaxObservationAge = 2 * time.Hour
+
+// NWSProvider провайдер Национальной метеослужбы США (api.weather.gov).
+// Точка сначала сопоставляется с ячейкой сетки и списком станций наблюдения
+// (/points/{lat},{lon}); это сопоставление почти не меняется и кешируется надолго.
+type NWSProvider struct {
+	userAgent string
+	client    *http.Client
+	baseURL   string
+	geocoder  geo.Geocoder
+	gridTTL   time.Duration
+
+	mu    sync.Mutex
+	grids map[string]*nwsGrid
+}
+
+// nwsGrid результат разрешения точки
+type nwsGrid struct {
+	GridID     string
+	GridX      int
+	GridY      int
+	Stations   []nwsStation
+	ResolvedAt time.Time
+}
+
+type nwsStation struct {
+	ID   string
+	Name string
+}
+
+// nwsQuantity значение с единицами WMO, например {"unitCode": "wmoUnit:degC", "value": 3.2}
+type nwsQuantity struct {
+	UnitCode string   `json:"unitCode"`
+	Value    *float64 `json:"value"`
+}
+
+// NewNWSProvider создает провайдер NWS. userAgent обязателен по условиям API
+// и должен содержать контакт, gridTTL - срок кеширования сопоставления точки.
+func NewNWSProvider(userAgent string, geocoder geo.Geocoder, gridTTL time.Duration) *NWSProvider {
+	return &NWSProvider{
+		userAgent: userAgent,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL:  "https://api.weather.gov",
+		geocoder: geocoder,
+		gridTTL:  gridTTL,
+		grids:    make(map[string]*nwsGrid),
+	}
+}
+
+// WithBaseURL переопределяет адрес API (например, для локального mock-upstream)
+func (p *NWSProvider) WithBaseURL(baseURL string) *NWSProvider {
+	if baseURL != "" {
+		p.baseURL = strings.TrimRight(baseURL, "/")
+	}
+	return p
+}
+
+func (p *NWSProvider) Name() string {
+	return "NWS"
+}
+
+func (p *NWSProvider) IsAvailable() bool {
+	return p.userAgent != "" && p.geocoder != nil
+}
+
+func (p *NWSProvider) GetWeather(ctx context.Context, city, country string) (*models.WeatherData, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("провайдер %s не настроен", p.Name())
+	}
+
+	// api.weather.gov покрывает только США и их территории
+	if !isNWSCountry(country) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedLocation, country)
+	}
+
+	loc, err := p.geocoder.Resolve(ctx, city, country)
+	if err != nil {
+		if errors.Is(err, geo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrCityNotFound, err)
+		}
+		return nil, fmt.Errorf("ошибка определения координат: %w", err)
+	}
+
+	grid, err := p.resolveGrid(ctx, loc.Lat, loc.Lon)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for i, station := range grid.Stations {
+		if i == nwsMaxStations {
+			break
+		}
+
+		weather, err := p.latestObservation(ctx, station)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		weather.Location = fmt.Sprintf("%s, %s", city, country)
+		return weather, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("нет станций наблюдения для точки")
+	}
+	return nil, lastErr
+}
+
+// resolveGrid сопоставляет точку с ячейкой сетки и станциями, используя кеш
+func (p *NWSProvider) resolveGrid(ctx context.Context, lat, lon float64) (*nwsGrid, error) {
+	// API принимает не более 4 знаков после запятой
+	point := fmt.Sprintf("%.4f,%.4f", lat, lon)
+
+	p.mu.Lock()
+	cached, ok := p.grids[point]
+	p.mu.Unlock()
+	if ok && time.Since(cached.ResolvedAt) < p.gridTTL {
+		return cached, nil
+	}
+
+	var points struct {
+		Properties struct {
+			GridID              string `json:"gridId"`
+			GridX               int    `json:"gridX"`
+			GridY               int    `json:"gridY"`
+			ObservationStations string `json:"observationStations"`
+		} `json:"properties"`
+	}
+	if err := p.getJSON(ctx, p.baseURL+"/points/"+point, &points); err != nil {
+		return nil, err
+	}
+
+	var stations struct {
+		Features []struct {
+			Properties struct {
+				StationIdentifier string `json:"stationIdentifier"`
+				Name              string `json:"name"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := p.getJSON(ctx, points.Properties.ObservationStations, &stations); err != nil {
+		return nil, fmt.Errorf("ошибка получения списка станций: %w", err)
+	}
+
+	// Станции в ответе уже упорядочены по удаленности от точки
+	grid := &nwsGrid{
+		GridID:     points.Properties.GridID,
+		GridX:      points.Properties.GridX,
+		GridY:      points.Properties.GridY,
+		ResolvedAt: time.Now(),
+	}
+	for _, f := range stations.Features {
+		grid.Stations = append(grid.Stations, nwsStation{
+			ID:   f.Properties.StationIdentifier,
+			Name: f.Properties.Name,
+		})
+	}
+	if len(grid.Stations) == 0 {
+		return nil, fmt.Errorf("нет станций наблюдения для точки %s", point)
+	}
+
+	p.mu.Lock()
+	p.grids[point] = grid
+	p.mu.Unlock()
+
+	return grid, nil
+}
+
+// latestObservation получает последнее наблюдение станции
+func (p *NWSProvider) latestObservation(ctx context.Context, station nwsStation) (*models.WeatherData, error) {
+	var result struct {
+		Properties struct {
+			Timestamp          time.Time   `json:"timestamp"`
+			TextDescription    string      `json:"textDescription"`
+			Icon               string      `json:"icon"`
+			Temperature        nwsQuantity `json:"temperature"`
+			WindDirection      nwsQuantity `json:"windDirection"`
+			WindSpeed          nwsQuantity `json:"windSpeed"`
+			BarometricPressure nwsQuantity `json:"barometricPressure"`
+			SeaLevelPressure   nwsQuantity `json:"seaLevelPressure"`
+			RelativeHumidity   nwsQuantity `json:"relativeHumidity"`
+			WindChill          nwsQuantity `json:"windChill"`
+			HeatIndex          nwsQuantity `json:"heatIndex"`
+			Dewpoint           nwsQuantity `json:"dewpoint"`
+			WindGust           nwsQuantity `json:"windGust"`
+			Visibility         nwsQuantity `json:"visibility"`
+			PrecipitationHour  nwsQuantity `json:"precipitationLastHour"`
+		} `json:"properties"`
+	}
+
+	reqURL := fmt.Sprintf("%s/stations/%s/observations/latest", p.baseURL, station.ID)
+	if err := p.getJSON(ctx, reqURL, &result); err != nil {
+		return nil, fmt.Errorf("станция %s: %w", station.ID, err)
+	}
+
+	obs := result.Properties
+	// Станция, переставшая передавать данные, продолжает отдавать последнее наблюдение
+	if obs.Timestamp.IsZero() {
+		return nil, fmt.Errorf("станция %s не сообщает время наблюдения", station.ID)
+	}
+	if age := time.Since(obs.Timestamp); age > nwsMaxObservationAge {
+		return nil, fmt.Errorf("наблюдение станции %s устарело (%s)", station.ID, age.Round(time.Minute))
+	}
+
+	temp, ok := obs.Temperature.metric()
+	if !ok {
+		return nil, fmt.Errorf("станция %s не сообщает температуру", station.ID)
+	}
+
+	humidity := obs.RelativeHumidity.optional()
+	windSpeed := obs.WindSpeed.optional()
+	windDirection := obs.WindDirection.optional()
+
+	pressure := obs.SeaLevelPressure.optional()
+	if pressure == nil {
+		pressure = obs.BarometricPressure.optional()
+	}
+
+	// NWS сообщает ощущаемую температуру только когда она отличается от фактической
+	feels := obs.WindChill.optional()
+	if feels == nil {
+		feels = obs.HeatIndex.optional()
+	}
+	if feels == nil {
+		feels = feelsLike(&temp, humidity, windSpeed)
+	}
+
+	weather := &models.WeatherData{
+		Provider:      p.Name(),
+		Temperature:   &temp,
+		FeelsLike:     feels,
+		Humidity:      humidity,
+		Pressure:      pressure,
+		WindSpeed:     windSpeed,
+		WindDirection: windDirection,
+		Description:   obs.TextDescription,
+		Condition:     conditions.FromNWSIcon(obs.Icon),
+		Icon:          obs.Icon,
+		Station:       station.ID,
+		Timestamp:     obs.Timestamp,
+		Units:         "metric",
+		DewPoint:      obs.Dewpoint.optional(),
+		WindGust:      obs.WindGust.optional(),
+		Precipitation: obs.PrecipitationHour.optional(),
+	}
+	if visibility := obs.Visibility.optional(); visibility != nil {
+		weather.Visibility = models.Float(*visibility / 1000)
+	}
+
+	return weather, nil
+}
+
+// getJSON выполняет GET запрос к API и разбирает GeoJSON ответ
+func (p *NWSProvider) getJSON(ctx context.Context, reqURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("User-Agent", p.userAgent)
+	req.Header.Set("Accept", "application/geo+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка HTTP запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiError)
+
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s", ErrUnsupportedLocation, apiError.Detail)
+		}
+		if apiError.Detail != "" {
+			return fmt.Errorf("ошибка NWS: %s", apiError.Detail)
+		}
+		return fmt.Errorf("ошибка API: статус %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+	return nil
+}
+
+// metric переводит значение в единицы models.WeatherData:
+// °C, м/с, hPa, %, градусы
+func (q nwsQuantity) metric() (float64, bool) {
+	if q.Value == nil {
+		return 0, false
+	}
+	v := *q.Value
+
+	switch strings.TrimPrefix(q.UnitCode, "wmoUnit:") {
+	case "degC", "percent", "degree_(angle)", "m_s-1", "hPa", "m", "mm":
+		return v, true
+	case "degF":
+		return (v - 32) * 5 / 9, true
+	case "K":
+		return v - 273.15, true
+	case "km_h-1":
+		return v / 3.6, true
+	case "kn":
+		return v * 0.514444, true
+	case "Pa":
+		return v / 100, true
+	default:
+		return 0, false
+	}
+}
+
+// optional значение в метрических единицах или nil, если его нет
+func (q nwsQuantity) optional() *float64 {
+	v, ok := q.metric()
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+// isNWSCountry проверяет, что страна входит в зону ответственности NWS
+func isNWSCountry(country string) bool {
+	switch strings.ToUpper(country) {
+	case "US", "PR", "GU", "VI", "AS", "MP":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package providers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"weather-aggregator/geo"
+	"weather-aggregator/providers"
+	"weather-aggregator/providers/providertest"
+)
+
+func TestNWSConformance(t *testing.T) {
+	providertest.Suite{
+		NewProvider: func(baseURL string) providers.Provider {
+			return providers.NewNWSProvider("weather-aggregator-test/1.0 test@example.com", geo.NewTable(), time.Hour).WithBaseURL(baseURL)
+		},
+		Upstream: providertest.NWSUpstream,
+		// api.weather.gov обслуживает только США
+		City:    "New York",
+		Country: "US",
+	}.Run(t)
+}
+
+// TestNWSStaleStationFallback проверяет, что станция с устаревшим последним
+// наблюдением пропускается и используется следующая по удаленности
+func TestNWSStaleStationFallback(t *testing.T) {
+	observed := map[string]time.Time{
+		"KOLD": time.Now().Add(-5 * time.Hour),
+		"KNEW": time.Now().Add(-20 * time.Minute),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /points/{point}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"properties": {"gridId": "OKX", "gridX": 33, "gridY": 35,
+			"observationStations": "http://%s/gridpoints/OKX/33,35/stations"}}`, r.Host)
+	})
+	mux.HandleFunc("GET /gridpoints/OKX/33,35/stations", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"features": [
+			{"properties": {"stationIdentifier": "KOLD", "name": "Stale"}},
+			{"properties": {"stationIdentifier": "KNEW", "name": "Fresh"}}]}`)
+	})
+	mux.HandleFunc("GET /stations/{id}/observations/latest", func(w http.ResponseWriter, r *http.Request) {
+		at, ok := observed[r.PathValue("id")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"properties": {"timestamp": %q,
+			"temperature": {"unitCode": "wmoUnit:degC", "value": 3.3}}}`, at.UTC().Format(time.RFC3339))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := providers.NewNWSProvider("weather-aggregator-test/1.0 test@example.com", geo.NewTable(), time.Hour).WithBaseURL(server.URL)
+	data, err := p.GetWeather(context.Background(), "New York", "US")
+	if err != nil {
+		t.Fatalf("GetWeather: %v", err)
+	}
+	if data.Station != "KNEW" {
+		t.Errorf("Station = %q, ожидается следующая станция KNEW", data.Station)
+	}
+	if !data.Timestamp.Equal(observed["KNEW"].Truncate(time.Second)) {
+		t.Errorf("Timestamp = %s, ожидается %s", data.Timestamp, observed["KNEW"])
+	}
+
+	// Если устарели все станции, ответа нет
+	observed["KNEW"] = time.Now().Add(-3 * time.Hour)
+	if data, err := p.GetWeather(context.Background(), "New York", "US"); err == nil {
+		t.Errorf("ожидается ошибка для устаревших наблюдений, получено %+v", data)
+	}
+}
@@ -4,16 +4,48 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
+
+	"weather-aggregator/airquality"
+	"weather-aggregator/conditions"
 	"weather-aggregator/models"
 )
 
+// Пути эндпоинтов OpenWeatherMap
+const (
+	openWeatherCurrentPath      = "/data/2.5/weather"
+	openWeatherForecastPath     = "/data/2.5/forecast"
+	openWeatherAirPollutionPath = "/data/2.5/air_pollution"
+	openWeatherOneCallPath      = "/data/3.0/onecall"
+	openWeatherGeocodingPath    = "/geo/1.0/direct"
+)
+
+// Качество воздуха OpenWeatherMap обновляет раз в час, поэтому отдельный
+// запрос делается не при каждом запросе погоды, а не чаще этих интервалов
+const (
+	openWeatherAirQualityTTL   = time.Hour
+	openWeatherAirQualityRetry = 10 * time.Minute // после ошибки
+)
+
 type OpenWeatherProvider struct {
 	apiKey  string
 	client  *http.Client
 	baseURL string
+	oneCall bool
+
+	airMu    sync.Mutex
+	airCache map[string]airQualityEntry
+}
+
+// airQualityEntry качество воздуха точки; nil - запрос не удался
+type airQualityEntry struct {
+	aq      *models.AirQuality
+	expires time.Time
 }
 
 func NewOpenWeatherProvider(apiKey string) *OpenWeatherProvider {
@@ -22,10 +54,26 @@ func NewOpenWeatherProvider(apiKey string) *OpenWeatherProvider {
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		baseURL: "https://api.openweathermap.org/data/2.5/weather",
+		baseURL:  "https://api.openweathermap.org",
+		airCache: make(map[string]airQualityEntry),
 	}
 }
 
+// WithBaseURL переопределяет адрес API (например, для локального mock-upstream)
+func (p *OpenWeatherProvider) WithBaseURL(baseURL string) *OpenWeatherProvider {
+	if baseURL != "" {
+		p.baseURL = strings.TrimRight(baseURL, "/")
+	}
+	return p
+}
+
+// WithOneCall включает предупреждения из One Call API 3.0, для которого
+// нужна отдельная подписка
+func (p *OpenWeatherProvider) WithOneCall(enabled bool) *OpenWeatherProvider {
+	p.oneCall = enabled
+	return p
+}
+
 func (p *OpenWeatherProvider) Name() string {
 	return "OpenWeatherMap"
 }
@@ -46,7 +94,7 @@ func (p *OpenWeatherProvider) GetWeather(ctx context.Context, city, country stri
 	query.Set("units", "metric") // метрическая система
 	query.Set("lang", "ru")
 
-	reqURL := fmt.Sprintf("%s?%s", p.baseURL, query.Encode())
+	reqURL := fmt.Sprintf("%s%s?%s", p.baseURL, openWeatherCurrentPath, query.Encode())
 
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
@@ -61,7 +109,7 @@ func (p *OpenWeatherProvider) GetWeather(ctx context.Context, city, country stri
 
 	if resp.StatusCode != http.StatusOK {
 		if resp.StatusCode == http.StatusNotFound {
-			return nil, fmt.Errorf("город не найден")
+			return nil, ErrCityNotFound
 		}
 		if resp.StatusCode == http.StatusUnauthorized {
 			return nil, fmt.Errorf("неверный API ключ")
@@ -71,18 +119,34 @@ func (p *OpenWeatherProvider) GetWeather(ctx context.Context, city, country stri
 
 	// Парсим ответ
 	var result struct {
-		Name string `json:"name"`
+		Name  string `json:"name"`
+		Coord struct {
+			Lat float64 `json:"lat"`
+			Lon float64 `json:"lon"`
+		} `json:"coord"`
 		Main struct {
-			Temp      float64 `json:"temp"`
-			FeelsLike float64 `json:"feels_like"`
-			Humidity  int     `json:"humidity"`
-			Pressure  int     `json:"pressure"`
+			Temp      *float64 `json:"temp"`
+			FeelsLike *float64 `json:"feels_like"`
+			Humidity  *float64 `json:"humidity"`
+			Pressure  *float64 `json:"pressure"`
 		} `json:"main"`
 		Wind struct {
-			Speed float64 `json:"speed"`
-			Deg   int     `json:"deg"`
+			Speed *float64 `json:"speed"`
+			Deg   *float64 `json:"deg"`
+			Gust  *float64 `json:"gust"`
 		} `json:"wind"`
+		Clouds struct {
+			All *float64 `json:"all"`
+		} `json:"clouds"`
+		Visibility *float64 `json:"visibility"` // метры, не более 10 км
+		Rain       struct {
+			OneHour *float64 `json:"1h"`
+		} `json:"rain"`
+		Snow struct {
+			OneHour *float64 `json:"1h"`
+		} `json:"snow"`
 		Weather []struct {
+			ID          int    `json:"id"`
 			Description string `json:"description"`
 			Icon        string `json:"icon"`
 		} `json:"weather"`
@@ -110,12 +174,265 @@ func (p *OpenWeatherProvider) GetWeather(ctx context.Context, city, country stri
 		WindSpeed:     result.Wind.Speed,
 		WindDirection: result.Wind.Deg,
 		Description:   result.Weather[0].Description,
+		Condition:     conditions.FromOpenWeather(result.Weather[0].ID),
 		Icon:          result.Weather[0].Icon,
-		Sunrise:       time.Unix(result.Sys.Sunrise, 0),
-		Sunset:        time.Unix(result.Sys.Sunset, 0),
 		Timestamp:     time.Now(),
 		Units:         "metric",
+		WindGust:      result.Wind.Gust,
+		CloudCover:    result.Clouds.All,
+		Rain:          result.Rain.OneHour,
+		Snow:          result.Snow.OneHour,
+	}
+	// В полярный день и полярную ночь восхода и заката нет
+	if result.Sys.Sunrise != 0 && result.Sys.Sunset != 0 {
+		sunrise, sunset := time.Unix(result.Sys.Sunrise, 0), time.Unix(result.Sys.Sunset, 0)
+		weather.Sunrise, weather.Sunset = &sunrise, &sunset
+	}
+	if result.Visibility != nil {
+		weather.Visibility = models.Float(*result.Visibility / 1000)
 	}
+	// Блоки rain и snow есть только при осадках; без них осадков нет
+	precipitation := 0.0
+	if result.Rain.OneHour != nil {
+		precipitation += *result.Rain.OneHour
+	}
+	if result.Snow.OneHour != nil {
+		precipitation += *result.Snow.OneHour
+	}
+	weather.Precipitation = &precipitation
+
+	// Качество воздуха - отдельный запрос; без него погода остается полезной
+	weather.AirQuality = p.cachedAirPollution(ctx, result.Coord.Lat, result.Coord.Lon)
 
 	return weather, nil
 }
+
+// cachedAirPollution качество воздуха точки из кеша или новым запросом не
+// чаще openWeatherAirQualityTTL; после ошибки запрос повторяется через
+// openWeatherAirQualityRetry
+func (p *OpenWeatherProvider) cachedAirPollution(ctx context.Context, lat, lon float64) *models.AirQuality {
+	key := fmt.Sprintf("%.2f,%.2f", lat, lon)
+
+	p.airMu.Lock()
+	entry, found := p.airCache[key]
+	p.airMu.Unlock()
+	if found && time.Now().Before(entry.expires) {
+		return entry.aq
+	}
+
+	aq, err := p.airPollution(ctx, lat, lon)
+	entry = airQualityEntry{aq: aq, expires: time.Now().Add(openWeatherAirQualityTTL)}
+	if err != nil {
+		log.Printf("%s: качество воздуха недоступно: %v", p.Name(), err)
+		// Отмена запроса погоды - не сбой API, ее не запоминаем
+		if ctx.Err() != nil {
+			return nil
+		}
+		entry.expires = time.Now().Add(openWeatherAirQualityRetry)
+	}
+
+	p.airMu.Lock()
+	p.airCache[key] = entry
+	p.airMu.Unlock()
+
+	return aq
+}
+
+// airPollution получает текущие концентрации загрязнителей для точки
+func (p *OpenWeatherProvider) airPollution(ctx context.Context, lat, lon float64) (*models.AirQuality, error) {
+	query := url.Values{}
+	query.Set("lat", fmt.Sprintf("%.4f", lat))
+	query.Set("lon", fmt.Sprintf("%.4f", lon))
+	query.Set("appid", p.apiKey)
+
+	reqURL := fmt.Sprintf("%s%s?%s", p.baseURL, openWeatherAirPollutionPath, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка HTTP запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ошибка API: статус %d", resp.StatusCode)
+	}
+
+	// Концентрации в мкг/м³; собственный индекс main.aqi (1-5) не используем
+	var result struct {
+		List []struct {
+			Components struct {
+				CO   *float64 `json:"co"`
+				NO2  *float64 `json:"no2"`
+				O3   *float64 `json:"o3"`
+				SO2  *float64 `json:"so2"`
+				PM25 *float64 `json:"pm2_5"`
+				PM10 *float64 `json:"pm10"`
+			} `json:"components"`
+		} `json:"list"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("нет данных о качестве воздуха")
+	}
+
+	c := result.List[0].Components
+	return airquality.Fill(&models.AirQuality{
+		PM25: c.PM25,
+		PM10: c.PM10,
+		O3:   c.O3,
+		NO2:  c.NO2,
+		SO2:  c.SO2,
+		CO:   c.CO,
+	}), nil
+}
+
+// GetAlerts возвращает предупреждения One Call API. Без подписки (WithOneCall)
+// предупреждений нет.
+func (p *OpenWeatherProvider) GetAlerts(ctx context.Context, city, country string) ([]models.Alert, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("провайдер %s не настроен", p.Name())
+	}
+	if !p.oneCall {
+		return nil, nil
+	}
+
+	var places []struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	}
+	query := url.Values{}
+	query.Set("q", fmt.Sprintf("%s,%s", city, country))
+	query.Set("limit", "1")
+	query.Set("appid", p.apiKey)
+	if err := p.get(ctx, openWeatherGeocodingPath, query, &places); err != nil {
+		return nil, err
+	}
+	if len(places) == 0 {
+		return nil, ErrCityNotFound
+	}
+
+	var result struct {
+		Alerts []struct {
+			SenderName  string   `json:"sender_name"`
+			Event       string   `json:"event"`
+			Start       int64    `json:"start"`
+			End         int64    `json:"end"`
+			Description string   `json:"description"`
+			Tags        []string `json:"tags"`
+		} `json:"alerts"`
+	}
+	query = url.Values{}
+	query.Set("lat", fmt.Sprintf("%.4f", places[0].Lat))
+	query.Set("lon", fmt.Sprintf("%.4f", places[0].Lon))
+	query.Set("exclude", "current,minutely,hourly,daily")
+	query.Set("lang", "ru")
+	query.Set("appid", p.apiKey)
+	if err := p.get(ctx, openWeatherOneCallPath, query, &result); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	alerts := make([]models.Alert, 0, len(result.Alerts))
+	for _, a := range result.Alerts {
+		onset := time.Unix(a.Start, 0)
+
+		// One Call не сообщает серьезность и срочность
+		urgency := models.UrgencyExpected
+		if !onset.After(now) {
+			urgency = models.UrgencyImmediate
+		}
+
+		alert := models.Alert{
+			Event:       a.Event,
+			Description: strings.TrimSpace(a.Description),
+			Severity:    severityFromText(a.Event),
+			Urgency:     urgency,
+			Onset:       onset,
+			Expires:     time.Unix(a.End, 0),
+			Source:      p.Name(),
+		}
+		if a.SenderName != "" {
+			alert.Headline = fmt.Sprintf("%s (%s)", a.Event, a.SenderName)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// GetForecast возвращает прогноз на 5 суток с шагом 3 часа
+func (p *OpenWeatherProvider) GetForecast(ctx context.Context, city, country string) ([]models.ForecastPoint, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("провайдер %s не настроен", p.Name())
+	}
+
+	query := url.Values{}
+	query.Set("q", fmt.Sprintf("%s,%s", city, country))
+	query.Set("appid", p.apiKey)
+	query.Set("units", "metric")
+
+	var result struct {
+		List []struct {
+			Dt   int64 `json:"dt"`
+			Main struct {
+				Temp     *float64 `json:"temp"`
+				Humidity *float64 `json:"humidity"`
+				Pressure *float64 `json:"pressure"`
+			} `json:"main"`
+			Wind struct {
+				Speed *float64 `json:"speed"`
+			} `json:"wind"`
+		} `json:"list"`
+	}
+	if err := p.get(ctx, openWeatherForecastPath, query, &result); err != nil {
+		return nil, err
+	}
+
+	points := make([]models.ForecastPoint, 0, len(result.List))
+	for _, item := range result.List {
+		points = append(points, models.ForecastPoint{
+			Time:        time.Unix(item.Dt, 0),
+			Temperature: item.Main.Temp,
+			Humidity:    item.Main.Humidity,
+			Pressure:    item.Main.Pressure,
+			WindSpeed:   item.Wind.Speed,
+		})
+	}
+	return points, nil
+}
+
+// get выполняет GET запрос к API и разбирает JSON ответ
+func (p *OpenWeatherProvider) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	reqURL := fmt.Sprintf("%s%s?%s", p.baseURL, path, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка HTTP запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("неверный API ключ или нет подписки")
+		}
+		return fmt.Errorf("ошибка API: статус %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+	return nil
+}
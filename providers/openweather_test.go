@@ -0,0 +1,58 @@
+package providers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"weather-aggregator/mockupstream"
+	"weather-aggregator/providers"
+	"weather-aggregator/providers/providertest"
+)
+
+func TestOpenWeatherConformance(t *testing.T) {
+	providertest.Suite{
+		NewProvider: func(baseURL string) providers.Provider {
+			return providers.NewOpenWeatherProvider("test").WithBaseURL(baseURL)
+		},
+		Upstream: providertest.OpenWeatherUpstream,
+	}.Run(t)
+}
+
+// TestOpenWeatherAirQualityCached проверяет, что качество воздуха не
+// запрашивается заново при каждом запросе погоды
+func TestOpenWeatherAirQualityCached(t *testing.T) {
+	upstream := mockupstream.NewServer(&mockupstream.Scenario{
+		Cities: map[string]mockupstream.CityScenario{
+			"Москва": {Observation: providertest.Observation{Temperature: 5, Humidity: 80}, Lat: 55.7558, Lon: 37.6173},
+		},
+	})
+	var weather, air atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/air_pollution"):
+			air.Add(1)
+		case strings.HasSuffix(r.URL.Path, "/weather"):
+			weather.Add(1)
+		}
+		upstream.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	provider := providers.NewOpenWeatherProvider("test").WithBaseURL(server.URL)
+	for i := 0; i < 3; i++ {
+		data, err := provider.GetWeather(context.Background(), "Москва", "RU")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data.AirQuality == nil {
+			t.Fatalf("запрос %d: нет качества воздуха", i+1)
+		}
+	}
+	if weather.Load() != 3 || air.Load() != 1 {
+		t.Errorf("запросов погоды %d, качества воздуха %d; ожидалось 3 и 1", weather.Load(), air.Load())
+	}
+}
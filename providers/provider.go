@@ -2,9 +2,14 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"weather-aggregator/models"
 )
 
+// ErrCityNotFound возвращается провайдером, если внешний API не знает город.
+// Проверяйте через errors.Is: провайдеры могут оборачивать ошибку подробностями.
+var ErrCityNotFound = errors.New("город не найден")
+
 // Provider интерфейс для всех погодных провайдеров
 type Provider interface {
 	Name() string
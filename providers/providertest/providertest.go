@@ -0,0 +1,432 @@
+// Package providertest содержит набор проверок соответствия для реализаций
+// providers.Provider. Набор запускает провайдер против локального фейкового
+// upstream и проверяет то, на что рассчитывает aggregator.Aggregator:
+// отмену по контексту, метрические единицы, заполнение Provider/Timestamp
+// и типизированную ошибку providers.ErrCityNotFound.
+//
+// Использование в тестах провайдера:
+//
+//	func TestConformance(t *testing.T) {
+//		providertest.Suite{
+//			NewProvider: func(baseURL string) providers.Provider {
+//				return providers.NewOpenWeatherProvider("test").WithBaseURL(baseURL)
+//			},
+//			Upstream: providertest.OpenWeatherUpstream,
+//		}.Run(t)
+//	}
+package providertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"weather-aggregator/mockupstream"
+	"weather-aggregator/models"
+	"weather-aggregator/providers"
+)
+
+// Observation эталонные значения, которые фейковый upstream отдает провайдеру
+type Observation = mockupstream.Observation
+
+// Upstream создает обработчик, эмулирующий внешний API провайдера: для city
+// он отвечает данными obs, для остальных городов - ответом "город не найден"
+type Upstream func(city string, obs Observation) http.Handler
+
+// OpenWeatherUpstream эмулирует API OpenWeatherMap
+func OpenWeatherUpstream(city string, obs Observation) http.Handler {
+	return scenarioUpstream(city, obs)
+}
+
+// WeatherAPIUpstream эмулирует API WeatherAPI
+func WeatherAPIUpstream(city string, obs Observation) http.Handler {
+	return scenarioUpstream(city, obs)
+}
+
+// MetNoUpstream эмулирует API met.no. Api отвечает для любой точки, поэтому
+// "город не найден" должен возвращать геокодер провайдера.
+func MetNoUpstream(city string, obs Observation) http.Handler {
+	return mockupstream.NewServer(&mockupstream.Scenario{
+		Defaults: &obs,
+	})
+}
+
+// NWSUpstream эмулирует api.weather.gov: сопоставление точки, список из
+// одной станции и ее последнее наблюдение в единицах WMO (км/ч, Па).
+// "Город не найден" возвращает геокодер провайдера.
+func NWSUpstream(city string, obs Observation) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /points/{point}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"properties": map[string]interface{}{
+				"gridId":              "TST",
+				"gridX":               1,
+				"gridY":               1,
+				"observationStations": "http://" + r.Host + "/gridpoints/TST/1,1/stations",
+			},
+		})
+	})
+	mux.HandleFunc("GET /gridpoints/TST/1,1/stations", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"features": []interface{}{
+				map[string]interface{}{"properties": map[string]string{"stationIdentifier": "KTST", "name": city}},
+			},
+		})
+	})
+	mux.HandleFunc("GET /stations/KTST/observations/latest", func(w http.ResponseWriter, r *http.Request) {
+		quantity := func(unit string, v float64) map[string]interface{} {
+			return map[string]interface{}{"unitCode": "wmoUnit:" + unit, "value": v}
+		}
+		writeJSON(w, map[string]interface{}{
+			"properties": map[string]interface{}{
+				"timestamp":        time.Now().UTC().Format(time.RFC3339),
+				"textDescription":  obs.Description,
+				"temperature":      quantity("degC", obs.Temperature),
+				"windChill":        quantity("degC", obs.FeelsLike),
+				"relativeHumidity": quantity("percent", float64(obs.Humidity)),
+				"seaLevelPressure": quantity("Pa", float64(obs.Pressure)*100),
+				"windSpeed":        quantity("km_h-1", obs.WindSpeed*3.6),
+				"windDirection":    quantity("degree_(angle)", float64(obs.WindDirection)),
+			},
+		})
+	})
+	return mux
+}
+
+// METARUpstream эмулирует источник сводок METAR: на любой запрос отдает
+// свежую сводку станции из параметра ids. Точные температура, точка росы и
+// давление передаются в замечаниях (T-группа и SLP), ветер - в узлах.
+// "Город не найден" возвращает геокодер провайдера.
+func METARUpstream(city string, obs Observation) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Точка росы по формуле Магнуса с теми же коэффициентами, что в metar
+		const a, b = 17.625, 243.04
+		gamma := math.Log(float64(obs.Humidity)/100) + a*obs.Temperature/(b+obs.Temperature)
+		dew := b * gamma / (a - gamma)
+
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "%s %sZ %03d%02dKT 9999 OVC020 %s/%s Q%04d RMK T%s%s SLP%03d\n",
+			r.URL.Query().Get("ids"), time.Now().UTC().Format("021504"),
+			obs.WindDirection, int(math.Round(obs.WindSpeed/0.514444)),
+			metarTemp(obs.Temperature), metarTemp(dew), obs.Pressure,
+			metarTenths(obs.Temperature), metarTenths(dew), int(math.Round(float64(obs.Pressure)*10))%1000)
+	})
+}
+
+// metarTemp температура в основной группе сводки: целые градусы, M - минус
+func metarTemp(v float64) string {
+	v = math.Round(v)
+	if v < 0 {
+		return fmt.Sprintf("M%02.0f", -v)
+	}
+	return fmt.Sprintf("%02.0f", v)
+}
+
+// metarTenths температура в T-группе замечаний: знак (1 - минус) и десятые доли
+func metarTenths(v float64) string {
+	sign := 0
+	if v < 0 {
+		sign, v = 1, -v
+	}
+	return fmt.Sprintf("%d%03.0f", sign, math.Round(v*10))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func scenarioUpstream(city string, obs Observation) http.Handler {
+	return mockupstream.NewServer(&mockupstream.Scenario{
+		Cities: map[string]mockupstream.CityScenario{
+			city: {Observation: obs},
+		},
+	})
+}
+
+// Suite набор проверок соответствия для одного провайдера
+type Suite struct {
+	// NewProvider создает провайдер, направленный на адрес фейкового upstream
+	NewProvider func(baseURL string) providers.Provider
+	// Upstream эмулирует внешний API провайдера
+	Upstream Upstream
+
+	// City и Country город, который знает фейковый upstream (по умолчанию Москва, RU)
+	City    string
+	Country string
+	// Observation эталонные данные (по умолчанию DefaultObservation)
+	Observation *Observation
+	// Tolerance допустимое отклонение при сравнении значений (по умолчанию 0.5)
+	Tolerance float64
+	// ComputedFeelsLike провайдер вычисляет ощущаемую температуру сам, а не
+	// получает ее от API; тогда FeelsLike не сравнивается с эталоном
+	ComputedFeelsLike bool
+	// CancelTimeout время, за которое провайдер обязан вернуться после отмены
+	// контекста (по умолчанию 2 секунды)
+	CancelTimeout time.Duration
+	// Offline провайдер отдает данные, заранее принятые от станций или
+	// датчиков, и не обращается к upstream при запросе; проверки отмены
+	// контекста для него пропускаются
+	Offline bool
+}
+
+// DefaultObservation эталонные данные по умолчанию. Значения подобраны так,
+// чтобы ошибки в единицах (км/ч вместо м/с, Фаренгейт вместо Цельсия) были заметны.
+var DefaultObservation = Observation{
+	Temperature:   -7.5,
+	FeelsLike:     -12.0,
+	Humidity:      83,
+	Pressure:      1024,
+	WindSpeed:     5.0,
+	WindDirection: 315,
+	Description:   "небольшой снег",
+}
+
+// Run запускает все проверки как подтесты t
+func (s Suite) Run(t *testing.T) {
+	t.Helper()
+	s = s.withDefaults()
+
+	t.Run("Identity", s.testIdentity)
+	t.Run("MetricUnits", s.testMetricUnits)
+	t.Run("ProviderAndTimestamp", s.testProviderAndTimestamp)
+	t.Run("NotFound", s.testNotFound)
+	t.Run("ContextCancel", s.testContextCancel)
+	t.Run("ContextDeadline", s.testContextDeadline)
+	t.Run("Concurrent", s.testConcurrent)
+}
+
+func (s Suite) withDefaults() Suite {
+	if s.City == "" {
+		s.City = "Москва"
+	}
+	if s.Country == "" {
+		s.Country = "RU"
+	}
+	if s.Observation == nil {
+		obs := DefaultObservation
+		s.Observation = &obs
+	}
+	if s.Tolerance == 0 {
+		s.Tolerance = 0.5
+	}
+	if s.CancelTimeout == 0 {
+		s.CancelTimeout = 2 * time.Second
+	}
+	return s
+}
+
+// start запускает фейковый upstream и создает провайдер
+func (s Suite) start(t *testing.T, handler http.Handler) providers.Provider {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	p := s.NewProvider(server.URL)
+	if p == nil {
+		t.Fatal("NewProvider вернул nil")
+	}
+	return p
+}
+
+// fetch запрашивает погоду для известного upstream города
+func (s Suite) fetch(t *testing.T) (providers.Provider, *models.WeatherData, time.Time, time.Time) {
+	t.Helper()
+
+	p := s.start(t, s.Upstream(s.City, *s.Observation))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	before := time.Now()
+	data, err := p.GetWeather(ctx, s.City, s.Country)
+	after := time.Now()
+	if err != nil {
+		t.Fatalf("GetWeather(%q, %q): %v", s.City, s.Country, err)
+	}
+	if data == nil {
+		t.Fatal("GetWeather вернул nil без ошибки")
+	}
+	return p, data, before, after
+}
+
+func (s Suite) testIdentity(t *testing.T) {
+	p := s.start(t, s.Upstream(s.City, *s.Observation))
+
+	name := p.Name()
+	if name == "" {
+		t.Fatal("Name() вернул пустую строку")
+	}
+	if p.Name() != name {
+		t.Error("Name() должен возвращать одно и то же значение")
+	}
+	if !p.IsAvailable() {
+		t.Error("IsAvailable() = false для настроенного провайдера: Aggregator.AddProvider его пропустит")
+	}
+}
+
+func (s Suite) testMetricUnits(t *testing.T) {
+	_, data, _, _ := s.fetch(t)
+	want := s.Observation
+
+	if data.Units != "metric" {
+		t.Errorf("Units = %q, ожидается \"metric\"", data.Units)
+	}
+	s.expectClose(t, "Temperature (°C)", data.Temperature, want.Temperature)
+	if !s.ComputedFeelsLike {
+		s.expectClose(t, "FeelsLike (°C)", data.FeelsLike, want.FeelsLike)
+	}
+	s.expectClose(t, "WindSpeed (м/с)", data.WindSpeed, want.WindSpeed)
+	s.expectClose(t, "Humidity (%)", data.Humidity, float64(want.Humidity))
+	s.expectClose(t, "Pressure (hPa)", data.Pressure, float64(want.Pressure))
+}
+
+func (s Suite) testProviderAndTimestamp(t *testing.T) {
+	p, data, before, after := s.fetch(t)
+
+	if data.Provider != p.Name() {
+		t.Errorf("Provider = %q, ожидается Name() = %q", data.Provider, p.Name())
+	}
+	if data.Timestamp.IsZero() {
+		t.Fatal("Timestamp не заполнен")
+	}
+	// Провайдер может указать время наблюдения, но не время из будущего
+	if data.Timestamp.After(after.Add(time.Minute)) {
+		t.Errorf("Timestamp %s позже времени запроса %s", data.Timestamp, before)
+	}
+}
+
+func (s Suite) testNotFound(t *testing.T) {
+	p := s.start(t, s.Upstream(s.City, *s.Observation))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data, err := p.GetWeather(ctx, "Несуществующийгород", s.Country)
+	if err == nil {
+		t.Fatalf("ожидается ошибка для неизвестного города, получено %+v", data)
+	}
+	if !errors.Is(err, providers.ErrCityNotFound) {
+		t.Errorf("ошибка %q должна оборачивать providers.ErrCityNotFound", err)
+	}
+}
+
+// hangingUpstream не отвечает, пока клиент не отменит запрос
+func hangingUpstream() (http.Handler, func()) {
+	release := make(chan struct{})
+	var once sync.Once
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+	return handler, func() { once.Do(func() { close(release) }) }
+}
+
+func (s Suite) testContextCancel(t *testing.T) {
+	s.skipOffline(t)
+	handler, release := hangingUpstream()
+	p := s.start(t, handler)
+	t.Cleanup(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	err := s.expectReturn(t, p, ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ошибка %q должна оборачивать context.Canceled", err)
+	}
+}
+
+func (s Suite) testContextDeadline(t *testing.T) {
+	s.skipOffline(t)
+	handler, release := hangingUpstream()
+	p := s.start(t, handler)
+	t.Cleanup(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := s.expectReturn(t, p, ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ошибка %q должна оборачивать context.DeadlineExceeded", err)
+	}
+}
+
+func (s Suite) skipOffline(t *testing.T) {
+	if s.Offline {
+		t.Skip("провайдер не обращается к upstream при запросе")
+	}
+}
+
+// expectReturn проверяет, что GetWeather вернулся с ошибкой вскоре после отмены ctx
+func (s Suite) expectReturn(t *testing.T, p providers.Provider, ctx context.Context) error {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.GetWeather(ctx, s.City, s.Country)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("GetWeather вернул nil ошибку после отмены контекста")
+		}
+		return err
+	case <-time.After(s.CancelTimeout):
+		t.Fatalf("GetWeather не вернулся за %s после отмены контекста", s.CancelTimeout)
+		return nil
+	}
+}
+
+// testConcurrent проверяет, что провайдер можно вызывать параллельно, как это делает Aggregator
+func (s Suite) testConcurrent(t *testing.T) {
+	p := s.start(t, s.Upstream(s.City, *s.Observation))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.GetWeather(ctx, s.City, s.Country); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("параллельный вызов GetWeather: %v", err)
+	}
+}
+
+func (s Suite) expectClose(t *testing.T, field string, got *float64, want float64) {
+	t.Helper()
+	if got == nil {
+		t.Errorf("%s не заполнено, ожидается %v", field, want)
+		return
+	}
+	if math.IsNaN(*got) || math.Abs(*got-want) > s.Tolerance {
+		t.Errorf("%s = %v, ожидается %v ± %v", field, *got, want, s.Tolerance)
+	}
+}
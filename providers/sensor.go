@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"weather-aggregator/geo"
+	"weather-aggregator/models"
+	"weather-aggregator/sensors"
+)
+
+// SensorProvider отдает свежие значения MQTT датчиков локации, если
+// запрошенный город находится в ее радиусе
+type SensorProvider struct {
+	store    *sensors.Store
+	location sensors.Location
+	maxAge   time.Duration
+	geocoder geo.Geocoder
+}
+
+// NewSensorProvider создает провайдер для локации из конфигурации датчиков.
+// Значения старше maxAge не используются.
+func NewSensorProvider(store *sensors.Store, location sensors.Location, maxAge time.Duration, geocoder geo.Geocoder) *SensorProvider {
+	return &SensorProvider{
+		store:    store,
+		location: location,
+		maxAge:   maxAge,
+		geocoder: geocoder,
+	}
+}
+
+func (p *SensorProvider) Name() string {
+	return "MQTT " + p.location.ID
+}
+
+func (p *SensorProvider) IsAvailable() bool {
+	return p.store != nil && p.geocoder != nil
+}
+
+func (p *SensorProvider) GetWeather(ctx context.Context, city, country string) (*models.WeatherData, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("провайдер %s не настроен", p.Name())
+	}
+
+	loc, err := p.geocoder.Resolve(ctx, city, country)
+	if err != nil {
+		if errors.Is(err, geo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrCityNotFound, err)
+		}
+		return nil, fmt.Errorf("ошибка определения координат: %w", err)
+	}
+
+	if distance := geo.Distance(loc.Lat, loc.Lon, p.location.Lat, p.location.Lon); distance > p.location.RadiusKm {
+		return nil, fmt.Errorf("%w: локация %s в %.0f км", ErrUnsupportedLocation, p.location.ID, distance)
+	}
+
+	values := p.store.Latest(p.location.ID, p.maxAge)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("нет свежих значений датчиков для %s", p.location.ID)
+	}
+
+	measurement := func(field string) *float64 {
+		if v, ok := values[field]; ok {
+			return models.Float(v.Value)
+		}
+		return nil
+	}
+	temp := measurement(sensors.FieldTemperature)
+	humidity := measurement(sensors.FieldHumidity)
+	windSpeed := measurement(sensors.FieldWindSpeed)
+
+	// Время наблюдения - самое старое из использованных значений, станция -
+	// топик температуры или любого другого датчика
+	var observed time.Time
+	var topic string
+	for field, v := range values {
+		if observed.IsZero() || v.Received.Before(observed) {
+			observed = v.Received
+		}
+		if topic == "" || field == sensors.FieldTemperature {
+			topic = v.Topic
+		}
+	}
+
+	return &models.WeatherData{
+		Provider:      p.Name(),
+		Location:      fmt.Sprintf("%s, %s", city, country),
+		Temperature:   temp,
+		FeelsLike:     feelsLike(temp, humidity, windSpeed),
+		Humidity:      humidity,
+		Pressure:      measurement(sensors.FieldPressure),
+		WindSpeed:     windSpeed,
+		WindDirection: measurement(sensors.FieldWindDirection),
+		Station:       topic,
+		Timestamp:     observed,
+		Units:         "metric",
+	}, nil
+}
@@ -0,0 +1,54 @@
+package providers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"weather-aggregator/geo"
+	"weather-aggregator/mqtt"
+	"weather-aggregator/providers"
+	"weather-aggregator/providers/providertest"
+	"weather-aggregator/sensors"
+)
+
+func TestSensorConformance(t *testing.T) {
+	moscow, err := geo.NewTable().Resolve(context.Background(), "Москва", "RU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	location := sensors.Location{ID: "home", Lat: moscow.Lat, Lon: moscow.Lon, RadiusKm: 25}
+	topics := []sensors.Topic{
+		{Topic: "zigbee2mqtt/balcony", Location: "home", Format: sensors.FormatZigbee2MQTT},
+		{Topic: "weather/wind", Location: "home", Format: sensors.FormatZigbee2MQTT, Fields: map[string]string{
+			"speed":     sensors.FieldWindSpeed,
+			"direction": sensors.FieldWindDirection,
+		}},
+	}
+
+	var store *sensors.Store
+	providertest.Suite{
+		NewProvider: func(baseURL string) providers.Provider {
+			return providers.NewSensorProvider(store, location, time.Hour, geo.NewTable())
+		},
+		// Upstream не нужен: датчики уже опубликовали значения в MQTT
+		Upstream: func(city string, obs providertest.Observation) http.Handler {
+			store = sensors.NewStore(topics)
+			store.Handle(mqtt.Message{
+				Topic: "zigbee2mqtt/balcony",
+				Payload: []byte(fmt.Sprintf(`{"temperature": %v, "humidity": %d, "pressure": %d, "battery": 97}`,
+					obs.Temperature, obs.Humidity, obs.Pressure)),
+			})
+			store.Handle(mqtt.Message{
+				Topic:   "weather/wind",
+				Payload: []byte(fmt.Sprintf(`{"speed": %v, "direction": %d}`, obs.WindSpeed, obs.WindDirection)),
+			})
+			return http.NotFoundHandler()
+		},
+		// Датчики не сообщают ощущаемую температуру
+		ComputedFeelsLike: true,
+		Offline:           true,
+	}.Run(t)
+}
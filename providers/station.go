@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"weather-aggregator/geo"
+	"weather-aggregator/models"
+	"weather-aggregator/pws"
+)
+
+// StationProvider отдает последнее показание собственной метеостанции,
+// если запрошенный город находится в радиусе станции
+type StationProvider struct {
+	store    *pws.Store
+	station  pws.Station
+	maxAge   time.Duration
+	geocoder geo.Geocoder
+}
+
+// NewStationProvider создает провайдер для станции из конфигурации pws.
+// Показания старше maxAge не используются.
+func NewStationProvider(store *pws.Store, station pws.Station, maxAge time.Duration, geocoder geo.Geocoder) *StationProvider {
+	return &StationProvider{
+		store:    store,
+		station:  station,
+		maxAge:   maxAge,
+		geocoder: geocoder,
+	}
+}
+
+func (p *StationProvider) Name() string {
+	return "PWS " + p.station.ID
+}
+
+func (p *StationProvider) IsAvailable() bool {
+	return p.store != nil && p.geocoder != nil
+}
+
+func (p *StationProvider) GetWeather(ctx context.Context, city, country string) (*models.WeatherData, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("провайдер %s не настроен", p.Name())
+	}
+
+	loc, err := p.geocoder.Resolve(ctx, city, country)
+	if err != nil {
+		if errors.Is(err, geo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", ErrCityNotFound, err)
+		}
+		return nil, fmt.Errorf("ошибка определения координат: %w", err)
+	}
+
+	if distance := geo.Distance(loc.Lat, loc.Lon, p.station.Lat, p.station.Lon); distance > p.station.RadiusKm {
+		return nil, fmt.Errorf("%w: станция %s в %.0f км", ErrUnsupportedLocation, p.station.ID, distance)
+	}
+
+	reading, ok := p.store.Latest(p.station.ID)
+	if !ok {
+		return nil, fmt.Errorf("станция %s еще не передавала данные", p.station.ID)
+	}
+	if age := time.Since(reading.Received); age > p.maxAge {
+		return nil, fmt.Errorf("данные станции %s устарели (%s)", p.station.ID, age.Round(time.Second))
+	}
+
+	if reading.Temperature == nil && reading.Humidity == nil && reading.Pressure == nil && reading.WindSpeed == nil {
+		return nil, fmt.Errorf("станция %s не передает измерений", p.station.ID)
+	}
+
+	return &models.WeatherData{
+		Provider:      p.Name(),
+		Location:      fmt.Sprintf("%s, %s", city, country),
+		Temperature:   reading.Temperature,
+		FeelsLike:     feelsLike(reading.Temperature, reading.Humidity, reading.WindSpeed),
+		Humidity:      reading.Humidity,
+		Pressure:      reading.Pressure,
+		WindSpeed:     reading.WindSpeed,
+		WindDirection: reading.WindDirection,
+		Station:       p.station.ID,
+		Timestamp:     reading.Time,
+		Units:         "metric",
+		DewPoint:      reading.DewPoint,
+		WindGust:      reading.WindGust,
+		UVIndex:       reading.UV,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package providers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"weather-aggregator/geo"
+	"weather-aggregator/providers"
+	"weather-aggregator/providers/providertest"
+	"weather-aggregator/pws"
+)
+
+func TestStationConformance(t *testing.T) {
+	moscow, err := geo.NewTable().Resolve(context.Background(), "Москва", "RU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	station := pws.Station{ID: "TEST1", Key: "secret", Lat: moscow.Lat, Lon: moscow.Lon, RadiusKm: 25}
+
+	var store *pws.Store
+	providertest.Suite{
+		NewProvider: func(baseURL string) providers.Provider {
+			return providers.NewStationProvider(store, station, time.Hour, geo.NewTable())
+		},
+		// Upstream - эндпоинт приема данных, в который станция уже
+		// загрузила наблюдение по протоколу Weather Underground
+		Upstream: func(city string, obs providertest.Observation) http.Handler {
+			store = pws.NewStore([]pws.Station{station})
+			mux := http.NewServeMux()
+			store.Register(mux)
+
+			format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+			values := url.Values{
+				"ID":           {station.ID},
+				"PASSWORD":     {station.Key},
+				"dateutc":      {"now"},
+				"tempf":        {format(obs.Temperature*9/5 + 32)},
+				"humidity":     {strconv.Itoa(obs.Humidity)},
+				"baromin":      {format(float64(obs.Pressure) / 33.8639)},
+				"windspeedmph": {format(obs.WindSpeed / 0.44704)},
+				"winddir":      {strconv.Itoa(obs.WindDirection)},
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", pws.WUPath+"?"+values.Encode(), nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("загрузка WU: статус %d: %s", rec.Code, rec.Body)
+			}
+			return mux
+		},
+		// Станция не сообщает ощущаемую температуру
+		ComputedFeelsLike: true,
+		Offline:           true,
+	}.Run(t)
+}
@@ -6,11 +6,20 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
+	"weather-aggregator/airquality"
+	"weather-aggregator/conditions"
 	"weather-aggregator/models"
 )
 
+// Пути эндпоинтов WeatherAPI
+const (
+	weatherAPICurrentPath  = "/v1/current.json"
+	weatherAPIForecastPath = "/v1/forecast.json"
+)
+
 type WeatherAPIProvider struct {
 	apiKey  string
 	client  *http.Client
@@ -23,10 +32,18 @@ func NewWeatherAPIProvider(apiKey string) *WeatherAPIProvider {
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		baseURL: "https://api.weatherapi.com/v1/current.json",
+		baseURL: "https://api.weatherapi.com",
 	}
 }
 
+// WithBaseURL переопределяет адрес API (например, для локального mock-upstream)
+func (p *WeatherAPIProvider) WithBaseURL(baseURL string) *WeatherAPIProvider {
+	if baseURL != "" {
+		p.baseURL = strings.TrimRight(baseURL, "/")
+	}
+	return p
+}
+
 func (p *WeatherAPIProvider) Name() string {
 	return "WeatherAPI"
 }
@@ -45,33 +62,7 @@ func (p *WeatherAPIProvider) GetWeather(ctx context.Context, city, country strin
 	query.Set("key", p.apiKey)
 	query.Set("q", fmt.Sprintf("%s,%s", city, country))
 	query.Set("lang", "ru")
-
-	reqURL := fmt.Sprintf("%s?%s", p.baseURL, query.Encode())
-
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
-	}
-
-	resp, err := p.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка HTTP запроса: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		var apiError struct {
-			Error struct {
-				Message string `json:"message"`
-			} `json:"error"`
-		}
-
-		if err := json.NewDecoder(resp.Body).Decode(&apiError); err == nil && apiError.Error.Message != "" {
-			return nil, fmt.Errorf("ошибка WeatherAPI: %s", apiError.Error.Message)
-		}
-
-		return nil, fmt.Errorf("ошибка API: статус %d", resp.StatusCode)
-	}
+	query.Set("aqi", "yes")
 
 	// Парсим ответ
 	var result struct {
@@ -80,25 +71,44 @@ func (p *WeatherAPIProvider) GetWeather(ctx context.Context, city, country strin
 			Country string `json:"country"`
 		} `json:"location"`
 		Current struct {
-			TempC      float64 `json:"temp_c"`
-			FeelsLikeC float64 `json:"feelslike_c"`
-			Humidity   int     `json:"humidity"`
-			PressureMB float64 `json:"pressure_mb"`
-			WindKph    float64 `json:"wind_kph"`
-			WindDeg    int     `json:"wind_degree"`
+			TempC      *float64 `json:"temp_c"`
+			FeelsLikeC *float64 `json:"feelslike_c"`
+			Humidity   *float64 `json:"humidity"`
+			PressureMB *float64 `json:"pressure_mb"`
+			WindKph    *float64 `json:"wind_kph"`
+			WindDeg    *float64 `json:"wind_degree"`
+			GustKph    *float64 `json:"gust_kph"`
+			DewPointC  *float64 `json:"dewpoint_c"`
+			Cloud      *float64 `json:"cloud"`
+			VisKm      *float64 `json:"vis_km"`
+			UV         *float64 `json:"uv"`
+			PrecipMM   *float64 `json:"precip_mm"`
 			Condition  struct {
+				Code int    `json:"code"`
 				Text string `json:"text"`
 				Icon string `json:"icon"`
 			} `json:"condition"`
+			// Концентрации в мкг/м³; индексы us-epa-index и gb-defra-index не используем
+			AirQuality *struct {
+				CO   *float64 `json:"co"`
+				NO2  *float64 `json:"no2"`
+				O3   *float64 `json:"o3"`
+				SO2  *float64 `json:"so2"`
+				PM25 *float64 `json:"pm2_5"`
+				PM10 *float64 `json:"pm10"`
+			} `json:"air_quality"`
 		} `json:"current"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("ошибка парсинга JSON: %w", err)
+	if err := p.get(ctx, weatherAPICurrentPath, query, &result); err != nil {
+		return nil, err
 	}
 
 	// Конвертируем скорость ветра из км/ч в м/с
-	windSpeedMS := result.Current.WindKph / 3.6
+	var windSpeedMS *float64
+	if result.Current.WindKph != nil {
+		windSpeedMS = models.Float(*result.Current.WindKph / 3.6)
+	}
 
 	weather := &models.WeatherData{
 		Provider:      p.Name(),
@@ -106,14 +116,190 @@ func (p *WeatherAPIProvider) GetWeather(ctx context.Context, city, country strin
 		Temperature:   result.Current.TempC,
 		FeelsLike:     result.Current.FeelsLikeC,
 		Humidity:      result.Current.Humidity,
-		Pressure:      int(result.Current.PressureMB),
+		Pressure:      result.Current.PressureMB,
 		WindSpeed:     windSpeedMS,
 		WindDirection: result.Current.WindDeg,
 		Description:   result.Current.Condition.Text,
+		Condition:     conditions.FromWeatherAPI(result.Current.Condition.Code),
 		Icon:          "https:" + result.Current.Condition.Icon,
 		Timestamp:     time.Now(),
 		Units:         "metric",
+		DewPoint:      result.Current.DewPointC,
+		CloudCover:    result.Current.Cloud,
+		Visibility:    result.Current.VisKm,
+		UVIndex:       result.Current.UV,
+		Precipitation: result.Current.PrecipMM,
+	}
+	if result.Current.GustKph != nil {
+		weather.WindGust = models.Float(*result.Current.GustKph / 3.6)
+	}
+
+	if aq := result.Current.AirQuality; aq != nil {
+		weather.AirQuality = airquality.Fill(&models.AirQuality{
+			PM25: aq.PM25,
+			PM10: aq.PM10,
+			O3:   aq.O3,
+			NO2:  aq.NO2,
+			SO2:  aq.SO2,
+			CO:   aq.CO,
+		})
 	}
 
 	return weather, nil
 }
+
+// GetAlerts возвращает действующие предупреждения (alerts=yes доступен
+// только в эндпоинте прогноза)
+func (p *WeatherAPIProvider) GetAlerts(ctx context.Context, city, country string) ([]models.Alert, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("провайдер %s не настроен", p.Name())
+	}
+
+	query := url.Values{}
+	query.Set("key", p.apiKey)
+	query.Set("q", fmt.Sprintf("%s,%s", city, country))
+	query.Set("days", "1")
+	query.Set("alerts", "yes")
+	query.Set("lang", "ru")
+
+	var result struct {
+		Alerts struct {
+			Alert []struct {
+				Headline    string `json:"headline"`
+				MsgType     string `json:"msgtype"`
+				Severity    string `json:"severity"`
+				Urgency     string `json:"urgency"`
+				Areas       string `json:"areas"`
+				Event       string `json:"event"`
+				Effective   string `json:"effective"`
+				Expires     string `json:"expires"`
+				Desc        string `json:"desc"`
+				Instruction string `json:"instruction"`
+			} `json:"alert"`
+		} `json:"alerts"`
+	}
+
+	if err := p.get(ctx, weatherAPIForecastPath, query, &result); err != nil {
+		return nil, err
+	}
+
+	alerts := make([]models.Alert, 0, len(result.Alerts.Alert))
+	for _, a := range result.Alerts.Alert {
+		if strings.EqualFold(a.MsgType, "Cancel") {
+			continue
+		}
+
+		alert := models.Alert{
+			Event:       a.Event,
+			Headline:    a.Headline,
+			Description: strings.TrimSpace(a.Desc),
+			Instruction: strings.TrimSpace(a.Instruction),
+			Severity:    alertLevel(a.Severity, models.SeverityUnknown),
+			Urgency:     alertLevel(a.Urgency, models.UrgencyUnknown),
+			Source:      p.Name(),
+		}
+		if alert.Event == "" {
+			alert.Event = a.Headline
+		}
+		for _, area := range strings.Split(a.Areas, ";") {
+			if area = strings.TrimSpace(area); area != "" {
+				alert.Areas = append(alert.Areas, area)
+			}
+		}
+		alert.Onset, _ = time.Parse(time.RFC3339, a.Effective)
+		alert.Expires, _ = time.Parse(time.RFC3339, a.Expires)
+
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// GetForecast возвращает почасовой прогноз на трое суток
+func (p *WeatherAPIProvider) GetForecast(ctx context.Context, city, country string) ([]models.ForecastPoint, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("провайдер %s не настроен", p.Name())
+	}
+
+	query := url.Values{}
+	query.Set("key", p.apiKey)
+	query.Set("q", fmt.Sprintf("%s,%s", city, country))
+	query.Set("days", "3")
+	query.Set("aqi", "no")
+	query.Set("alerts", "no")
+
+	var result struct {
+		Forecast struct {
+			ForecastDay []struct {
+				Hour []struct {
+					TimeEpoch  int64    `json:"time_epoch"`
+					TempC      *float64 `json:"temp_c"`
+					Humidity   *float64 `json:"humidity"`
+					PressureMB *float64 `json:"pressure_mb"`
+					WindKph    *float64 `json:"wind_kph"`
+				} `json:"hour"`
+			} `json:"forecastday"`
+		} `json:"forecast"`
+	}
+
+	if err := p.get(ctx, weatherAPIForecastPath, query, &result); err != nil {
+		return nil, err
+	}
+
+	var points []models.ForecastPoint
+	for _, day := range result.Forecast.ForecastDay {
+		for _, h := range day.Hour {
+			point := models.ForecastPoint{
+				Time:        time.Unix(h.TimeEpoch, 0),
+				Temperature: h.TempC,
+				Humidity:    h.Humidity,
+				Pressure:    h.PressureMB,
+			}
+			if h.WindKph != nil {
+				point.WindSpeed = models.Float(*h.WindKph / 3.6)
+			}
+			points = append(points, point)
+		}
+	}
+	return points, nil
+}
+
+// get выполняет запрос к API и разбирает ответ или ошибку WeatherAPI
+func (p *WeatherAPIProvider) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	reqURL := fmt.Sprintf("%s%s?%s", p.baseURL, path, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка HTTP запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Error struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&apiError); err == nil && apiError.Error.Message != "" {
+			// 1006 - "No matching location found"
+			if apiError.Error.Code == 1006 {
+				return fmt.Errorf("%w: %s", ErrCityNotFound, apiError.Error.Message)
+			}
+			return fmt.Errorf("ошибка WeatherAPI: %s", apiError.Error.Message)
+		}
+
+		return fmt.Errorf("ошибка API: статус %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package providers_test
+
+import (
+	"testing"
+
+	"weather-aggregator/providers"
+	"weather-aggregator/providers/providertest"
+)
+
+func TestWeatherAPIConformance(t *testing.T) {
+	providertest.Suite{
+		NewProvider: func(baseURL string) providers.Provider {
+			return providers.NewWeatherAPIProvider("test").WithBaseURL(baseURL)
+		},
+		Upstream: providertest.WeatherAPIUpstream,
+	}.Run(t)
+}
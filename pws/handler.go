@@ -0,0 +1,74 @@
+package pws
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Пути, на которые станции отправляют данные по умолчанию
+const (
+	WUPath      = "/weatherstation/updateweatherstation.php"
+	EcowittPath = "/data/report/"
+)
+
+// maxUploadSize ограничение размера тела загрузки Ecowitt
+const maxUploadSize = 16 << 10
+
+// Register подключает эндпоинты приема данных к mux
+func (s *Store) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+WUPath, s.handleWU)
+	mux.HandleFunc("POST "+EcowittPath, s.handleEcowitt)
+}
+
+// handleWU принимает загрузку по протоколу Weather Underground.
+// Станция ожидает в ответ текст "success".
+func (s *Store) handleWU(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	station, err := s.Authenticate(values.Get("ID"), values.Get("PASSWORD"))
+	if err != nil {
+		log.Printf("PWS: отклонена загрузка WU от %s: %v", r.RemoteAddr, err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reading, err := ParseWU(values, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reading.StationID = station.ID
+	s.Save(reading)
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("success\n"))
+}
+
+// handleEcowitt принимает загрузку Ecowitt (application/x-www-form-urlencoded)
+func (s *Store) handleEcowitt(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	station, err := s.Authenticate("", r.PostForm.Get("PASSKEY"))
+	if err != nil {
+		log.Printf("PWS: отклонена загрузка Ecowitt от %s: %v", r.RemoteAddr, err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reading, err := ParseEcowitt(r.PostForm, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reading.StationID = station.ID
+	s.Save(reading)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,273 @@
+// Package pws принимает данные от собственных метеостанций по протоколам
+// Weather Underground (updateweatherstation.php) и Ecowitt (custom server),
+// переводит имперские единицы в метрические и хранит последнее показание
+// каждой станции.
+package pws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownStation возвращается для загрузки от незарегистрированной станции
+var ErrUnknownStation = errors.New("неизвестная станция или неверный ключ")
+
+// maxClockSkew на сколько время измерения может опережать время приема:
+// показание с часами станции, ушедшими дальше вперед, заблокировало бы
+// все следующие (Save сохраняет только более новые)
+const maxClockSkew = 5 * time.Minute
+
+// Коэффициенты перевода единиц
+const (
+	mphToMS   = 0.44704
+	inHgToHPa = 33.8639
+	inchToMM  = 25.4
+)
+
+// Reading показание станции в метрических единицах. Поля, которые станция
+// не передала, равны nil.
+type Reading struct {
+	StationID string    `json:"station_id"`
+	Protocol  string    `json:"protocol"` // wu или ecowitt
+	Model     string    `json:"model,omitempty"`
+	Time      time.Time `json:"time"`     // время измерения по данным станции
+	Received  time.Time `json:"received"` // время приема сервером
+
+	Temperature    *float64 `json:"temperature,omitempty"`     // °C
+	DewPoint       *float64 `json:"dew_point,omitempty"`       // °C
+	Humidity       *float64 `json:"humidity,omitempty"`        // %
+	Pressure       *float64 `json:"pressure,omitempty"`        // hPa, приведенное к уровню моря
+	WindSpeed      *float64 `json:"wind_speed,omitempty"`      // м/с
+	WindGust       *float64 `json:"wind_gust,omitempty"`       // м/с
+	WindDirection  *float64 `json:"wind_direction,omitempty"`  // градусы
+	RainRate       *float64 `json:"rain_rate,omitempty"`       // мм/ч
+	DailyRain      *float64 `json:"daily_rain,omitempty"`      // мм
+	SolarRadiation *float64 `json:"solar_radiation,omitempty"` // Вт/м²
+	UV             *float64 `json:"uv,omitempty"`
+}
+
+// Station зарегистрированная станция
+type Station struct {
+	ID       string  `json:"id"`
+	Key      string  `json:"key"` // PASSWORD для WU или PASSKEY для Ecowitt
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	RadiusKm float64 `json:"radius_km,omitempty"` // радиус, в котором показания относятся к городу
+}
+
+// Config конфигурация станций
+type Config struct {
+	Stations []Station `json:"stations"`
+	// MaxAgeMinutes показания старше считаются устаревшими
+	MaxAgeMinutes int `json:"max_age_minutes,omitempty"`
+}
+
+// LoadConfig читает конфигурацию станций из JSON файла
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения конфигурации станций: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга конфигурации станций: %w", err)
+	}
+
+	for i, s := range cfg.Stations {
+		if s.ID == "" || s.Key == "" {
+			return nil, fmt.Errorf("станция %d: нужны id и key", i)
+		}
+		if s.RadiusKm == 0 {
+			cfg.Stations[i].RadiusKm = 25
+		}
+	}
+	if cfg.MaxAgeMinutes == 0 {
+		cfg.MaxAgeMinutes = 15
+	}
+
+	return &cfg, nil
+}
+
+// MaxAge срок годности показаний
+func (c *Config) MaxAge() time.Duration {
+	return time.Duration(c.MaxAgeMinutes) * time.Minute
+}
+
+// Store хранит последнее показание каждой станции
+type Store struct {
+	mu       sync.RWMutex
+	stations []Station
+	latest   map[string]Reading
+}
+
+// NewStore создает хранилище для зарегистрированных станций
+func NewStore(stations []Station) *Store {
+	return &Store{
+		stations: stations,
+		latest:   make(map[string]Reading),
+	}
+}
+
+// Authenticate находит станцию по идентификатору и/или ключу.
+// Для WU передаются ID и PASSWORD, для Ecowitt только PASSKEY.
+func (s *Store) Authenticate(id, key string) (Station, error) {
+	for _, st := range s.stations {
+		if st.Key != key {
+			continue
+		}
+		if id == "" || strings.EqualFold(st.ID, id) {
+			return st, nil
+		}
+	}
+	return Station{}, ErrUnknownStation
+}
+
+// Save сохраняет показание, если оно новее уже сохраненного
+func (s *Store) Save(r Reading) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prev, ok := s.latest[r.StationID]; ok && prev.Time.After(r.Time) {
+		return
+	}
+	s.latest[r.StationID] = r
+}
+
+// Latest возвращает последнее показание станции
+func (s *Store) Latest(stationID string) (Reading, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.latest[stationID]
+	return r, ok
+}
+
+// All возвращает последние показания всех станций
+func (s *Store) All() []Reading {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	readings := make([]Reading, 0, len(s.latest))
+	for _, r := range s.latest {
+		readings = append(readings, r)
+	}
+	return readings
+}
+
+// ParseWU разбирает параметры протокола Weather Underground
+func ParseWU(values url.Values, received time.Time) (Reading, error) {
+	r := Reading{
+		Protocol: "wu",
+		Model:    values.Get("softwaretype"),
+		Received: received,
+	}
+
+	var err error
+	if r.Time, err = parseDateUTC(values.Get("dateutc"), received); err != nil {
+		return Reading{}, err
+	}
+
+	r.Temperature = fahrenheit(values, "tempf")
+	r.DewPoint = fahrenheit(values, "dewptf")
+	r.Humidity = number(values, "humidity")
+	r.Pressure = scaled(values, "baromin", inHgToHPa)
+	r.WindSpeed = scaled(values, "windspeedmph", mphToMS)
+	r.WindGust = scaled(values, "windgustmph", mphToMS)
+	r.WindDirection = number(values, "winddir")
+	r.RainRate = scaled(values, "rainin", inchToMM)
+	r.DailyRain = scaled(values, "dailyrainin", inchToMM)
+	r.SolarRadiation = number(values, "solarradiation")
+	r.UV = number(values, "UV")
+
+	return r, nil
+}
+
+// ParseEcowitt разбирает параметры протокола Ecowitt (custom server)
+func ParseEcowitt(values url.Values, received time.Time) (Reading, error) {
+	r := Reading{
+		Protocol: "ecowitt",
+		Model:    values.Get("model"),
+		Received: received,
+	}
+	if r.Model == "" {
+		r.Model = values.Get("stationtype")
+	}
+
+	var err error
+	if r.Time, err = parseDateUTC(values.Get("dateutc"), received); err != nil {
+		return Reading{}, err
+	}
+
+	r.Temperature = fahrenheit(values, "tempf")
+	r.Humidity = number(values, "humidity")
+	// Относительное давление приведено к уровню моря, как у остальных провайдеров
+	r.Pressure = scaled(values, "baromrelin", inHgToHPa)
+	if r.Pressure == nil {
+		r.Pressure = scaled(values, "baromabsin", inHgToHPa)
+	}
+	r.WindSpeed = scaled(values, "windspeedmph", mphToMS)
+	r.WindGust = scaled(values, "windgustmph", mphToMS)
+	r.WindDirection = number(values, "winddir")
+	r.RainRate = scaled(values, "rainratein", inchToMM)
+	r.DailyRain = scaled(values, "dailyrainin", inchToMM)
+	r.SolarRadiation = number(values, "solarradiation")
+	r.UV = number(values, "uv")
+
+	return r, nil
+}
+
+// parseDateUTC разбирает dateutc ("now" или "2006-01-02 15:04:05"). Время
+// из будущего (дальше maxClockSkew) заменяется временем приема.
+func parseDateUTC(value string, received time.Time) (time.Time, error) {
+	if value == "" || strings.EqualFold(value, "now") {
+		return received.UTC(), nil
+	}
+
+	t, err := time.Parse("2006-01-02 15:04:05", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("некорректное время dateutc %q", value)
+	}
+	if t.After(received.Add(maxClockSkew)) {
+		return received.UTC(), nil
+	}
+	return t, nil
+}
+
+// number читает число; отсутствующее или служебное значение (-9999) - nil
+func number(values url.Values, key string) *float64 {
+	raw := values.Get(key)
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v <= -9999 {
+		return nil
+	}
+	return &v
+}
+
+func scaled(values url.Values, key string, factor float64) *float64 {
+	v := number(values, key)
+	if v == nil {
+		return nil
+	}
+	converted := *v * factor
+	return &converted
+}
+
+func fahrenheit(values url.Values, key string) *float64 {
+	v := number(values, key)
+	if v == nil {
+		return nil
+	}
+	celsius := (*v - 32) * 5 / 9
+	return &celsius
+}
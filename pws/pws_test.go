@@ -0,0 +1,67 @@
+package pws
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseWUFutureDate(t *testing.T) {
+	received := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		dateutc string
+		want    time.Time
+	}{
+		{"now", received},
+		{"2025-01-15 11:58:30", time.Date(2025, 1, 15, 11, 58, 30, 0, time.UTC)},
+		// Небольшое расхождение часов допустимо
+		{"2025-01-15 12:03:00", time.Date(2025, 1, 15, 12, 3, 0, 0, time.UTC)},
+		// Часы станции ушли вперед: используется время приема
+		{"2025-01-15 13:00:00", received},
+		{"2026-01-15 12:00:00", received},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseWU(url.Values{"dateutc": {tt.dateutc}, "tempf": {"32"}}, received)
+		if err != nil {
+			t.Fatalf("ParseWU(%q): %v", tt.dateutc, err)
+		}
+		if !r.Time.Equal(tt.want) {
+			t.Errorf("dateutc %q: Time = %s, ожидается %s", tt.dateutc, r.Time, tt.want)
+		}
+	}
+}
+
+func TestSaveAfterFutureUpload(t *testing.T) {
+	store := NewStore([]Station{{ID: "TEST1", Key: "secret"}})
+	start := time.Now()
+
+	upload := func(dateutc string, tempf string, received time.Time) {
+		t.Helper()
+		r, err := ParseWU(url.Values{"dateutc": {dateutc}, "tempf": {tempf}}, received)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.StationID = "TEST1"
+		store.Save(r)
+	}
+
+	// Загрузка с часами на сутки вперед не должна блокировать следующие
+	upload(start.Add(24*time.Hour).UTC().Format("2006-01-02 15:04:05"), "50", start)
+	upload("now", "32", start.Add(time.Minute))
+	upload("now", "41", start.Add(10*time.Minute))
+
+	latest, ok := store.Latest("TEST1")
+	if !ok {
+		t.Fatal("нет показаний станции")
+	}
+	if latest.Temperature == nil || *latest.Temperature != 5 {
+		t.Errorf("Temperature = %v, ожидается последнее показание 5°C", latest.Temperature)
+	}
+
+	// Показание старее сохраненного не заменяет его
+	upload(start.UTC().Format("2006-01-02 15:04:05"), "32", start.Add(11*time.Minute))
+	if latest, _ := store.Latest("TEST1"); *latest.Temperature != 5 {
+		t.Errorf("Temperature = %v, более старое показание заменило новое", *latest.Temperature)
+	}
+}
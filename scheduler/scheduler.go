@@ -0,0 +1,206 @@
+// Package scheduler периодически обновляет погоду для отслеживаемых городов
+// в режиме сервера, чтобы запросы к API получали результат из кеша.
+// Запросы к провайдерам распределяются равномерно по интервалу и выполняются
+// по одному, чтобы не превышать квоты API.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"weather-aggregator/models"
+)
+
+// refreshTimeout ограничение одного обновления
+const refreshTimeout = 30 * time.Second
+
+// Refresher запрашивает свежие данные в обход кеша (aggregator.Aggregator)
+type Refresher interface {
+	Refresh(ctx context.Context, city, country string) (*models.AggregatedWeather, error)
+}
+
+// Location отслеживаемый город
+type Location struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+// ParseLocations разбирает список вида "Москва:RU", "London:GB"; без страны
+// подставляется defaultCountry
+func ParseLocations(items []string, defaultCountry string) ([]Location, error) {
+	var locations []Location
+	for _, item := range items {
+		city, country, found := strings.Cut(strings.TrimSpace(item), ":")
+		city = strings.TrimSpace(city)
+		if city == "" {
+			return nil, fmt.Errorf("пустое название города в %q", item)
+		}
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !found || country == "" {
+			country = defaultCountry
+		}
+		locations = append(locations, Location{City: city, Country: country})
+	}
+	return locations, nil
+}
+
+// LocationStatus состояние обновления города
+type LocationStatus struct {
+	Location
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	NextRun     time.Time  `json:"next_run"`
+	Duration    float64    `json:"duration_ms,omitempty"` // длительность последнего обновления
+	Failures    int        `json:"failures"`              // ошибок подряд
+	TotalRuns   int        `json:"total_runs"`
+	TotalFails  int        `json:"total_failures"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// Status состояние планировщика
+type Status struct {
+	Running   bool             `json:"running"`
+	Interval  string           `json:"interval"`
+	StartedAt *time.Time       `json:"started_at,omitempty"`
+	Locations []LocationStatus `json:"locations"`
+}
+
+// Scheduler обновляет отслеживаемые города раз в interval
+type Scheduler struct {
+	refresher Refresher
+	interval  time.Duration
+
+	mu        sync.Mutex
+	running   bool
+	startedAt *time.Time
+	locations []*LocationStatus
+}
+
+// New создает планировщик; запускается методом Run
+func New(refresher Refresher, locations []Location, interval time.Duration) *Scheduler {
+	s := &Scheduler{
+		refresher: refresher,
+		interval:  interval,
+	}
+	for _, loc := range locations {
+		s.locations = append(s.locations, &LocationStatus{Location: loc})
+	}
+	return s
+}
+
+// Run обновляет города до отмены ctx. Первое обновление города i из n
+// выполняется через interval*i/n после запуска, дальше - раз в interval.
+func (s *Scheduler) Run(ctx context.Context) {
+	if len(s.locations) == 0 || s.interval <= 0 {
+		return
+	}
+
+	start := time.Now()
+	s.mu.Lock()
+	s.running = true
+	s.startedAt = &start
+	stagger := s.interval / time.Duration(len(s.locations))
+	for i, loc := range s.locations {
+		loc.NextRun = start.Add(stagger * time.Duration(i))
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		next := s.nextDue()
+		timer.Reset(time.Until(next.NextRun))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		s.refresh(ctx, next)
+	}
+}
+
+// nextDue возвращает город с ближайшим временем обновления
+func (s *Scheduler) nextDue() *LocationStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.locations[0]
+	for _, loc := range s.locations[1:] {
+		if loc.NextRun.Before(next.NextRun) {
+			next = loc
+		}
+	}
+	return next
+}
+
+// refresh обновляет один город и планирует следующее обновление
+func (s *Scheduler) refresh(ctx context.Context, loc *LocationStatus) {
+	refreshCtx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+
+	started := time.Now()
+	_, err := s.refresher.Refresh(refreshCtx, loc.City, loc.Country)
+	finished := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loc.LastRun = &started
+	loc.Duration = float64(finished.Sub(started).Microseconds()) / 1000
+	loc.TotalRuns++
+	// Расписание не сдвигается от длительности обновления; пропущенные
+	// из-за долгих запросов слоты не наверстываются
+	for !loc.NextRun.After(finished) {
+		loc.NextRun = loc.NextRun.Add(s.interval)
+	}
+
+	if err != nil {
+		// Отмена при остановке сервера не считается ошибкой города
+		if ctx.Err() != nil {
+			return
+		}
+		loc.Failures++
+		loc.TotalFails++
+		loc.LastError = err.Error()
+		log.Printf("Планировщик: ошибка обновления %s, %s: %v", loc.City, loc.Country, err)
+		return
+	}
+	loc.Failures = 0
+	loc.LastError = ""
+	loc.LastSuccess = &finished
+}
+
+// Status возвращает состояние планировщика; города упорядочены по времени
+// следующего обновления
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{
+		Running:   s.running,
+		Interval:  s.interval.String(),
+		StartedAt: s.startedAt,
+		Locations: make([]LocationStatus, 0, len(s.locations)),
+	}
+	for _, loc := range s.locations {
+		status.Locations = append(status.Locations, *loc)
+	}
+	sort.SliceStable(status.Locations, func(i, j int) bool {
+		return status.Locations[i].NextRun.Before(status.Locations[j].NextRun)
+	})
+	return status
+}
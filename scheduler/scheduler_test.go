@@ -0,0 +1,217 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"weather-aggregator/models"
+)
+
+// fakeRefresher запоминает время обновлений по городам
+type fakeRefresher struct {
+	mu    sync.Mutex
+	calls map[string][]time.Time
+	fn    func(ctx context.Context, city string) error
+}
+
+func (f *fakeRefresher) Refresh(ctx context.Context, city, country string) (*models.AggregatedWeather, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string][]time.Time)
+	}
+	f.calls[city] = append(f.calls[city], time.Now())
+	f.mu.Unlock()
+
+	if f.fn != nil {
+		if err := f.fn(ctx, city); err != nil {
+			return nil, err
+		}
+	}
+	return &models.AggregatedWeather{}, nil
+}
+
+func (f *fakeRefresher) first(city string) (time.Time, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.calls[city]) == 0 {
+		return time.Time{}, false
+	}
+	return f.calls[city][0], true
+}
+
+func (f *fakeRefresher) count(city string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.calls[city])
+}
+
+var cities = []Location{{"Москва", "RU"}, {"Казань", "RU"}, {"Сочи", "RU"}}
+
+func TestParseLocations(t *testing.T) {
+	locations, err := ParseLocations([]string{" Москва:ru ", "London:GB", "Казань", "Сочи:"}, "RU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Location{{"Москва", "RU"}, {"London", "GB"}, {"Казань", "RU"}, {"Сочи", "RU"}}
+	if len(locations) != len(want) {
+		t.Fatalf("города %v, ожидалось %v", locations, want)
+	}
+	for i := range want {
+		if locations[i] != want[i] {
+			t.Errorf("город %d: %v, ожидалось %v", i, locations[i], want[i])
+		}
+	}
+	if _, err := ParseLocations([]string{":RU"}, "RU"); err == nil {
+		t.Error("ожидалась ошибка для пустого города")
+	}
+}
+
+func TestRunStaggersLocations(t *testing.T) {
+	const interval = 600 * time.Millisecond
+	refresher := &fakeRefresher{}
+	s := New(refresher, cities, interval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	deadline := time.Now().Add(2 * interval)
+	for refresher.count("Сочи") == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	started := s.Status().StartedAt
+	if started == nil {
+		t.Fatal("время запуска не записано")
+	}
+	// Первые обновления разнесены на interval/3
+	stagger := interval / time.Duration(len(cities))
+	for i, loc := range cities {
+		at, ok := refresher.first(loc.City)
+		if !ok {
+			t.Fatalf("%s не обновлен", loc.City)
+		}
+		if d := at.Sub(*started) - stagger*time.Duration(i); d < 0 || d > stagger/2 {
+			t.Errorf("%s обновлен через %s после запуска, ожидалось %s", loc.City, at.Sub(*started), stagger*time.Duration(i))
+		}
+	}
+
+	// Следующее обновление - через interval после запланированного, а не
+	// после фактического
+	for _, loc := range s.Status().Locations {
+		i := 0
+		for i < len(cities) && cities[i].City != loc.City {
+			i++
+		}
+		if want := started.Add(stagger*time.Duration(i) + interval); !loc.NextRun.Equal(want) {
+			t.Errorf("%s: следующее обновление %s, ожидалось %s", loc.City, loc.NextRun, want)
+		}
+	}
+}
+
+func TestRefreshSkipsMissedSlots(t *testing.T) {
+	const interval = time.Minute
+	s := New(&fakeRefresher{}, cities[:1], interval)
+	loc := s.locations[0]
+
+	// Сервер спал или обновление заняло несколько интервалов: пропущенные
+	// слоты не наверстываются, сетка расписания сохраняется
+	scheduled := time.Now().Add(-3*interval - 10*time.Second)
+	loc.NextRun = scheduled
+	s.refresh(context.Background(), loc)
+
+	if want := scheduled.Add(4 * interval); !loc.NextRun.Equal(want) {
+		t.Errorf("следующее обновление %s, ожидалось %s", loc.NextRun, want)
+	}
+}
+
+func TestRefreshCountsFailures(t *testing.T) {
+	fail := true
+	refresher := &fakeRefresher{fn: func(ctx context.Context, city string) error {
+		if fail {
+			return errors.New("провайдеры недоступны")
+		}
+		return nil
+	}}
+	s := New(refresher, cities[:1], time.Minute)
+	loc := s.locations[0]
+	loc.NextRun = time.Now()
+
+	s.refresh(context.Background(), loc)
+	s.refresh(context.Background(), loc)
+	status := s.Status().Locations[0]
+	if status.Failures != 2 || status.TotalFails != 2 || status.TotalRuns != 2 ||
+		status.LastError != "провайдеры недоступны" || status.LastRun == nil || status.LastSuccess != nil {
+		t.Errorf("после двух ошибок: %+v", status)
+	}
+
+	fail = false
+	s.refresh(context.Background(), loc)
+	status = s.Status().Locations[0]
+	if status.Failures != 0 || status.TotalFails != 2 || status.TotalRuns != 3 ||
+		status.LastError != "" || status.LastSuccess == nil {
+		t.Errorf("после успеха: %+v", status)
+	}
+
+	// Ошибка из-за остановки сервера не считается ошибкой города
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	refresher.fn = func(ctx context.Context, city string) error { return ctx.Err() }
+	s.refresh(ctx, loc)
+	status = s.Status().Locations[0]
+	if status.Failures != 0 || status.TotalFails != 2 || status.TotalRuns != 4 {
+		t.Errorf("после отмены: %+v", status)
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	refresher := &fakeRefresher{}
+	s := New(refresher, cities, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	// Первый город обновляется сразу, остальные ждут своей очереди
+	deadline := time.Now().Add(time.Second)
+	for refresher.count("Москва") == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !s.Status().Running {
+		t.Error("планировщик не отмечен запущенным")
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run не завершился после отмены контекста")
+	}
+	if s.Status().Running {
+		t.Error("планировщик отмечен запущенным после остановки")
+	}
+	if n := refresher.count("Москва") + refresher.count("Казань") + refresher.count("Сочи"); n != 1 {
+		t.Errorf("обновлений %d, ожидалось одно до остановки", n)
+	}
+}
+
+func TestRunWithoutLocations(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		New(&fakeRefresher{}, nil, time.Minute).Run(context.Background())
+		New(&fakeRefresher{}, cities, 0).Run(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run без городов или интервала должен сразу завершаться")
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"weather-aggregator/config"
+	"weather-aggregator/history"
+	"weather-aggregator/models"
+	"weather-aggregator/verification"
+)
+
+// newScoresCmd создает команду просмотра точности прогнозов провайдеров
+func newScoresCmd() *cobra.Command {
+	var scoresCmd = &cobra.Command{
+		Use:   "scores [город]",
+		Short: "Точность прогнозов провайдеров для города",
+		Long: "Сравнивает прогнозы провайдеров, сохраненные сервером в HISTORY_DIR, с\n" +
+			"агрегированными наблюдениями и выводит таблицу лидеров по MAE, а также\n" +
+			"ошибки по заблаговременности.",
+		Args: cobra.ExactArgs(1),
+		// Расчету не нужны ключи API и агрегатор
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+		Run: func(cmd *cobra.Command, args []string) {
+			country, _ := cmd.Flags().GetString("country")
+			field, _ := cmd.Flags().GetString("field")
+			days, _ := cmd.Flags().GetInt("days")
+			output, _ := cmd.Flags().GetString("output")
+
+			scoresCLI(args[0], country, field, days, output)
+		},
+	}
+
+	scoresCmd.Flags().StringP("country", "c", "RU", "Код страны (например, RU, US)")
+	scoresCmd.Flags().String("field", "", "Величина: "+strings.Join(verification.Fields, ", ")+" (по умолчанию все)")
+	scoresCmd.Flags().Int("days", 30, "За сколько последних суток сравнивать прогнозы")
+	scoresCmd.Flags().StringP("output", "o", "text", "Формат вывода (text, json)")
+
+	return scoresCmd
+}
+
+// scoresCLI рассчитывает и выводит точность прогнозов
+func scoresCLI(city, country, field string, days int, output string) {
+	dir := config.LoadClient().HistoryDir
+	if dir == "" {
+		log.Fatalf("История не ведется: задайте HISTORY_DIR")
+	}
+	s, err := history.Open(dir, history.Options{})
+	if err != nil {
+		log.Fatalf("Ошибка открытия истории: %v", err)
+	}
+
+	scores, err := verification.NewScorer(s, verification.Options{
+		Window: time.Duration(days) * 24 * time.Hour,
+	}).Scores(city, country, time.Now())
+	if err != nil {
+		log.Fatalf("Ошибка: %v", err)
+	}
+	scores = filterScores(scores, field)
+
+	if output == "json" {
+		data, _ := json.MarshalIndent(scores, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("🎯 Точность прогнозов: %s, %s, %s - %s\n", city, country,
+		scores.From.Local().Format("02.01.2006"), scores.To.Local().Format("02.01.2006"))
+	fmt.Println(strings.Repeat("=", 40))
+	if len(scores.Leaderboard) == 0 {
+		fmt.Println("Нет сравнений: прогнозы сохраняет сервер с HISTORY_DIR, а проверить их")
+		fmt.Println("можно только после того, как наступит время, на которое они составлены")
+		return
+	}
+
+	printScoreTable := func(list []models.ProviderScore, withLead bool) {
+		current := ""
+		for _, score := range list {
+			if score.Field != current {
+				current = score.Field
+				fmt.Printf("\n%s:\n", current)
+				if withLead {
+					fmt.Printf("  %-8s", "Заблаг.")
+				}
+				fmt.Printf("  %-16s %8s %8s %8s %6s\n", "Провайдер", "MAE", "Bias", "RMSE", "N")
+			}
+			if withLead {
+				fmt.Printf("  %-8s", score.Lead)
+			}
+			fmt.Printf("  %-16s %8.2f %+8.2f %8.2f %6d\n", score.Provider, score.MAE, score.Bias, score.RMSE, score.Count)
+		}
+	}
+
+	fmt.Println("Таблица лидеров (все заблаговременности):")
+	printScoreTable(scores.Leaderboard, false)
+	fmt.Println()
+	fmt.Println("По заблаговременности:")
+	printScoreTable(scores.ByLead, true)
+}
+
+// filterScores оставляет оценки одной величины; пустое поле - все
+func filterScores(scores *models.ScoresResponse, field string) *models.ScoresResponse {
+	if field == "" {
+		return scores
+	}
+	filtered := *scores
+	filtered.Leaderboard, filtered.ByLead = []models.ProviderScore{}, []models.ProviderScore{}
+	for _, score := range scores.Leaderboard {
+		if score.Field == field {
+			filtered.Leaderboard = append(filtered.Leaderboard, score)
+		}
+	}
+	for _, score := range scores.ByLead {
+		if score.Field == field {
+			filtered.ByLead = append(filtered.ByLead, score)
+		}
+	}
+	return &filtered
+}
@@ -0,0 +1,131 @@
+package sensors
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Стандартные ключи Zigbee2MQTT: значения уже в °C, % и hPa
+var zigbee2mqttFields = map[string]string{
+	"temperature": FieldTemperature,
+	"humidity":    FieldHumidity,
+	"pressure":    FieldPressure,
+}
+
+// Ключи показаний в объектах датчиков Tasmota ({"BME280": {"Temperature": ...}})
+var tasmotaFields = map[string]string{
+	"Temperature": FieldTemperature,
+	"Humidity":    FieldHumidity,
+	"SeaPressure": FieldPressure,
+	"Pressure":    FieldPressure,
+}
+
+// Parse извлекает значения полей из JSON сообщения топика
+func Parse(t Topic, payload []byte) (map[string]float64, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+
+	var values map[string]float64
+	switch {
+	case len(t.Fields) > 0:
+		values = make(map[string]float64)
+		for path, field := range t.Fields {
+			if v, ok := lookup(doc, path); ok {
+				values[field] = v
+			}
+		}
+	case t.Format == FormatTasmota:
+		values = tasmotaDefaults(doc)
+	default:
+		values = make(map[string]float64)
+		for key, field := range zigbee2mqttFields {
+			if v, ok := number(doc[key]); ok {
+				values[field] = v
+			}
+		}
+	}
+
+	if t.Format == FormatTasmota {
+		convertTasmotaUnits(doc, values)
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("нет известных полей")
+	}
+	return values, nil
+}
+
+// tasmotaDefaults ищет показания во вложенных объектах датчиков. Если
+// датчиков несколько, используется первый по алфавиту, сообщивший значение.
+func tasmotaDefaults(doc map[string]interface{}) map[string]float64 {
+	values := make(map[string]float64)
+
+	sensors := make([]string, 0, len(doc))
+	for name, v := range doc {
+		if _, ok := v.(map[string]interface{}); ok {
+			sensors = append(sensors, name)
+		}
+	}
+	sort.Strings(sensors)
+
+	for _, name := range sensors {
+		obj := doc[name].(map[string]interface{})
+		// SeaPressure (приведенное к уровню моря) важнее Pressure
+		for _, key := range []string{"Temperature", "Humidity", "SeaPressure", "Pressure"} {
+			field := tasmotaFields[key]
+			if _, ok := values[field]; ok {
+				continue
+			}
+			if v, ok := number(obj[key]); ok {
+				values[field] = v
+			}
+		}
+	}
+	return values
+}
+
+// convertTasmotaUnits переводит значения по TempUnit и PressureUnit сообщения
+func convertTasmotaUnits(doc map[string]interface{}, values map[string]float64) {
+	if v, ok := values[FieldTemperature]; ok && doc["TempUnit"] == "F" {
+		values[FieldTemperature] = (v - 32) * 5 / 9
+	}
+	if v, ok := values[FieldPressure]; ok {
+		switch doc["PressureUnit"] {
+		case "mmHg":
+			values[FieldPressure] = v * 1.33322
+		case "inHg":
+			values[FieldPressure] = v * 33.8639
+		}
+	}
+}
+
+// lookup находит число по пути через точку
+func lookup(doc map[string]interface{}, path string) (float64, bool) {
+	var current interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return 0, false
+		}
+		current = obj[key]
+	}
+	return number(current)
+}
+
+// number принимает числа и числовые строки (некоторые прошивки шлют "21.5")
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,255 @@
+// Package sensors собирает показания датчиков из MQTT (Zigbee2MQTT, Tasmota)
+// и хранит последнее значение каждого поля для каждой локации.
+package sensors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"weather-aggregator/mqtt"
+)
+
+// Поля, которые можно сопоставить значениям из сообщений
+const (
+	FieldTemperature   = "temperature"    // °C
+	FieldHumidity      = "humidity"       // %
+	FieldPressure      = "pressure"       // hPa
+	FieldWindSpeed     = "wind_speed"     // м/с
+	FieldWindDirection = "wind_direction" // градусы
+)
+
+var knownFields = map[string]bool{
+	FieldTemperature:   true,
+	FieldHumidity:      true,
+	FieldPressure:      true,
+	FieldWindSpeed:     true,
+	FieldWindDirection: true,
+}
+
+// Форматы сообщений
+const (
+	FormatZigbee2MQTT = "zigbee2mqtt"
+	FormatTasmota     = "tasmota"
+)
+
+// Location место, к которому относятся датчики
+type Location struct {
+	ID       string  `json:"id"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	RadiusKm float64 `json:"radius_km,omitempty"`
+}
+
+// Topic сопоставление топика локации и полям. Fields - путь к значению в
+// JSON сообщении (через точку, например "BME280.Temperature") -> поле.
+// Если Fields не задан, используются стандартные ключи формата.
+type Topic struct {
+	Topic    string            `json:"topic"`
+	Location string            `json:"location"`
+	Format   string            `json:"format"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// Config конфигурация подключения и сопоставлений
+type Config struct {
+	Broker        string     `json:"broker"`
+	ClientID      string     `json:"client_id,omitempty"`
+	Username      string     `json:"username,omitempty"`
+	Password      string     `json:"password,omitempty"`
+	MaxAgeMinutes int        `json:"max_age_minutes,omitempty"`
+	Locations     []Location `json:"locations"`
+	Topics        []Topic    `json:"topics"`
+}
+
+// LoadConfig читает и проверяет конфигурацию из JSON файла
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения конфигурации датчиков: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга конфигурации датчиков: %w", err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	if c.Broker == "" {
+		return fmt.Errorf("не указан адрес брокера")
+	}
+	if c.ClientID == "" {
+		c.ClientID = "weather-aggregator"
+	}
+	if c.MaxAgeMinutes == 0 {
+		c.MaxAgeMinutes = 30
+	}
+
+	locations := make(map[string]bool)
+	for i, loc := range c.Locations {
+		if loc.ID == "" {
+			return fmt.Errorf("локация %d: не указан id", i)
+		}
+		if loc.RadiusKm == 0 {
+			c.Locations[i].RadiusKm = 25
+		}
+		locations[loc.ID] = true
+	}
+
+	for i, t := range c.Topics {
+		if t.Topic == "" || !locations[t.Location] {
+			return fmt.Errorf("топик %d: нужен topic и location из списка locations", i)
+		}
+		switch t.Format {
+		case "":
+			c.Topics[i].Format = FormatZigbee2MQTT
+		case FormatZigbee2MQTT, FormatTasmota:
+		default:
+			return fmt.Errorf("топик %s: неизвестный формат %q", t.Topic, t.Format)
+		}
+		for path, field := range t.Fields {
+			if !knownFields[field] {
+				return fmt.Errorf("топик %s: неизвестное поле %q для %s", t.Topic, field, path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// MaxAge срок, после которого значение датчика считается устаревшим
+func (c *Config) MaxAge() time.Duration {
+	return time.Duration(c.MaxAgeMinutes) * time.Minute
+}
+
+// Value значение поля и время его получения
+type Value struct {
+	Value    float64   `json:"value"`
+	Topic    string    `json:"topic"`
+	Received time.Time `json:"received"`
+}
+
+// Store последние значения полей по локациям
+type Store struct {
+	mu     sync.RWMutex
+	topics []Topic
+	values map[string]map[string]Value // локация -> поле -> значение
+}
+
+// NewStore создает хранилище для заданных сопоставлений топиков
+func NewStore(topics []Topic) *Store {
+	return &Store{
+		topics: topics,
+		values: make(map[string]map[string]Value),
+	}
+}
+
+// Filters возвращает фильтры подписки
+func (s *Store) Filters() []string {
+	filters := make([]string, 0, len(s.topics))
+	for _, t := range s.topics {
+		filters = append(filters, t.Topic)
+	}
+	return filters
+}
+
+// Handle разбирает сообщение и сохраняет значения для всех подходящих топиков
+func (s *Store) Handle(msg mqtt.Message) {
+	now := time.Now()
+
+	for _, t := range s.topics {
+		if !mqtt.Match(t.Topic, msg.Topic) {
+			continue
+		}
+
+		values, err := Parse(t, msg.Payload)
+		if err != nil {
+			log.Printf("MQTT: сообщение %s пропущено: %v", msg.Topic, err)
+			continue
+		}
+
+		s.mu.Lock()
+		fields, ok := s.values[t.Location]
+		if !ok {
+			fields = make(map[string]Value)
+			s.values[t.Location] = fields
+		}
+		for field, v := range values {
+			fields[field] = Value{Value: v, Topic: msg.Topic, Received: now}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Latest возвращает значения полей локации не старше maxAge
+func (s *Store) Latest(location string, maxAge time.Duration) map[string]Value {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fresh := make(map[string]Value)
+	for field, v := range s.values[location] {
+		if time.Since(v.Received) <= maxAge {
+			fresh[field] = v
+		}
+	}
+	return fresh
+}
+
+// Run подключается к брокеру, подписывается на топики и принимает сообщения,
+// переподключаясь после обрыва, пока не отменен ctx
+func Run(ctx context.Context, cfg *Config, store *Store) {
+	opts := mqtt.Options{
+		ClientID: cfg.ClientID,
+		Username: cfg.Username,
+		Password: cfg.Password,
+	}
+
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := listen(ctx, cfg.Broker, opts, store)
+		if ctx.Err() != nil {
+			return
+		}
+		// Соединение работало долго - обрыв случайный, начинаем паузы заново
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		log.Printf("MQTT: %v, повторное подключение через %s", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+func listen(ctx context.Context, broker string, opts mqtt.Options, store *Store) error {
+	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	client, err := mqtt.Dial(dialCtx, broker, opts)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Subscribe(store.Filters()...); err != nil {
+		return err
+	}
+	log.Printf("MQTT: подключено к %s, подписка на %s", broker, strings.Join(store.Filters(), ", "))
+
+	return client.Listen(ctx, store.Handle)
+}
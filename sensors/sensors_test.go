@@ -0,0 +1,160 @@
+package sensors
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"weather-aggregator/mqtt"
+	"weather-aggregator/mqtt/mqtttest"
+)
+
+// waitFor ждет, пока у локации появятся значения всех полей want, и
+// возвращает их
+func waitFor(t *testing.T, store *Store, location string, want map[string]float64) map[string]Value {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		values := store.Latest(location, time.Hour)
+		ready := true
+		for field, v := range want {
+			if got, ok := values[field]; !ok || math.Abs(got.Value-v) > 1e-6 {
+				ready = false
+			}
+		}
+		if ready {
+			return values
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("локация %s: значения %v, ожидается %v", location, values, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRun(t *testing.T) {
+	broker, err := mqtttest.NewBroker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+
+	cfg := &Config{
+		Broker:    broker.Addr(),
+		Locations: []Location{{ID: "home"}, {ID: "garden"}},
+		Topics: []Topic{
+			{Topic: "zigbee2mqtt/balcony", Location: "home"},
+			{Topic: "tele/+/SENSOR", Location: "garden", Format: FormatTasmota},
+			{Topic: "weather/wind", Location: "garden", Fields: map[string]string{
+				"wind.speed": FieldWindSpeed,
+				"wind.dir":   FieldWindDirection,
+			}},
+		},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Сообщения, опубликованные до подключения, доставляются как retained
+	broker.Publish("zigbee2mqtt/balcony",
+		[]byte(`{"temperature": 21.5, "humidity": 45, "pressure": 1012.3, "battery": 100, "linkquality": 120}`), true)
+	broker.Publish("tele/garden/SENSOR",
+		[]byte(`{"Time": "2025-01-15T12:00:00", "BME280": {"Temperature": 70.1, "Humidity": 40.2, "Pressure": 990.4, "SeaPressure": 1013.2}, "PressureUnit": "hPa", "TempUnit": "F"}`), true)
+
+	store := NewStore(cfg.Topics)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, cfg, store)
+		close(done)
+	}()
+
+	home := waitFor(t, store, "home", map[string]float64{
+		FieldTemperature: 21.5,
+		FieldHumidity:    45,
+		FieldPressure:    1012.3,
+	})
+	if len(home) != 3 || home[FieldTemperature].Topic != "zigbee2mqtt/balcony" {
+		t.Errorf("home: %+v, ожидаются только температура, влажность и давление", home)
+	}
+
+	// Tasmota: °F переводятся в °C, давление на уровне моря важнее Pressure
+	waitFor(t, store, "garden", map[string]float64{
+		FieldTemperature: (70.1 - 32) * 5 / 9,
+		FieldHumidity:    40.2,
+		FieldPressure:    1013.2,
+	})
+
+	// Сообщения после подписки; числа строкой тоже принимаются
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		broker.Publish("weather/wind", []byte(`{"wind": {"speed": "3.4", "dir": 270}}`), false)
+		if _, ok := store.Latest("garden", time.Hour)[FieldWindSpeed]; ok || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	waitFor(t, store, "garden", map[string]float64{FieldWindSpeed: 3.4, FieldWindDirection: 270})
+
+	// После обрыва соединения клиент переподключается и снова подписывается
+	broker.DisconnectAll()
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		broker.Publish("zigbee2mqtt/balcony", []byte(`{"temperature": 19.0}`), false)
+		if v := store.Latest("home", time.Hour)[FieldTemperature]; v.Value == 19 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	waitFor(t, store, "home", map[string]float64{FieldTemperature: 19, FieldHumidity: 45})
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run не завершился после отмены контекста")
+	}
+}
+
+func TestLatestMaxAge(t *testing.T) {
+	topic := Topic{Topic: "zigbee2mqtt/balcony", Location: "home", Format: FormatZigbee2MQTT}
+	store := NewStore([]Topic{topic})
+
+	store.Handle(mqttMessage(topic.Topic, `{"temperature": 21.5, "humidity": 45}`))
+	time.Sleep(150 * time.Millisecond)
+	// Датчик прислал только температуру: влажность остается старой
+	store.Handle(mqttMessage(topic.Topic, `{"temperature": 22.0}`))
+
+	fresh := store.Latest("home", 100*time.Millisecond)
+	if len(fresh) != 1 || fresh[FieldTemperature].Value != 22 {
+		t.Errorf("свежие значения %+v, ожидается только температура 22", fresh)
+	}
+
+	all := store.Latest("home", time.Hour)
+	if len(all) != 2 || all[FieldHumidity].Value != 45 {
+		t.Errorf("значения за час %+v, ожидаются температура и влажность", all)
+	}
+
+	if values := store.Latest("garden", time.Hour); len(values) != 0 {
+		t.Errorf("значения неизвестной локации: %+v", values)
+	}
+}
+
+func TestHandleSkipsInvalid(t *testing.T) {
+	topic := Topic{Topic: "zigbee2mqtt/+", Location: "home", Format: FormatZigbee2MQTT}
+	store := NewStore([]Topic{topic})
+
+	store.Handle(mqttMessage("zigbee2mqtt/balcony", `not json`))
+	store.Handle(mqttMessage("zigbee2mqtt/balcony", `{"battery": 97}`))
+	store.Handle(mqttMessage("other/balcony", `{"temperature": 21.5}`))
+
+	if values := store.Latest("home", time.Hour); len(values) != 0 {
+		t.Errorf("сохранены значения из некорректных сообщений: %+v", values)
+	}
+}
+
+func mqttMessage(topic, payload string) mqtt.Message {
+	return mqtt.Message{Topic: topic, Payload: []byte(payload)}
+}
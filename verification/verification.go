@@ -0,0 +1,340 @@
+// Package verification проверяет точность прогнозов провайдеров: прогнозы,
+// сохраненные в истории, сравниваются с агрегированными наблюдениями на тот же
+// момент. Для каждого провайдера, величины и заблаговременности считаются
+// средняя абсолютная ошибка (MAE), систематическая ошибка (bias, прогноз минус
+// наблюдение) и среднеквадратичная ошибка (RMSE).
+package verification
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"weather-aggregator/history"
+	"weather-aggregator/models"
+)
+
+// LeadAll заблаговременность строк итоговой таблицы
+const LeadAll = "all"
+
+// leads интервалы заблаговременности (от момента получения прогноза до
+// момента, на который он составлен)
+var leads = []struct {
+	label string
+	max   time.Duration
+}{
+	{"0-6h", 6 * time.Hour},
+	{"6-12h", 12 * time.Hour},
+	{"12-24h", 24 * time.Hour},
+	{"24-48h", 48 * time.Hour},
+	{"48-72h", 72 * time.Hour},
+}
+
+// Fields проверяемые величины
+var Fields = []string{"temperature", "humidity", "pressure", "wind_speed"}
+
+// errorFloor нижняя граница ошибки для весов: точность измерений, чтобы
+// провайдер, совпавший с наблюдениями на коротком отрезке, не получал
+// бесконечный вес
+var errorFloor = map[string]float64{
+	"temperature": 0.5,
+	"humidity":    3,
+	"pressure":    0.5,
+	"wind_speed":  0.5,
+}
+
+// Options параметры проверки
+type Options struct {
+	// Window за сколько последних суток сравнивать прогнозы (по умолчанию 30)
+	Window time.Duration
+	// Tolerance максимальное расхождение времени прогноза и наблюдения (по умолчанию 30 минут)
+	Tolerance time.Duration
+	// MinCount сколько сравнений нужно, чтобы оценка использовалась как вес (по умолчанию 12)
+	MinCount int
+	// CacheTTL сколько хранить рассчитанные оценки (по умолчанию час)
+	CacheTTL time.Duration
+}
+
+// Scorer рассчитывает и кеширует оценки точности прогнозов по истории
+type Scorer struct {
+	store *history.Store
+	opts  Options
+
+	mu      sync.Mutex
+	cache   map[string]scoresEntry
+	pending map[string]*scoresCall
+}
+
+type scoresEntry struct {
+	scores     *models.ScoresResponse
+	calculated time.Time
+}
+
+// scoresCall расчет оценок, который уже идет; остальные запросы ждут его
+type scoresCall struct {
+	done   chan struct{}
+	scores *models.ScoresResponse
+	err    error
+}
+
+// NewScorer создает расчет оценок по хранилищу истории
+func NewScorer(store *history.Store, opts Options) *Scorer {
+	if opts.Window <= 0 {
+		opts.Window = 30 * 24 * time.Hour
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 30 * time.Minute
+	}
+	if opts.MinCount <= 0 {
+		opts.MinCount = 12
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = time.Hour
+	}
+	return &Scorer{
+		store:   store,
+		opts:    opts,
+		cache:   make(map[string]scoresEntry),
+		pending: make(map[string]*scoresCall),
+	}
+}
+
+// Scores возвращает оценки точности прогнозов для города за окно до now.
+// Результат кешируется на CacheTTL; одновременные запросы при промахе кеша
+// ждут один расчет, а не читают историю каждый сам.
+func (s *Scorer) Scores(city, country string, now time.Time) (*models.ScoresResponse, error) {
+	key := fmt.Sprintf("%s,%s", city, country)
+
+	s.mu.Lock()
+	if cached, found := s.cache[key]; found && now.Sub(cached.calculated) < s.opts.CacheTTL {
+		s.mu.Unlock()
+		return cached.scores, nil
+	}
+	if call, ok := s.pending[key]; ok {
+		s.mu.Unlock()
+		<-call.done
+		return call.scores, call.err
+	}
+	call := &scoresCall{done: make(chan struct{})}
+	s.pending[key] = call
+	s.mu.Unlock()
+
+	call.scores, call.err = s.calculate(city, country, now.Add(-s.opts.Window), now)
+
+	s.mu.Lock()
+	if call.err == nil {
+		s.cache[key] = scoresEntry{scores: call.scores, calculated: now}
+	}
+	delete(s.pending, key)
+	s.mu.Unlock()
+	close(call.done)
+
+	return call.scores, call.err
+}
+
+func (s *Scorer) calculate(city, country string, from, to time.Time) (*models.ScoresResponse, error) {
+	forecasts, err := s.store.QueryForecasts(history.Query{City: city, Country: country, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	observations, err := s.store.Query(history.Query{
+		City:    city,
+		Country: country,
+		From:    from.Add(-s.opts.Tolerance),
+		To:      to.Add(s.opts.Tolerance),
+		Source:  history.SourceAggregated,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byLead, leaderboard := Verify(forecasts, observations, s.opts.Tolerance)
+	return &models.ScoresResponse{
+		City:        city,
+		Country:     country,
+		From:        from,
+		To:          to,
+		Leaderboard: leaderboard,
+		ByLead:      byLead,
+	}, nil
+}
+
+// Weights веса провайдеров для города: провайдер -> величина -> вес.
+// Оценки читаются один раз на вызов, поэтому агрегатор запрашивает веса
+// один раз на агрегацию. Ошибка чтения истории - весов нет. Реализует
+// aggregator.Weigher.
+func (s *Scorer) Weights(city, country string) map[string]map[string]float64 {
+	scores, err := s.Scores(city, country, time.Now())
+	if err != nil {
+		return nil
+	}
+	return Weights(scores, s.opts.MinCount)
+}
+
+// Weights веса по оценкам: обратный квадрат RMSE на самой короткой
+// заблаговременности, по которой набралось minCount сравнений, иначе по всем.
+// Провайдеров и величин с меньшим числом сравнений в результате нет.
+func Weights(scores *models.ScoresResponse, minCount int) map[string]map[string]float64 {
+	type key struct{ provider, field string }
+	best := make(map[key]models.ProviderScore)
+	leadOrder := indexOf(leadLabels())
+	for _, score := range scores.ByLead {
+		k := key{score.Provider, score.Field}
+		if score.Count < minCount {
+			continue
+		}
+		if current, ok := best[k]; !ok || leadOrder[score.Lead] < leadOrder[current.Lead] {
+			best[k] = score
+		}
+	}
+	for _, score := range scores.Leaderboard {
+		k := key{score.Provider, score.Field}
+		if _, ok := best[k]; !ok && score.Count >= minCount {
+			best[k] = score
+		}
+	}
+
+	weights := make(map[string]map[string]float64)
+	for k, score := range best {
+		if weights[k.provider] == nil {
+			weights[k.provider] = make(map[string]float64)
+		}
+		floor := errorFloor[k.field]
+		weights[k.provider][k.field] = 1 / (score.RMSE*score.RMSE + floor*floor)
+	}
+	return weights
+}
+
+func leadLabels() []string {
+	labels := make([]string, len(leads))
+	for i, l := range leads {
+		labels[i] = l.label
+	}
+	return labels
+}
+
+// leadLabel интервал заблаговременности; ok = false для прошедших моментов
+// и заблаговременности больше проверяемой
+func leadLabel(lead time.Duration) (string, bool) {
+	if lead < 0 {
+		return "", false
+	}
+	for _, l := range leads {
+		if lead < l.max {
+			return l.label, true
+		}
+	}
+	return "", false
+}
+
+// accumulator суммы ошибок
+type accumulator struct {
+	count            int
+	sumAbs, sum, sq2 float64
+}
+
+func (a *accumulator) add(err float64) {
+	a.count++
+	a.sumAbs += math.Abs(err)
+	a.sum += err
+	a.sq2 += err * err
+}
+
+func (a *accumulator) score(provider, field, lead string) models.ProviderScore {
+	n := float64(a.count)
+	return models.ProviderScore{
+		Provider: provider,
+		Field:    field,
+		Lead:     lead,
+		Count:    a.count,
+		MAE:      round(a.sumAbs / n),
+		Bias:     round(a.sum / n),
+		RMSE:     round(math.Sqrt(a.sq2 / n)),
+	}
+}
+
+// Verify сравнивает прогнозы с ближайшими по времени наблюдениями (не дальше
+// tolerance) и возвращает оценки по заблаговременностям и итоговую таблицу по
+// всем заблаговременностям, упорядоченную по MAE внутри каждой величины
+func Verify(forecasts, observations []history.Point, tolerance time.Duration) (byLead, leaderboard []models.ProviderScore) {
+	obs := make([]history.Point, len(observations))
+	copy(obs, observations)
+	sort.SliceStable(obs, func(i, j int) bool { return obs[i].Time.Before(obs[j].Time) })
+
+	byLead, leaderboard = []models.ProviderScore{}, []models.ProviderScore{}
+
+	type key struct{ provider, field, lead string }
+	sums := make(map[key]*accumulator)
+	addTo := func(k key, err float64) {
+		acc, ok := sums[k]
+		if !ok {
+			acc = &accumulator{}
+			sums[k] = acc
+		}
+		acc.add(err)
+	}
+
+	for _, f := range forecasts {
+		if f.Issued == nil {
+			continue
+		}
+		lead, ok := leadLabel(f.Time.Sub(*f.Issued))
+		if !ok {
+			continue
+		}
+		observed, ok := history.Nearest(obs, f.Time, tolerance)
+		if !ok {
+			continue
+		}
+		for _, field := range Fields {
+			predicted, ok1 := f.Values[field]
+			actual, ok2 := observed.Values[field]
+			if !ok1 || !ok2 {
+				continue
+			}
+			addTo(key{f.Source, field, lead}, predicted-actual)
+			addTo(key{f.Source, field, LeadAll}, predicted-actual)
+		}
+	}
+
+	for k, acc := range sums {
+		score := acc.score(k.provider, k.field, k.lead)
+		if k.lead == LeadAll {
+			leaderboard = append(leaderboard, score)
+		} else {
+			byLead = append(byLead, score)
+		}
+	}
+
+	fieldOrder := indexOf(Fields)
+	leadOrder := indexOf(leadLabels())
+	less := func(a, b models.ProviderScore) bool {
+		if a.Field != b.Field {
+			return fieldOrder[a.Field] < fieldOrder[b.Field]
+		}
+		if a.Lead != b.Lead {
+			return leadOrder[a.Lead] < leadOrder[b.Lead]
+		}
+		if a.MAE != b.MAE {
+			return a.MAE < b.MAE
+		}
+		return a.Provider < b.Provider
+	}
+	sort.Slice(byLead, func(i, j int) bool { return less(byLead[i], byLead[j]) })
+	sort.Slice(leaderboard, func(i, j int) bool { return less(leaderboard[i], leaderboard[j]) })
+	return byLead, leaderboard
+}
+
+func indexOf(list []string) map[string]int {
+	index := make(map[string]int, len(list))
+	for i, v := range list {
+		index[v] = i
+	}
+	return index
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,196 @@
+package verification
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"weather-aggregator/history"
+	"weather-aggregator/models"
+)
+
+var base = time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+// verifyData почасовые наблюдения 10°C и 70% (без 5-го часа) и прогнозы,
+// полученные в base
+func verifyData() (forecasts, observations []history.Point) {
+	for h := 0; h <= 30; h++ {
+		if h == 5 {
+			continue
+		}
+		observations = append(observations, history.Point{
+			Time:   base.Add(time.Duration(h) * time.Hour),
+			Source: history.SourceAggregated,
+			Values: map[string]float64{"temperature": 10, "humidity": 70},
+		})
+	}
+
+	issued := base
+	forecast := func(source string, at time.Duration, values map[string]float64) history.Point {
+		return history.Point{Time: base.Add(at), Issued: &issued, Source: source, Values: values}
+	}
+	forecasts = []history.Point{
+		forecast("A", time.Hour, map[string]float64{"temperature": 12, "humidity": 80}),
+		// Ближайшее наблюдение через 20 минут - в пределах допуска
+		forecast("A", 2*time.Hour-20*time.Minute, map[string]float64{"temperature": 9}),
+		forecast("A", 7*time.Hour, map[string]float64{"temperature": 13}),
+		forecast("B", time.Hour, map[string]float64{"temperature": 10.5}),
+		// Наблюдения за 5-й час нет, ближайшие дальше допуска
+		forecast("B", 5*time.Hour, map[string]float64{"temperature": 30}),
+		// Прошедший момент и заблаговременность больше проверяемой
+		forecast("B", -time.Hour, map[string]float64{"temperature": 30}),
+		forecast("B", 72*time.Hour, map[string]float64{"temperature": 30}),
+		// Точка без времени получения - не прогноз
+		{Time: base.Add(3 * time.Hour), Source: "B", Values: map[string]float64{"temperature": 30}},
+	}
+	return forecasts, observations
+}
+
+func TestVerify(t *testing.T) {
+	forecasts, observations := verifyData()
+	byLead, leaderboard := Verify(forecasts, observations, 30*time.Minute)
+
+	rmse := func(errs ...float64) float64 {
+		sum := 0.0
+		for _, e := range errs {
+			sum += e * e
+		}
+		return round(math.Sqrt(sum / float64(len(errs))))
+	}
+	wantLeaderboard := []models.ProviderScore{
+		{Provider: "B", Field: "temperature", Lead: LeadAll, Count: 1, MAE: 0.5, Bias: 0.5, RMSE: 0.5},
+		{Provider: "A", Field: "temperature", Lead: LeadAll, Count: 3, MAE: 2, Bias: 1.33, RMSE: rmse(2, -1, 3)},
+		{Provider: "A", Field: "humidity", Lead: LeadAll, Count: 1, MAE: 10, Bias: 10, RMSE: 10},
+	}
+	wantByLead := []models.ProviderScore{
+		{Provider: "B", Field: "temperature", Lead: "0-6h", Count: 1, MAE: 0.5, Bias: 0.5, RMSE: 0.5},
+		{Provider: "A", Field: "temperature", Lead: "0-6h", Count: 2, MAE: 1.5, Bias: 0.5, RMSE: rmse(2, -1)},
+		{Provider: "A", Field: "temperature", Lead: "6-12h", Count: 1, MAE: 3, Bias: 3, RMSE: 3},
+		{Provider: "A", Field: "humidity", Lead: "0-6h", Count: 1, MAE: 10, Bias: 10, RMSE: 10},
+	}
+
+	check := func(name string, got, want []models.ProviderScore) {
+		if len(got) != len(want) {
+			t.Fatalf("%s: %d оценок, ожидалось %d: %+v", name, len(got), len(want), got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s[%d] = %+v, ожидалось %+v", name, i, got[i], want[i])
+			}
+		}
+	}
+	check("leaderboard", leaderboard, wantLeaderboard)
+	check("byLead", byLead, wantByLead)
+}
+
+func TestVerifyEmpty(t *testing.T) {
+	byLead, leaderboard := Verify(nil, nil, time.Hour)
+	if byLead == nil || leaderboard == nil || len(byLead)+len(leaderboard) != 0 {
+		t.Errorf("ожидались пустые списки, а не nil (для JSON): %v, %v", byLead, leaderboard)
+	}
+}
+
+func TestLeadLabel(t *testing.T) {
+	tests := []struct {
+		lead time.Duration
+		want string
+		ok   bool
+	}{
+		{-time.Minute, "", false},
+		{0, "0-6h", true},
+		{6*time.Hour - time.Second, "0-6h", true},
+		{6 * time.Hour, "6-12h", true},
+		{24 * time.Hour, "24-48h", true},
+		{72*time.Hour - time.Second, "48-72h", true},
+		{72 * time.Hour, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := leadLabel(tt.lead)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("leadLabel(%v) = %q, %v; ожидалось %q, %v", tt.lead, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWeights(t *testing.T) {
+	forecasts, observations := verifyData()
+	byLead, leaderboard := Verify(forecasts, observations, 30*time.Minute)
+	scores := &models.ScoresResponse{ByLead: byLead, Leaderboard: leaderboard}
+
+	// При minCount 2 оценен только A по температуре на 0-6h
+	weights := Weights(scores, 2)
+	floor := errorFloor["temperature"]
+	want := 1 / (rmseOf(byLead, "A", "temperature", "0-6h")*rmseOf(byLead, "A", "temperature", "0-6h") + floor*floor)
+	if len(weights) != 1 || len(weights["A"]) != 1 || weights["A"]["temperature"] != want {
+		t.Errorf("веса %v, ожидался только A/temperature = %v", weights, want)
+	}
+
+	// При minCount 3 на коротких заблаговременностях не хватает, берется оценка по всем
+	weights = Weights(scores, 3)
+	rmse := rmseOf(leaderboard, "A", "temperature", LeadAll)
+	if got := weights["A"]["temperature"]; got != 1/(rmse*rmse+floor*floor) {
+		t.Errorf("вес A/temperature %v, ожидался по оценке за все заблаговременности", got)
+	}
+
+	if weights := Weights(scores, 100); len(weights) != 0 {
+		t.Errorf("веса без достаточного числа сравнений: %v", weights)
+	}
+}
+
+func rmseOf(scores []models.ProviderScore, provider, field, lead string) float64 {
+	for _, s := range scores {
+		if s.Provider == provider && s.Field == field && s.Lead == lead {
+			return s.RMSE
+		}
+	}
+	return math.NaN()
+}
+
+func TestScoresConcurrent(t *testing.T) {
+	store, err := history.Open(t.TempDir(), history.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecasts, observations := verifyData()
+	if err := store.Append("Москва", "RU", observations...); err != nil {
+		t.Fatal(err)
+	}
+	issued := base
+	for _, f := range forecasts[:4] {
+		point := models.ForecastPoint{Time: f.Time}
+		if v, ok := f.Values["temperature"]; ok {
+			point.Temperature = &v
+		}
+		if err := store.RecordForecast("Москва", "RU", f.Source, issued, []models.ForecastPoint{point}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Одновременные промахи кеша получают результат одного расчета
+	scorer := NewScorer(store, Options{})
+	now := base.Add(24 * time.Hour)
+	results := make([]*models.ScoresResponse, 8)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			scores, err := scorer.Scores("Москва", "RU", now)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = scores
+		}(i)
+	}
+	wg.Wait()
+
+	for _, scores := range results[1:] {
+		if scores != results[0] {
+			t.Fatal("одновременные запросы рассчитали оценки несколько раз")
+		}
+	}
+	if len(results[0].Leaderboard) != 2 {
+		t.Errorf("итоговая таблица %+v, ожидались A и B по температуре", results[0].Leaderboard)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"weather-aggregator/models"
+	"weather-aggregator/scheduler"
+)
+
+// Управляющие последовательности терминала
+const (
+	ansiHome       = "\033[H"
+	ansiClear      = "\033[2J"
+	ansiClearLine  = "\033[K"
+	ansiClearBelow = "\033[J"
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+	ansiReset      = "\033[0m"
+	ansiBold       = "\033[1m"
+	ansiDim        = "\033[2m"
+	ansiRed        = "\033[31m"
+	ansiBlue       = "\033[34m"
+	ansiYellow     = "\033[33m"
+)
+
+// watchTimeout ограничение одного обновления всех городов
+const watchTimeout = 10 * time.Second
+
+// newWatchCmd создает команду наблюдения за погодой в нескольких городах
+func newWatchCmd() *cobra.Command {
+	var watchCmd = &cobra.Command{
+		Use:   "watch [город...]",
+		Short: "Следить за погодой в городах",
+		Long: "Обновляет погоду в городах с заданным интервалом и перерисовывает таблицу\n" +
+			"на месте, выделяя изменившиеся значения. Города в формате \"Город\" или\n" +
+			"\"Город:СТРАНА\". Ответы берутся из кеша агрегатора, поэтому провайдеры\n" +
+			"запрашиваются не чаще CACHE_DURATION. Выход - Ctrl-C.",
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			country, _ := cmd.Flags().GetString("country")
+			interval, _ := cmd.Flags().GetDuration("interval")
+
+			locations, err := scheduler.ParseLocations(args, strings.ToUpper(country))
+			if err != nil {
+				log.Fatalf("Ошибка: %v", err)
+			}
+			if interval < time.Second {
+				log.Fatalf("Интервал обновления должен быть не меньше секунды")
+			}
+
+			watchCLI(locations, interval)
+		},
+	}
+
+	watchCmd.Flags().StringP("country", "c", "RU", "Код страны для городов без \":СТРАНА\"")
+	watchCmd.Flags().DurationP("interval", "i", time.Minute, "Интервал обновления")
+
+	return watchCmd
+}
+
+// watchRow строка таблицы: погода города или ошибка ее получения
+type watchRow struct {
+	location scheduler.Location
+	weather  *models.AggregatedWeather
+	err      error
+}
+
+// watchColumn колонка таблицы с числовым значением
+type watchColumn struct {
+	title, format string
+	width         int
+	value         func(w *models.AggregatedWeather) *models.AggregatedValue
+}
+
+var watchColumns = []watchColumn{
+	{"Темп,°C", "%.1f", 9, func(w *models.AggregatedWeather) *models.AggregatedValue { return w.Temperature }},
+	{"Ощущ,°C", "%.1f", 9, func(w *models.AggregatedWeather) *models.AggregatedValue { return w.FeelsLike }},
+	{"Влаж,%", "%.0f", 8, func(w *models.AggregatedWeather) *models.AggregatedValue { return w.Humidity }},
+	{"Давл,hPa", "%.0f", 10, func(w *models.AggregatedWeather) *models.AggregatedValue { return w.Pressure }},
+	{"Ветер,м/с", "%.1f", 11, func(w *models.AggregatedWeather) *models.AggregatedValue { return w.WindSpeed }},
+}
+
+// watchCLI обновляет и выводит таблицу до Ctrl-C
+func watchCLI(locations []scheduler.Location, interval time.Duration) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Вне терминала (перенаправление в файл) таблицы выводятся друг за другом
+	interactive := isTerminal(os.Stdout)
+	if interactive {
+		fmt.Print(ansiHideCursor + ansiHome + ansiClear)
+		defer fmt.Print(ansiShowCursor)
+	}
+
+	var previous map[string]*models.AggregatedWeather
+	refresh := func() {
+		rows := fetchWatchRows(ctx, locations)
+		if ctx.Err() != nil {
+			return
+		}
+
+		var b strings.Builder
+		renderWatchTable(&b, rows, previous, interval, time.Now(), interactive)
+		if interactive {
+			// Строки перезаписываются на месте, остатки прошлой таблицы стираются
+			fmt.Print(ansiHome + strings.ReplaceAll(b.String(), "\n", ansiClearLine+"\n") + ansiClearBelow)
+		} else {
+			fmt.Println(b.String())
+		}
+
+		// Для города, который не удалось обновить, сравнение идет с последним успешным ответом
+		current := make(map[string]*models.AggregatedWeather, len(rows))
+		for _, row := range rows {
+			key := row.location.City + "," + row.location.Country
+			if row.weather != nil {
+				current[key] = row.weather
+			} else if previous != nil {
+				current[key] = previous[key]
+			}
+		}
+		previous = current
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	refresh()
+	for {
+		select {
+		case <-ctx.Done():
+			if interactive {
+				fmt.Println()
+			}
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// fetchWatchRows получает погоду для всех городов параллельно через кеш агрегатора
+func fetchWatchRows(ctx context.Context, locations []scheduler.Location) []watchRow {
+	ctx, cancel := context.WithTimeout(ctx, watchTimeout)
+	defer cancel()
+
+	rows := make([]watchRow, len(locations))
+	var wg sync.WaitGroup
+	for i, location := range locations {
+		wg.Add(1)
+		go func(i int, location scheduler.Location) {
+			defer wg.Done()
+
+			weather, err := agg.GetWeather(ctx, location.City, location.Country)
+			rows[i] = watchRow{location: location, weather: weather, err: err}
+		}(i, location)
+	}
+	wg.Wait()
+	return rows
+}
+
+// renderWatchTable выводит таблицу; значения, изменившиеся с прошлого
+// обновления, выделяются цветом и стрелкой
+func renderWatchTable(b *strings.Builder, rows []watchRow, previous map[string]*models.AggregatedWeather,
+	interval time.Duration, now time.Time, color bool) {
+	paint := func(code, s string) string {
+		if !color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	fmt.Fprintf(b, "%s\n", paint(ansiBold, fmt.Sprintf("🌤️  Погода: обновлено в %s, следующее в %s (каждые %s), Ctrl-C - выход",
+		now.Format("15:04:05"), now.Add(interval).Format("15:04:05"), formatDuration(interval))))
+	fmt.Fprintf(b, "%-20s", "Город")
+	for _, col := range watchColumns {
+		fmt.Fprintf(b, " %*s", col.width, col.title)
+	}
+	fmt.Fprintf(b, "  %-8s  %s\n", "Данные", "Описание")
+	fmt.Fprintln(b, strings.Repeat("-", 100))
+
+	for _, row := range rows {
+		name := row.location.City + ", " + row.location.Country
+		fmt.Fprintf(b, "%-20s", truncate(name, 20))
+		if row.weather == nil {
+			fmt.Fprintf(b, " %s\n", paint(ansiRed, "ошибка: "+errorText(row.err)))
+			continue
+		}
+
+		var before *models.AggregatedWeather
+		if previous != nil {
+			before = previous[row.location.City+","+row.location.Country]
+		}
+		for _, col := range watchColumns {
+			value := col.value(row.weather)
+			if value == nil {
+				fmt.Fprintf(b, " %*s ", col.width-1, "-")
+				continue
+			}
+			text := fmt.Sprintf(col.format, value.Average)
+			// Сравниваются отображаемые значения: изменения в невидимых
+			// разрядах не выделяются
+			marker, code := " ", ""
+			if before != nil {
+				if old := col.value(before); old != nil {
+					if oldText := fmt.Sprintf(col.format, old.Average); oldText != text {
+						if value.Average > old.Average {
+							marker, code = "↑", ansiRed
+						} else {
+							marker, code = "↓", ansiBlue
+						}
+					}
+				}
+			}
+			cell := fmt.Sprintf("%*s", col.width-1, text) + marker
+			if code != "" {
+				cell = paint(ansiBold+code, cell)
+			}
+			fmt.Fprintf(b, " %s", cell)
+		}
+
+		updated := row.weather.LastUpdated.Local().Format("15:04")
+		if before != nil && !before.LastUpdated.Equal(row.weather.LastUpdated) {
+			updated = paint(ansiYellow, fmt.Sprintf("%-8s", updated))
+		} else {
+			updated = paint(ansiDim, fmt.Sprintf("%-8s", updated))
+		}
+		fmt.Fprintf(b, "  %s  %s\n", updated, truncate(row.weather.Description, 30))
+	}
+}
+
+// errorText первая строка ошибки, укороченная для ячейки таблицы
+func errorText(err error) string {
+	text, _, _ := strings.Cut(err.Error(), "\n")
+	return truncate(text, 70)
+}
+
+// truncate укорачивает строку до n символов
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// isTerminal проверяет, что вывод идет в терминал
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}